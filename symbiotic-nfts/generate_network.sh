@@ -278,6 +278,12 @@ EOF
     depends_on:
       relay-sidecar-$i:
         condition: service_started
+    healthcheck:
+      test: ["CMD", "wget", "-qO-", "http://localhost:8080/readyz"]
+      interval: 10s
+      timeout: 5s
+      retries: 3
+      start_period: 60s
     networks:
       - symbiotic-network
     restart: unless-stopped
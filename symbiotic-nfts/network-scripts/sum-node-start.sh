@@ -7,4 +7,4 @@ echo "SumTask address from sum_task_contracts.json: $SUMTASK_ADDRESS"
 SETTLEMENT_SUMTASK_ADDRESS=$(jq -r '.sumTasks[1].addr' /deploy-data/sum_task_contracts.json)
 echo "Settlement SumTask address from sum_task_contracts.json: $SETTLEMENT_SUMTASK_ADDRESS"
 
-exec /app/sum-node --evm-rpc-urls http://anvil:8545,http://anvil-settlement:8546 --relay-api-url "$1" --contract-addresses "$SUMTASK_ADDRESS,$SETTLEMENT_SUMTASK_ADDRESS" --private-key "$2" --log-level info 
\ No newline at end of file
+exec /app/sum-node --evm-rpc-urls http://anvil:8545,http://anvil-settlement:8546 --relay-api-url "$1" --contract-addresses "$SUMTASK_ADDRESS,$SETTLEMENT_SUMTASK_ADDRESS" --contract-types sum,sum --private-key "$2" --log-level info --http-addr :8080 
\ No newline at end of file
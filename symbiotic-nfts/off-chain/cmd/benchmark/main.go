@@ -177,7 +177,7 @@ func runProcesses(ctx context.Context, runSeparately bool) (processes, error) {
 			}
 		}
 
-		conn, err := utils.GetGRPCConnection("localhost" + apiAddr + "/api/v1")
+		conn, err := utils.GetGRPCConnection("localhost"+apiAddr+"/api/v1", utils.DefaultGRPCOptions())
 		if err != nil {
 			return nil, errors.Errorf("failed to create relay client: %w", err)
 		}
@@ -0,0 +1,374 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/go-errors/errors"
+
+	"sum/internal/txmgr"
+)
+
+// phaseRemoved marks the lifecycle event of a task dropped from tracking, e.g. after a reorg.
+const phaseRemoved = "removed"
+
+func init() {
+	httpMux.HandleFunc("GET /tasks", handleListTasks)
+	httpMux.HandleFunc("GET /tasks/events", handleTaskEvents)
+	httpMux.HandleFunc("GET /tasks/{id}", handleGetTask)
+	httpMux.HandleFunc("GET /chains", handleChains)
+	httpMux.HandleFunc("GET /relay", handleRelay)
+}
+
+// taskView is the admin API representation of a TaskState.
+type taskView struct {
+	Key            string             `json:"key"`
+	ChainID        int64              `json:"chainId"`
+	Contract       common.Address     `json:"contract"`
+	TaskID         common.Hash        `json:"taskId"`
+	Type           string             `json:"type"`
+	Phase          string             `json:"phase"`
+	Request        json.RawMessage    `json:"request"`
+	Payload        hexutil.Bytes      `json:"payload,omitempty"`
+	SigEpoch       int64              `json:"sigEpoch"`
+	SigRequestHash string             `json:"sigRequestHash,omitempty"`
+	AggProof       hexutil.Bytes      `json:"aggProof,omitempty"`
+	ProofAt        *time.Time         `json:"proofAt,omitempty"`
+	MinEpoch       int64              `json:"minEpoch"`
+	Attempts       []SignAttempt      `json:"attempts"`
+	Statuses       map[int64]uint8    `json:"statuses"`
+	Submissions    map[int64]txmgr.Tx `json:"submissions"`
+	// FailedSubmissions are the failed response txs, replaced by a resubmission in Submissions.
+	FailedSubmissions map[int64][]txmgr.Tx `json:"failedSubmissions,omitempty"`
+	// Rejections are the reverted response simulations per chain since the last successful one.
+	Rejections       map[int64]simRejection `json:"rejections,omitempty"`
+	CreatedBlock     uint64                 `json:"createdBlock"`
+	CreatedBlockHash common.Hash            `json:"createdBlockHash"`
+	CreatedAt        *time.Time             `json:"createdAt,omitempty"`
+}
+
+func newTaskView(st TaskState) taskView {
+	v := taskView{
+		Key:               st.Key().String(),
+		ChainID:           st.ChainID,
+		Contract:          st.Contract,
+		TaskID:            st.TaskID,
+		Type:              st.Type,
+		Phase:             taskPhase(st),
+		Request:           st.Req,
+		Payload:           st.Payload,
+		SigEpoch:          st.SigEpoch,
+		SigRequestHash:    st.SigRequestHash,
+		AggProof:          st.AggProof,
+		MinEpoch:          st.MinEpoch,
+		Attempts:          st.Attempts,
+		Statuses:          st.Statuses,
+		Submissions:       st.Submissions,
+		FailedSubmissions: st.FailedSubmissions,
+		Rejections:        st.Rejections,
+		CreatedBlock:      st.CreatedBlock,
+		CreatedBlockHash:  st.CreatedBlockHash,
+	}
+	if !st.ProofAt.IsZero() {
+		v.ProofAt = &st.ProofAt
+	}
+	if !st.CreatedAt.IsZero() {
+		v.CreatedAt = &st.CreatedAt
+	}
+	return v
+}
+
+// taskFilter selects tasks by the query parameters status (lifecycle phase), chain (origin or
+// delivery chain), contract and collection (the NFT collection of nft-ownership requests).
+type taskFilter struct {
+	phase      string
+	chainID    *int64
+	contract   *common.Address
+	collection *common.Address
+}
+
+func parseTaskFilter(r *http.Request) (taskFilter, error) {
+	var f taskFilter
+	q := r.URL.Query()
+	if s := q.Get("status"); s != "" {
+		phases := []string{phaseUnsigned, phaseAwaitProof, phaseProven, phaseSubmitted, phaseConfirmed, phaseFailed}
+		if !slices.Contains(phases, s) {
+			return f, errors.Errorf("unknown status %q, expected one of %s", s, strings.Join(phases, "|"))
+		}
+		f.phase = s
+	}
+	if s := q.Get("chain"); s != "" {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return f, errors.Errorf("invalid chain %q", s)
+		}
+		f.chainID = &id
+	}
+	for name, dst := range map[string]**common.Address{"contract": &f.contract, "collection": &f.collection} {
+		if s := q.Get(name); s != "" {
+			if !common.IsHexAddress(s) {
+				return f, errors.Errorf("invalid %s address %q", name, s)
+			}
+			addr := common.HexToAddress(s)
+			*dst = &addr
+		}
+	}
+	return f, nil
+}
+
+func (f taskFilter) match(st TaskState) bool {
+	if f.phase != "" && taskPhase(st) != f.phase {
+		return false
+	}
+	return f.matchKey(st)
+}
+
+// matchKey applies the filters that don't depend on the task's progress.
+func (f taskFilter) matchKey(st TaskState) bool {
+	if f.chainID != nil && !isTaskTarget(st, *f.chainID) {
+		return false
+	}
+	if f.contract != nil && st.Contract != *f.contract {
+		return false
+	}
+	if f.collection != nil {
+		var req struct{ Collection *common.Address }
+		if err := json.Unmarshal(st.Req, &req); err != nil || req.Collection == nil || *req.Collection != *f.collection {
+			return false
+		}
+	}
+	return true
+}
+
+func handleListTasks(w http.ResponseWriter, r *http.Request) {
+	f, err := parseTaskFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	views := []taskView{}
+	for _, st := range taskSnapshot() {
+		if f.match(st) {
+			views = append(views, newTaskView(st))
+		}
+	}
+	slices.SortFunc(views, func(a, b taskView) int { return strings.Compare(a.Key, b.Key) })
+	writeJSON(w, http.StatusOK, views)
+}
+
+// handleGetTask looks a task up by its ID. Task IDs are only unique per contract, the chain and
+// contract query parameters pick one if several contracts created the same ID.
+func handleGetTask(w http.ResponseWriter, r *http.Request) {
+	taskID, err := hexutil.Decode(r.PathValue("id"))
+	if err != nil || len(taskID) != common.HashLength {
+		writeError(w, http.StatusBadRequest, errors.Errorf("invalid task ID %q", r.PathValue("id")))
+		return
+	}
+	f, err := parseTaskFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	var found []TaskState
+	for _, st := range taskSnapshot() {
+		if st.TaskID == common.BytesToHash(taskID) && (f.chainID == nil || st.ChainID == *f.chainID) && f.matchKey(st) {
+			found = append(found, st)
+		}
+	}
+	switch len(found) {
+	case 0:
+		writeError(w, http.StatusNotFound, errors.Errorf("task %s not found", r.PathValue("id")))
+	case 1:
+		writeJSON(w, http.StatusOK, newTaskView(found[0]))
+	default:
+		var keys []string
+		for _, st := range found {
+			keys = append(keys, st.Key().String())
+		}
+		slices.Sort(keys)
+		writeError(w, http.StatusConflict, errors.Errorf("task ID matches %s, set chain and contract", strings.Join(keys, ", ")))
+	}
+}
+
+type chainView struct {
+	ChainID   int64          `json:"chainId"`
+	Contracts []contractView `json:"contracts"`
+	Health    chainHealth    `json:"health"`
+	Progress  chainProgress  `json:"progress"`
+	Responder common.Address `json:"responder"`
+	Tasks     map[string]int `json:"tasks"`
+}
+
+type contractView struct {
+	Address common.Address `json:"address"`
+	Type    string         `json:"type"`
+}
+
+func handleChains(w http.ResponseWriter, _ *http.Request) {
+	views := []chainView{}
+	for chainID, wk := range workers {
+		v := chainView{
+			ChainID:   chainID,
+			Health:    wk.Health(),
+			Progress:  wk.Progress(),
+			Responder: txManagers[chainID].From(),
+			Tasks:     map[string]int{},
+		}
+		for _, h := range chainHandlers(chainID) {
+			v.Contracts = append(v.Contracts, contractView{Address: h.Address(), Type: h.Type()})
+		}
+		views = append(views, v)
+	}
+	for _, st := range taskSnapshot() {
+		for i := range views {
+			if views[i].ChainID == st.ChainID {
+				views[i].Tasks[taskPhase(st)]++
+			}
+		}
+	}
+	slices.SortFunc(views, func(a, b chainView) int { return cmp.Compare(a.ChainID, b.ChainID) })
+	writeJSON(w, http.StatusOK, views)
+}
+
+// handleRelay probes every relay endpoint; it answers 503 only if none is reachable.
+func handleRelay(w http.ResponseWriter, r *http.Request) {
+	views := relays.probe(r.Context())
+	status := http.StatusServiceUnavailable
+	if slices.ContainsFunc(views, func(v relayView) bool { return v.Reachable }) {
+		status = http.StatusOK
+	}
+	writeJSON(w, status, views)
+}
+
+// taskEvent is a lifecycle transition of a task, streamed by GET /tasks/events.
+type taskEvent struct {
+	Time     time.Time      `json:"time"`
+	Key      string         `json:"key"`
+	ChainID  int64          `json:"chainId"`
+	Contract common.Address `json:"contract"`
+	TaskID   common.Hash    `json:"taskId"`
+	From     string         `json:"from,omitempty"`
+	To       string         `json:"to"`
+
+	state TaskState
+}
+
+// taskEventHub fans task lifecycle transitions out to the streaming API clients.
+type taskEventHub struct {
+	mu   sync.Mutex
+	subs map[chan taskEvent]struct{}
+}
+
+var taskEvents = &taskEventHub{subs: make(map[chan taskEvent]struct{})}
+
+func (h *taskEventHub) subscribe() chan taskEvent {
+	ch := make(chan taskEvent, 256)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.subs[ch] = struct{}{}
+	return ch
+}
+
+func (h *taskEventHub) unsubscribe(ch chan taskEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subs[ch]; ok {
+		delete(h.subs, ch)
+		close(ch)
+	}
+}
+
+// publish records a transition of st from phase from, "" for a new task. Subscribers that fall
+// behind are disconnected rather than silently missing events.
+func (h *taskEventHub) publish(from string, st TaskState, to string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.subs) == 0 {
+		return
+	}
+	ev := taskEvent{
+		Time:     time.Now(),
+		Key:      st.Key().String(),
+		ChainID:  st.ChainID,
+		Contract: st.Contract,
+		TaskID:   st.TaskID,
+		From:     from,
+		To:       to,
+		state:    st,
+	}
+	for ch := range h.subs {
+		select {
+		case ch <- ev:
+		default:
+			delete(h.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// handleTaskEvents streams task lifecycle transitions as server-sent events until the client disconnects.
+// The status, chain, contract and collection parameters filter the stream like GET /tasks, status
+// matching the phase a task moves to.
+func handleTaskEvents(w http.ResponseWriter, r *http.Request) {
+	f, err := parseTaskFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming unsupported"))
+		return
+	}
+	ch := taskEvents.subscribe()
+	defer taskEvents.unsubscribe(ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	for {
+		select {
+		case ev, ok := <-ch:
+			if !ok {
+				return
+			}
+			if (f.phase != "" && ev.To != f.phase) || !f.matchKey(ev.state) {
+				continue
+			}
+			data, err := json.Marshal(ev)
+			if err != nil {
+				slog.ErrorContext(r.Context(), "Failed to encode task event", "task", ev.Key, "err", err)
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: task\ndata: %s\n\n", data); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"sum/internal/store"
+	"sum/internal/txmgr"
+)
+
+var (
+	adminTestSum = common.HexToAddress("0x9fE46736679d2D9a65F0992F2272dE9f3c7fa6e0")
+	adminTestNFT = common.HexToAddress("0x5FbDB2315678afecb367f032d93F642f64180aa3")
+)
+
+// setupAdminTest tracks three tasks: an unsigned sum task 0x01 and an nft-ownership task 0x02
+// awaiting its proof on chain 1, and a confirmed sum task 0x01 on chain 2.
+func setupAdminTest(t *testing.T) (unsigned, awaiting, confirmed TaskState) {
+	t.Helper()
+	req, err := json.Marshal(ownershipRequest(0, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	unsigned = TaskState{ChainID: 1, Contract: adminTestSum, TaskID: common.HexToHash("0x01"), Type: sumType, Req: json.RawMessage(`{}`)}
+	awaiting = TaskState{ChainID: 1, Contract: adminTestNFT, TaskID: common.HexToHash("0x02"), Type: nftOwnershipType, Req: req, SigEpoch: 3, SigRequestHash: "0xab"}
+	confirmed = TaskState{
+		ChainID: 2, Contract: adminTestSum, TaskID: common.HexToHash("0x01"), Type: sumType, Req: json.RawMessage(`{}`),
+		SigRequestHash: "0xcd", AggProof: []byte{1},
+		Submissions: map[int64]txmgr.Tx{2: {State: txmgr.StateConfirmed, Nonce: 4}},
+	}
+	tasks = make(map[store.TaskKey]TaskState)
+	for _, st := range []TaskState{unsigned, awaiting, confirmed} {
+		tasks[st.Key()] = st
+	}
+	t.Cleanup(func() { tasks = nil })
+	return unsigned, awaiting, confirmed
+}
+
+// serveAdmin makes a GET request to the node's HTTP endpoints and decodes the JSON response into v.
+func serveAdmin(t *testing.T, target string, v any) int {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	httpMux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("GET %s: Content-Type = %q", target, ct)
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("GET %s: %v: %s", target, err, rec.Body)
+	}
+	return rec.Code
+}
+
+func TestHandleListTasks(t *testing.T) {
+	unsigned, awaiting, confirmed := setupAdminTest(t)
+	key := func(st TaskState) string { return st.Key().String() }
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{key(awaiting), key(unsigned), key(confirmed)}},
+		{"?status=unsigned", []string{key(unsigned)}},
+		{"?status=confirmed", []string{key(confirmed)}},
+		{"?status=failed", []string{}},
+		{"?chain=2", []string{key(confirmed)}},
+		{"?contract=" + adminTestSum.Hex(), []string{key(unsigned), key(confirmed)}},
+		{"?collection=" + ownershipTestCollection.Hex(), []string{key(awaiting)}},
+		{"?chain=1&status=awaiting_proof", []string{key(awaiting)}},
+	}
+	for _, tt := range tests {
+		var views []taskView
+		if code := serveAdmin(t, "/tasks"+tt.query, &views); code != http.StatusOK {
+			t.Fatalf("GET /tasks%s = %d", tt.query, code)
+		}
+		got := []string{}
+		for _, v := range views {
+			got = append(got, v.Key)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("GET /tasks%s = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	for _, query := range []string{"?status=done", "?chain=one", "?contract=0x01", "?collection=nft"} {
+		var body map[string]string
+		if code := serveAdmin(t, "/tasks"+query, &body); code != http.StatusBadRequest || body["error"] == "" {
+			t.Errorf("GET /tasks%s = %d %v, want a 400 with an error", query, code, body)
+		}
+	}
+}
+
+func TestHandleGetTask(t *testing.T) {
+	_, awaiting, confirmed := setupAdminTest(t)
+
+	var v taskView
+	if code := serveAdmin(t, "/tasks/"+awaiting.TaskID.Hex(), &v); code != http.StatusOK {
+		t.Fatalf("GET /tasks/%s = %d", awaiting.TaskID.Hex(), code)
+	}
+	if v.Key != awaiting.Key().String() || v.Type != nftOwnershipType || v.Phase != phaseAwaitProof || v.SigEpoch != 3 || v.SigRequestHash != "0xab" {
+		t.Errorf("GET /tasks/%s = %+v", awaiting.TaskID.Hex(), v)
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, v.Request); err != nil || compact.String() != string(awaiting.Req) {
+		t.Errorf("request = %s, want %s", v.Request, awaiting.Req)
+	}
+
+	// Both chains have a task 0x01, the chain parameter picks one.
+	var conflict map[string]string
+	if code := serveAdmin(t, "/tasks/"+confirmed.TaskID.Hex(), &conflict); code != http.StatusConflict || !strings.Contains(conflict["error"], confirmed.Key().String()) {
+		t.Errorf("GET /tasks/%s = %d %v, want a 409 listing the tasks", confirmed.TaskID.Hex(), code, conflict)
+	}
+	v = taskView{}
+	if code := serveAdmin(t, "/tasks/"+confirmed.TaskID.Hex()+"?chain=2", &v); code != http.StatusOK || v.Key != confirmed.Key().String() || v.Phase != phaseConfirmed {
+		t.Errorf("GET /tasks/%s?chain=2 = %d %+v", confirmed.TaskID.Hex(), code, v)
+	}
+	if v.Submissions[2].Nonce != 4 {
+		t.Errorf("submissions = %v", v.Submissions)
+	}
+
+	missing := common.HexToHash("0x03").Hex()
+	var notFound map[string]string
+	if code := serveAdmin(t, "/tasks/"+missing, &notFound); code != http.StatusNotFound || notFound["error"] != "task "+missing+" not found" {
+		t.Errorf("GET /tasks/%s = %d %v, want a 404", missing, code, notFound)
+	}
+	if code := serveAdmin(t, "/tasks/"+awaiting.TaskID.Hex()+"?chain=2", &notFound); code != http.StatusNotFound {
+		t.Errorf("GET /tasks/%s?chain=2 = %d, want a 404", awaiting.TaskID.Hex(), code)
+	}
+	for _, id := range []string{"0x02", "task", common.HexToHash("0x02").Hex() + "00"} {
+		var body map[string]string
+		if code := serveAdmin(t, "/tasks/"+id, &body); code != http.StatusBadRequest {
+			t.Errorf("GET /tasks/%s = %d, want a 400", id, code)
+		}
+	}
+}
+
+func TestHandleChains(t *testing.T) {
+	setupAdminTest(t)
+	_, cli := newFakeChain(t, 1)
+	responder := common.HexToAddress("0x70997970C51812dc3A010C7d01b50e0d17dc79C8")
+	newTestHandler := func(typ string, address common.Address) TaskHandler {
+		h, err := newHandler(typ, address, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+	txm, err := txmgr.New(cli, big.NewInt(1), responder, nil, txmgr.Config{BumpPercent: 10, BaseFeeMultiplier: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wk := newChainWorker(1, cli, 91, false)
+	wk.recordProgress(100)
+	handlers = map[int64]map[common.Address]TaskHandler{1: {
+		adminTestSum: newTestHandler(sumType, adminTestSum),
+		adminTestNFT: newTestHandler(nftOwnershipType, adminTestNFT),
+	}}
+	workers = map[int64]*chainWorker{1: wk}
+	txManagers = map[int64]*txmgr.Manager{1: txm}
+	t.Cleanup(func() {
+		handlers, workers, txManagers = nil, nil, nil
+	})
+
+	var views []chainView
+	if code := serveAdmin(t, "/chains", &views); code != http.StatusOK {
+		t.Fatalf("GET /chains = %d", code)
+	}
+	if len(views) != 1 {
+		t.Fatalf("GET /chains = %+v, want chain 1", views)
+	}
+	v := views[0]
+	if v.ChainID != 1 || v.Responder != responder || v.Health.Status != chainHealthy {
+		t.Errorf("chain view = %+v", v)
+	}
+	if v.Progress.Head != 100 || v.Progress.Scanned != 90 || v.Progress.Lag != 10 {
+		t.Errorf("progress = %+v, want head 100, scanned 90, lag 10", v.Progress)
+	}
+	want := []contractView{{adminTestNFT, nftOwnershipType}, {adminTestSum, sumType}}
+	if !slices.Equal(v.Contracts, want) {
+		t.Errorf("contracts = %v, want %v", v.Contracts, want)
+	}
+	// The task of chain 2 is not counted, the chain has no worker.
+	if len(v.Tasks) != 2 || v.Tasks[phaseUnsigned] != 1 || v.Tasks[phaseAwaitProof] != 1 {
+		t.Errorf("tasks = %v, want one unsigned and one awaiting its proof", v.Tasks)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/go-errors/errors"
+
+	"sum/internal/contracts"
+)
+
+// resolveCheckedBlock picks the NFT chain block the ownership check is evaluated at.
+// An explicit req.CheckedBlock wins. Otherwise every operator derives the same block
+// from the task itself: the newest NFT chain block with timestamp <= req.CreatedAt.
+// Reading `latest` instead would make each operator sign a different payload.
+// The block must also be buried under the NFT chain's configured confirmations.
+func resolveCheckedBlock(ctx context.Context, cli *ethclient.Client, req contracts.NftOwnershipTaskRequest) (uint64, error) {
+	block := req.CheckedBlock
+	if block == 0 {
+		if req.CreatedAt == nil || req.CreatedAt.Sign() == 0 {
+			return 0, errors.Errorf("task has neither checkedBlock nor createdAt")
+		}
+		var err error
+		block, err = blockAtTimestamp(ctx, cli, req.CreatedAt.Uint64())
+		if err != nil {
+			return 0, err
+		}
+	}
+	if depth := nftChains[req.ChainId.Uint64()].Confirmations; depth != 0 {
+		head, err := cli.BlockNumber(ctx)
+		if err != nil {
+			return 0, errors.Errorf("failed to get head block number: %w", err)
+		}
+		if head < block+depth {
+			return 0, errors.Errorf("NFT chain block %d has %d of %d confirmations", block, head-min(head, block), depth)
+		}
+	}
+	return block, nil
+}
+
+// headerReader is the part of the NFT chain client blockAtTimestamp reads headers with.
+type headerReader interface {
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+}
+
+// blockAtTimestamp returns the number of the newest block with timestamp <= ts.
+// It fails until the chain head is newer than ts: several blocks may share a timestamp (L2s, anvil),
+// so before that a block with timestamp ts may still be produced and operators seeing
+// different heads would resolve different blocks.
+func blockAtTimestamp(ctx context.Context, cli headerReader, ts uint64) (uint64, error) {
+	head, err := cli.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return 0, errors.Errorf("failed to get head header: %w", err)
+	}
+	if head.Time <= ts {
+		return 0, errors.Errorf("NFT chain head %d (time %d) has not passed task time %d yet", head.Number.Uint64(), head.Time, ts)
+	}
+
+	lo, hi := uint64(0), head.Number.Uint64()
+	genesis, err := cli.HeaderByNumber(ctx, new(big.Int))
+	if err != nil {
+		return 0, errors.Errorf("failed to get genesis header: %w", err)
+	}
+	if genesis.Time > ts {
+		return 0, errors.Errorf("task time %d precedes NFT chain genesis time %d", ts, genesis.Time)
+	}
+
+	// Invariant: time(lo) <= ts < time(hi).
+	for hi-lo > 1 {
+		mid := lo + (hi-lo)/2
+		h, err := cli.HeaderByNumber(ctx, new(big.Int).SetUint64(mid))
+		if err != nil {
+			return 0, errors.Errorf("failed to get header %d: %w", mid, err)
+		}
+		if h.Time <= ts {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return lo, nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"slices"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/go-errors/errors"
+)
+
+// fakeHeaders serves headers whose timestamps are the slice values, block i having time[i].
+type fakeHeaders []uint64
+
+func (f fakeHeaders) HeaderByNumber(_ context.Context, number *big.Int) (*types.Header, error) {
+	n := uint64(len(f) - 1)
+	if number != nil {
+		n = number.Uint64()
+	}
+	if n >= uint64(len(f)) {
+		return nil, errors.Errorf("header %d not found", n)
+	}
+	return &types.Header{Number: new(big.Int).SetUint64(n), Time: f[n]}, nil
+}
+
+func TestBlockAtTimestamp(t *testing.T) {
+	chains := map[string]fakeHeaders{
+		"distinct":          {10, 12, 14, 16, 18, 20},
+		"equal runs":        {10, 12, 12, 12, 15, 15, 20},
+		"equal genesis run": {10, 10, 10, 11},
+		"all equal":         {7, 7, 7, 7, 7, 8},
+		"two blocks":        {3, 4},
+	}
+	for name, chain := range chains {
+		t.Run(name, func(t *testing.T) {
+			genesis, head := chain[0], chain[len(chain)-1]
+			for ts := genesis - 1; ts <= head+1; ts++ {
+				got, err := blockAtTimestamp(context.Background(), chain, ts)
+				switch {
+				case ts < genesis || ts >= head:
+					if err == nil {
+						t.Errorf("ts %d: got block %d, want an error", ts, got)
+					}
+				case err != nil:
+					t.Errorf("ts %d: %v", ts, err)
+				default:
+					// The newest block not after ts, found by a linear scan.
+					want := uint64(slices.IndexFunc(chain, func(time uint64) bool { return time > ts }) - 1)
+					if got != want {
+						t.Errorf("ts %d: got block %d, want %d", ts, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestBlockAtTimestampEqualRuns(t *testing.T) {
+	chain := fakeHeaders{10, 12, 12, 12, 15, 15, 20}
+	tests := []struct {
+		ts   uint64
+		want uint64
+	}{
+		{10, 0},
+		{11, 0},
+		{12, 3},
+		{14, 3},
+		{15, 5},
+		{19, 5},
+	}
+	for _, tt := range tests {
+		got, err := blockAtTimestamp(context.Background(), chain, tt.ts)
+		if err != nil || got != tt.want {
+			t.Errorf("blockAtTimestamp(%d) = %d, %v, want %d", tt.ts, got, err, tt.want)
+		}
+	}
+	// Until the head passes ts a block with the same timestamp may still follow.
+	if got, err := blockAtTimestamp(context.Background(), chain[:6], 15); err == nil {
+		t.Errorf("blockAtTimestamp(15) with head at time 15 = %d, want an error", got)
+	}
+}
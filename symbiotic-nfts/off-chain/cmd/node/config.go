@@ -0,0 +1,617 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"maps"
+	"math/big"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/go-errors/errors"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// envPrefix prefixes the environment variables overriding flags, e.g. SUM_NODE_RELAY_API_URL for --relay-api-url.
+const envPrefix = "SUM_NODE_"
+
+// fileConfig is the layout of the --config file. Scalar settings mirror flags and are applied to flags
+// not set on the command line or through the environment.
+type fileConfig struct {
+	Relay           relaySection     `yaml:"relay" toml:"relay"`
+	Signer          signerSection    `yaml:"signer" toml:"signer"`
+	LogLevel        *string          `yaml:"log_level" toml:"log_level"`
+	DBPath          *string          `yaml:"db_path" toml:"db_path"`
+	HTTPAddr        *string          `yaml:"http_addr" toml:"http_addr"`
+	OperatorAddress *string          `yaml:"operator_address" toml:"operator_address"`
+	Scan            scanSection      `yaml:"scan" toml:"scan"`
+	Tx              txSection        `yaml:"tx" toml:"tx"`
+	Policy          policySection    `yaml:"policy" toml:"policy"`
+	Health          healthSection    `yaml:"health" toml:"health"`
+	Tracing         tracingSection   `yaml:"tracing" toml:"tracing"`
+	Chains          []appChainConfig `yaml:"chains" toml:"chains"`
+	NFTChains       []nftChainConfig `yaml:"nft_chains" toml:"nft_chains"`
+}
+
+type relaySection struct {
+	// URL is the single relay endpoint of older configs, URLs lists the endpoints in failover order.
+	URL              *string   `yaml:"url" toml:"url"`
+	URLs             *[]string `yaml:"urls" toml:"urls"`
+	HealthInterval   *string   `yaml:"health_interval" toml:"health_interval"`
+	TLS              *bool     `yaml:"tls" toml:"tls"`
+	CAFile           *string   `yaml:"ca_file" toml:"ca_file"`
+	ServerName       *string   `yaml:"server_name" toml:"server_name"`
+	CertFile         *string   `yaml:"cert_file" toml:"cert_file"`
+	KeyFile          *string   `yaml:"key_file" toml:"key_file"`
+	TokenFile        *string   `yaml:"token_file" toml:"token_file"`
+	MaxRetries       *uint64   `yaml:"max_retries" toml:"max_retries"`
+	RetryBackoff     *string   `yaml:"retry_backoff" toml:"retry_backoff"`
+	Backoff          *string   `yaml:"backoff" toml:"backoff"`
+	KeepaliveTime    *string   `yaml:"keepalive_time" toml:"keepalive_time"`
+	KeepaliveTimeout *string   `yaml:"keepalive_timeout" toml:"keepalive_timeout"`
+}
+
+type signerSection struct {
+	Type                 *string       `yaml:"type" toml:"type"`
+	PrivateKey           *string       `yaml:"private_key" toml:"private_key"`
+	Keystore             *string       `yaml:"keystore" toml:"keystore"`
+	KeystorePasswordFile *string       `yaml:"keystore_password_file" toml:"keystore_password_file"`
+	External             *string       `yaml:"external" toml:"external"`
+	Address              *string       `yaml:"address" toml:"address"`
+	PKCS11               pkcs11Section `yaml:"pkcs11" toml:"pkcs11"`
+}
+
+type pkcs11Section struct {
+	Module     *string `yaml:"module" toml:"module"`
+	TokenLabel *string `yaml:"token_label" toml:"token_label"`
+	KeyLabel   *string `yaml:"key_label" toml:"key_label"`
+	PinFile    *string `yaml:"pin_file" toml:"pin_file"`
+}
+
+type scanSection struct {
+	BlockTag      *string `yaml:"block_tag" toml:"block_tag"`
+	Confirmations *uint64 `yaml:"confirmations" toml:"confirmations"`
+	MaxBlockRange *uint64 `yaml:"max_block_range" toml:"max_block_range"`
+	ErrorBudget   *int    `yaml:"error_budget" toml:"error_budget"`
+	MaxBackoff    *string `yaml:"max_backoff" toml:"max_backoff"`
+}
+
+type txSection struct {
+	MaxFeeGwei        *uint64 `yaml:"max_fee_gwei" toml:"max_fee_gwei"`
+	MaxTipGwei        *uint64 `yaml:"max_tip_gwei" toml:"max_tip_gwei"`
+	BaseFeeMultiplier *uint64 `yaml:"base_fee_multiplier" toml:"base_fee_multiplier"`
+	FeeBumpPercent    *uint64 `yaml:"fee_bump_percent" toml:"fee_bump_percent"`
+	StuckAfter        *string `yaml:"stuck_after" toml:"stuck_after"`
+	MaxReplacements   *int    `yaml:"max_replacements" toml:"max_replacements"`
+	Confirmations     *uint64 `yaml:"confirmations" toml:"confirmations"`
+}
+
+type policySection struct {
+	SubmitStagger      *string `yaml:"submit_stagger" toml:"submit_stagger"`
+	ResignMargin       *string `yaml:"resign_margin" toml:"resign_margin"`
+	AggregationTimeout *string `yaml:"aggregation_timeout" toml:"aggregation_timeout"`
+}
+
+type healthSection struct {
+	MaxScanLag    *uint64  `yaml:"max_scan_lag" toml:"max_scan_lag"`
+	MinBalanceEth *float64 `yaml:"min_balance_eth" toml:"min_balance_eth"`
+}
+
+type tracingSection struct {
+	OTLPEndpoint *string `yaml:"otlp_endpoint" toml:"otlp_endpoint"`
+	OTLPInsecure *bool   `yaml:"otlp_insecure" toml:"otlp_insecure"`
+}
+
+type appChainConfig struct {
+	ChainID         int64            `yaml:"chain_id" toml:"chain_id"`
+	RPCs            []string         `yaml:"rpcs" toml:"rpcs"`
+	Contracts       []contractConfig `yaml:"contracts" toml:"contracts"`
+	Confirmations   *uint64          `yaml:"confirmations" toml:"confirmations"`
+	MaxBlockRange   *uint64          `yaml:"max_block_range" toml:"max_block_range"`
+	DeliveryTargets []int64          `yaml:"delivery_targets" toml:"delivery_targets"`
+}
+
+type contractConfig struct {
+	Address    string `yaml:"address" toml:"address"`
+	Type       string `yaml:"type" toml:"type"`
+	StartBlock uint64 `yaml:"start_block" toml:"start_block"`
+}
+
+type nftChainConfig struct {
+	ChainID       uint64   `yaml:"chain_id" toml:"chain_id"`
+	RPCs          []string `yaml:"rpcs" toml:"rpcs"`
+	Confirmations uint64   `yaml:"confirmations" toml:"confirmations"`
+	RateLimit     float64  `yaml:"rate_limit" toml:"rate_limit"`
+	RateBurst     int      `yaml:"rate_burst" toml:"rate_burst"`
+}
+
+// appChainSpec is a validated app chain, from the config file or the --evm-rpc-urls flag family.
+type appChainSpec struct {
+	// ChainID is 0 if not configured, the RPC's chain ID is used then.
+	ChainID   int64
+	RPCs      []string
+	Contracts []contractSpec
+}
+
+type contractSpec struct {
+	Address    common.Address
+	Type       string
+	StartBlock uint64
+}
+
+// nftChainSpec is a validated NFT chain.
+type nftChainSpec struct {
+	RPCs []string
+	// Confirmations is how deep the checked block must be below the head before ownership is read.
+	Confirmations uint64
+	// RateLimit caps requests per second to the chain's RPCs, 0 means unlimited.
+	RateLimit float64
+	RateBurst int
+}
+
+var (
+	appChains []appChainSpec
+	nftChains map[uint64]nftChainSpec
+)
+
+// loadConfig applies the --config file and SUM_NODE_* environment variables to the flags not set
+// on the command line, then builds the chain specs. Flags win over the environment, which wins over the file.
+func loadConfig(fs *pflag.FlagSet, path string) error {
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *pflag.Flag) { explicit[f.Name] = true })
+
+	var fc fileConfig
+	if path != "" {
+		if err := readConfigFile(path, &fc); err != nil {
+			return err
+		}
+		if fc.Relay.URL != nil && fc.Relay.URLs != nil {
+			return errors.Errorf("%s: relay.url and relay.urls are both set, use one of them", path)
+		}
+		for _, s := range fc.settings() {
+			if explicit[s.flag] {
+				continue
+			}
+			if err := s.apply(fs); err != nil {
+				return errors.Errorf("%s: %s: %w", path, s.key, err)
+			}
+		}
+	}
+
+	var envErr error
+	fs.VisitAll(func(f *pflag.Flag) {
+		if explicit[f.Name] || f.Name == "config" {
+			return
+		}
+		name := envPrefix + strings.ToUpper(strings.ReplaceAll(f.Name, "-", "_"))
+		if v, ok := os.LookupEnv(name); ok {
+			if err := setFlag(fs, f.Name, v); err != nil {
+				envErr = errors.Join(envErr, errors.Errorf("%s: %w", name, err))
+			}
+		}
+	})
+	if envErr != nil {
+		return envErr
+	}
+
+	var err error
+	if fs.Changed("evm-rpc-urls") {
+		if len(fc.Chains) != 0 {
+			return errors.Errorf("app chains are configured both in %s and by --evm-rpc-urls, use one of them", path)
+		}
+		appChains, err = chainsFromFlags()
+	} else {
+		appChains, err = fc.appChainSpecs(path)
+	}
+	if err != nil {
+		return err
+	}
+	if len(appChains) == 0 {
+		return errors.Errorf("no app chains configured, set --evm-rpc-urls and --contract-addresses or chains in --config")
+	}
+
+	nftChains, err = fc.nftChainSpecs(path)
+	if err != nil {
+		return err
+	}
+	flagNFT, err := parseNFTRPCMap(cfg.nftRpcMap)
+	if err != nil {
+		return err
+	}
+	maps.Copy(nftChains, flagNFT)
+
+	// The file's per chain settings are defaults for the per chain flags.
+	chainConfirmations = make(map[int64]uint64)
+	chainBlockRanges = make(map[int64]uint64)
+	deliveryTargets = make(map[int64][]int64)
+	for _, c := range fc.Chains {
+		if c.Confirmations != nil {
+			chainConfirmations[c.ChainID] = *c.Confirmations
+		}
+		if c.MaxBlockRange != nil {
+			chainBlockRanges[c.ChainID] = *c.MaxBlockRange
+		}
+		for _, dest := range c.DeliveryTargets {
+			if dest != c.ChainID && !slices.Contains(deliveryTargets[c.ChainID], dest) {
+				deliveryTargets[c.ChainID] = append(deliveryTargets[c.ChainID], dest)
+			}
+		}
+	}
+	flagConfirmations, err := parseChainValues(cfg.chainConfirmations, "chain confirmations")
+	if err != nil {
+		return err
+	}
+	maps.Copy(chainConfirmations, flagConfirmations)
+	flagBlockRanges, err := parseChainValues(cfg.chainBlockRanges, "chain max block ranges")
+	if err != nil {
+		return err
+	}
+	maps.Copy(chainBlockRanges, flagBlockRanges)
+	flagTargets, err := parseDeliveryTargets(cfg.deliveryTargets)
+	if err != nil {
+		return err
+	}
+	maps.Copy(deliveryTargets, flagTargets)
+	return nil
+}
+
+// readConfigFile decodes a YAML or TOML file, chosen by extension. ${VAR} references are
+// expanded from the environment first, so secrets like RPC API keys can stay out of the file.
+func readConfigFile(path string, fc *fileConfig) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return errors.Errorf("failed to read config file: %w", err)
+	}
+	var missing []string
+	data := os.Expand(string(raw), func(name string) string {
+		v, ok := os.LookupEnv(name)
+		if !ok && !slices.Contains(missing, name) {
+			missing = append(missing, name)
+		}
+		return v
+	})
+	if len(missing) != 0 {
+		return errors.Errorf("%s: unset environment variables referenced: %s", path, strings.Join(missing, ", "))
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(strings.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(fc); err != nil {
+			return errors.Errorf("%s: %w", path, err)
+		}
+	case ".toml":
+		md, err := toml.NewDecoder(bytes.NewBufferString(data)).Decode(fc)
+		if err != nil {
+			return errors.Errorf("%s: %w", path, err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) != 0 {
+			keys := make([]string, len(undecoded))
+			for i, k := range undecoded {
+				keys[i] = k.String()
+			}
+			return errors.Errorf("%s: unknown keys: %s", path, strings.Join(keys, ", "))
+		}
+	default:
+		return errors.Errorf("%s: unsupported config file extension, use .yaml, .yml or .toml", path)
+	}
+	return nil
+}
+
+// setting ties a scalar or list file setting to the flag it sets.
+type setting struct {
+	key   string
+	flag  string
+	value any
+}
+
+func (c *fileConfig) settings() []setting {
+	return []setting{
+		{"relay.url", "relay-api-url", c.Relay.URL},
+		{"relay.urls", "relay-api-url", c.Relay.URLs},
+		{"relay.health_interval", "relay-health-interval", c.Relay.HealthInterval},
+		{"relay.tls", "relay-tls", c.Relay.TLS},
+		{"relay.ca_file", "relay-ca-file", c.Relay.CAFile},
+		{"relay.server_name", "relay-server-name", c.Relay.ServerName},
+		{"relay.cert_file", "relay-cert-file", c.Relay.CertFile},
+		{"relay.key_file", "relay-key-file", c.Relay.KeyFile},
+		{"relay.token_file", "relay-token-file", c.Relay.TokenFile},
+		{"relay.max_retries", "relay-max-retries", c.Relay.MaxRetries},
+		{"relay.retry_backoff", "relay-retry-backoff", c.Relay.RetryBackoff},
+		{"relay.backoff", "relay-backoff", c.Relay.Backoff},
+		{"relay.keepalive_time", "relay-keepalive-time", c.Relay.KeepaliveTime},
+		{"relay.keepalive_timeout", "relay-keepalive-timeout", c.Relay.KeepaliveTimeout},
+		{"signer.type", "signer", c.Signer.Type},
+		{"signer.private_key", "private-key", c.Signer.PrivateKey},
+		{"signer.keystore", "keystore", c.Signer.Keystore},
+		{"signer.keystore_password_file", "keystore-password-file", c.Signer.KeystorePasswordFile},
+		{"signer.external", "external-signer", c.Signer.External},
+		{"signer.address", "signer-address", c.Signer.Address},
+		{"signer.pkcs11.module", "pkcs11-module", c.Signer.PKCS11.Module},
+		{"signer.pkcs11.token_label", "pkcs11-token-label", c.Signer.PKCS11.TokenLabel},
+		{"signer.pkcs11.key_label", "pkcs11-key-label", c.Signer.PKCS11.KeyLabel},
+		{"signer.pkcs11.pin_file", "pkcs11-pin-file", c.Signer.PKCS11.PinFile},
+		{"log_level", "log-level", c.LogLevel},
+		{"db_path", "db-path", c.DBPath},
+		{"http_addr", "http-addr", c.HTTPAddr},
+		{"operator_address", "operator-address", c.OperatorAddress},
+		{"scan.block_tag", "block-tag", c.Scan.BlockTag},
+		{"scan.confirmations", "confirmations", c.Scan.Confirmations},
+		{"scan.max_block_range", "max-block-range", c.Scan.MaxBlockRange},
+		{"scan.error_budget", "chain-error-budget", c.Scan.ErrorBudget},
+		{"scan.max_backoff", "chain-max-backoff", c.Scan.MaxBackoff},
+		{"tx.max_fee_gwei", "max-fee-gwei", c.Tx.MaxFeeGwei},
+		{"tx.max_tip_gwei", "max-tip-gwei", c.Tx.MaxTipGwei},
+		{"tx.base_fee_multiplier", "base-fee-multiplier", c.Tx.BaseFeeMultiplier},
+		{"tx.fee_bump_percent", "fee-bump-percent", c.Tx.FeeBumpPercent},
+		{"tx.stuck_after", "tx-stuck-after", c.Tx.StuckAfter},
+		{"tx.max_replacements", "tx-max-replacements", c.Tx.MaxReplacements},
+		{"tx.confirmations", "tx-confirmations", c.Tx.Confirmations},
+		{"policy.submit_stagger", "submit-stagger", c.Policy.SubmitStagger},
+		{"policy.resign_margin", "resign-margin", c.Policy.ResignMargin},
+		{"policy.aggregation_timeout", "aggregation-timeout", c.Policy.AggregationTimeout},
+		{"health.max_scan_lag", "max-scan-lag", c.Health.MaxScanLag},
+		{"health.min_balance_eth", "min-balance-eth", c.Health.MinBalanceEth},
+		{"tracing.otlp_endpoint", "otlp-endpoint", c.Tracing.OTLPEndpoint},
+		{"tracing.otlp_insecure", "otlp-insecure", c.Tracing.OTLPInsecure},
+	}
+}
+
+// apply sets the flag if the setting is present in the file.
+func (s setting) apply(fs *pflag.FlagSet) error {
+	var v string
+	switch p := s.value.(type) {
+	case *string:
+		if p == nil {
+			return nil
+		}
+		v = *p
+	case *uint64:
+		if p == nil {
+			return nil
+		}
+		v = strconv.FormatUint(*p, 10)
+	case *int:
+		if p == nil {
+			return nil
+		}
+		v = strconv.Itoa(*p)
+	case *bool:
+		if p == nil {
+			return nil
+		}
+		v = strconv.FormatBool(*p)
+	case *float64:
+		if p == nil {
+			return nil
+		}
+		v = strconv.FormatFloat(*p, 'g', -1, 64)
+	case *[]string:
+		if p == nil {
+			return nil
+		}
+		v = strings.Join(*p, ",")
+	default:
+		panic(fmt.Sprintf("unsupported setting type %T", s.value))
+	}
+	return setFlag(fs, s.flag, v)
+}
+
+// setFlag sets the flag from its string form. pflag appends to a slice flag that is already set,
+// so a slice set before, e.g. by the config file, is replaced to let the environment override it.
+func setFlag(fs *pflag.FlagSet, name, v string) error {
+	f := fs.Lookup(name)
+	sv, ok := f.Value.(pflag.SliceValue)
+	if !ok || !f.Changed {
+		return fs.Set(name, v)
+	}
+	var vals []string
+	if v != "" {
+		var err error
+		if vals, err = csv.NewReader(strings.NewReader(v)).Read(); err != nil {
+			return errors.Errorf("invalid list %q: %w", v, err)
+		}
+	}
+	return sv.Replace(vals)
+}
+
+func (c *fileConfig) appChainSpecs(path string) ([]appChainSpec, error) {
+	var (
+		specs []appChainSpec
+		errs  []error
+	)
+	seen := make(map[int64]int)
+	for i, ch := range c.Chains {
+		key := fmt.Sprintf("%s: chains[%d]", path, i)
+		if ch.ChainID <= 0 {
+			errs = append(errs, errors.Errorf("%s.chain_id: must be set to the chain's ID", key))
+		} else if j, dup := seen[ch.ChainID]; dup {
+			errs = append(errs, errors.Errorf("%s.chain_id: chain %d is already configured by chains[%d]", key, ch.ChainID, j))
+		}
+		seen[ch.ChainID] = i
+		errs = append(errs, validateRPCs(key+".rpcs", ch.RPCs)...)
+		if len(ch.Contracts) == 0 {
+			errs = append(errs, errors.Errorf("%s.contracts: at least one task contract is required", key))
+		}
+		spec := appChainSpec{ChainID: ch.ChainID, RPCs: ch.RPCs}
+		for j, ct := range ch.Contracts {
+			ckey := fmt.Sprintf("%s.contracts[%d]", key, j)
+			cs, err := newContractSpec(ct.Address, ct.Type, ct.StartBlock)
+			if err != nil {
+				errs = append(errs, errors.Errorf("%s: %w", ckey, err))
+				continue
+			}
+			if k := slices.IndexFunc(spec.Contracts, func(o contractSpec) bool { return o.Address == cs.Address }); k >= 0 {
+				errs = append(errs, errors.Errorf("%s.address: %s is already configured by contracts[%d]", ckey, cs.Address.Hex(), k))
+				continue
+			}
+			spec.Contracts = append(spec.Contracts, cs)
+		}
+		for _, dest := range ch.DeliveryTargets {
+			if dest <= 0 {
+				errs = append(errs, errors.Errorf("%s.delivery_targets: invalid chain ID %d", key, dest))
+			}
+		}
+		specs = append(specs, spec)
+	}
+	return specs, errors.Join(errs...)
+}
+
+func (c *fileConfig) nftChainSpecs(path string) (map[uint64]nftChainSpec, error) {
+	var errs []error
+	specs := make(map[uint64]nftChainSpec)
+	for i, ch := range c.NFTChains {
+		key := fmt.Sprintf("%s: nft_chains[%d]", path, i)
+		if ch.ChainID == 0 {
+			errs = append(errs, errors.Errorf("%s.chain_id: must be set to the chain's ID", key))
+		} else if _, dup := specs[ch.ChainID]; dup {
+			errs = append(errs, errors.Errorf("%s.chain_id: chain %d is configured twice", key, ch.ChainID))
+		}
+		errs = append(errs, validateRPCs(key+".rpcs", ch.RPCs)...)
+		if ch.RateLimit < 0 {
+			errs = append(errs, errors.Errorf("%s.rate_limit: must not be negative", key))
+		}
+		if ch.RateBurst < 0 {
+			errs = append(errs, errors.Errorf("%s.rate_burst: must not be negative", key))
+		}
+		if ch.RateLimit > 0 {
+			for j, u := range ch.RPCs {
+				if !strings.HasPrefix(u, "http://") && !strings.HasPrefix(u, "https://") {
+					errs = append(errs, errors.Errorf("%s.rpcs[%d]: rate limits are only supported for http(s) RPCs", key, j))
+				}
+			}
+		}
+		specs[ch.ChainID] = nftChainSpec{
+			RPCs:          ch.RPCs,
+			Confirmations: ch.Confirmations,
+			RateLimit:     ch.RateLimit,
+			RateBurst:     ch.RateBurst,
+		}
+	}
+	return specs, errors.Join(errs...)
+}
+
+// validateRPCs checks that at least one RPC is given and every one is a URL go-ethereum can dial.
+func validateRPCs(key string, rpcs []string) []error {
+	if len(rpcs) == 0 {
+		return []error{errors.Errorf("%s: at least one RPC URL is required", key)}
+	}
+	var errs []error
+	for i, raw := range rpcs {
+		if err := validateRPCURL(raw); err != nil {
+			errs = append(errs, errors.Errorf("%s[%d]: %w", key, i, err))
+		}
+	}
+	return errs
+}
+
+func validateRPCURL(raw string) error {
+	if strings.TrimSpace(raw) == "" {
+		return errors.New("empty RPC URL")
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return errors.Errorf("invalid RPC URL %q: %w", raw, err)
+	}
+	switch u.Scheme {
+	case "http", "https", "ws", "wss":
+		if u.Host == "" {
+			return errors.Errorf("RPC URL %q has no host", raw)
+		}
+	case "":
+		// An IPC socket path.
+	default:
+		return errors.Errorf("RPC URL %q has unsupported scheme %q", raw, u.Scheme)
+	}
+	return nil
+}
+
+func newContractSpec(address, typ string, startBlock uint64) (contractSpec, error) {
+	address = strings.TrimSpace(address)
+	if !common.IsHexAddress(address) {
+		return contractSpec{}, errors.Errorf("invalid contract address %q", address)
+	}
+	typ = strings.TrimSpace(typ)
+	if typ == "" {
+		typ = nftOwnershipType
+	}
+	if !slices.Contains(handlerTypes(), typ) {
+		return contractSpec{}, errors.Errorf("unknown contract type %q (supported: %s)", typ, strings.Join(handlerTypes(), ", "))
+	}
+	return contractSpec{Address: common.HexToAddress(address), Type: typ, StartBlock: startBlock}, nil
+}
+
+// chainsFromFlags builds the app chains from the aligned --evm-rpc-urls, --contract-addresses,
+// --contract-types and --start-block lists, one spec per RPC. Specs resolving to the same chain are merged at startup.
+func chainsFromFlags() ([]appChainSpec, error) {
+	if len(cfg.contractAddresses) != len(cfg.evmRpcURLs) {
+		return nil, errors.Errorf("mismatched lengths: evm-rpc-urls=%d, contract-addresses=%d", len(cfg.evmRpcURLs), len(cfg.contractAddresses))
+	}
+	if len(cfg.contractTypes) != 0 && len(cfg.contractTypes) != len(cfg.contractAddresses) {
+		return nil, errors.Errorf("mismatched lengths: contract-types=%d, contract-addresses=%d", len(cfg.contractTypes), len(cfg.contractAddresses))
+	}
+	if len(cfg.startBlocks) != 0 && len(cfg.startBlocks) != len(cfg.contractAddresses) {
+		return nil, errors.Errorf("mismatched lengths: start-block=%d, contract-addresses=%d", len(cfg.startBlocks), len(cfg.contractAddresses))
+	}
+	var specs []appChainSpec
+	for i, rpcURL := range cfg.evmRpcURLs {
+		if err := validateRPCURL(rpcURL); err != nil {
+			return nil, errors.Errorf("evm-rpc-urls[%d]: %w", i, err)
+		}
+		var typ string
+		if len(cfg.contractTypes) != 0 {
+			typ = cfg.contractTypes[i]
+		}
+		var start uint64
+		if len(cfg.startBlocks) != 0 && strings.TrimSpace(cfg.startBlocks[i]) != "" {
+			var err error
+			start, err = strconv.ParseUint(strings.TrimSpace(cfg.startBlocks[i]), 10, 64)
+			if err != nil {
+				return nil, errors.Errorf("invalid start block %q for contract %s: %w", cfg.startBlocks[i], cfg.contractAddresses[i], err)
+			}
+		}
+		cs, err := newContractSpec(cfg.contractAddresses[i], typ, start)
+		if err != nil {
+			return nil, errors.Errorf("contract-addresses[%d]: %w", i, err)
+		}
+		specs = append(specs, appChainSpec{RPCs: []string{rpcURL}, Contracts: []contractSpec{cs}})
+	}
+	return specs, nil
+}
+
+// parseNFTRPCMap parses '1=https://...,11155111=https://...' into NFT chains with a single RPC each.
+func parseNFTRPCMap(s string) (map[uint64]nftChainSpec, error) {
+	m := make(map[uint64]nftChainSpec)
+	if strings.TrimSpace(s) == "" {
+		return m, nil
+	}
+	for _, p := range strings.Split(s, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		kv := strings.SplitN(p, "=", 2)
+		if len(kv) != 2 {
+			return nil, errors.Errorf("invalid NFT RPC map entry %q, expected chainID=url", p)
+		}
+		cid, ok := new(big.Int).SetString(strings.TrimSpace(kv[0]), 10)
+		if !ok || !cid.IsUint64() || cid.Sign() == 0 {
+			return nil, errors.Errorf("invalid chain ID in NFT RPC map entry %q", p)
+		}
+		rpcURL := strings.TrimSpace(kv[1])
+		if err := validateRPCURL(rpcURL); err != nil {
+			return nil, errors.Errorf("NFT RPC map entry %q: %w", p, err)
+		}
+		m[cid.Uint64()] = nftChainSpec{RPCs: []string{rpcURL}}
+	}
+	return m, nil
+}
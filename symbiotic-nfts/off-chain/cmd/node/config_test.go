@@ -0,0 +1,152 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+// testFlags resets cfg and parses args into a fresh flag set.
+func testFlags(t *testing.T, args ...string) *pflag.FlagSet {
+	t.Helper()
+	cfg = config{}
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	registerFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	return fs
+}
+
+func writeConfig(t *testing.T, name, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+const testYAMLConfig = `
+log_level: warn
+relay:
+  urls: [file-a:1, file-b:1]
+tx:
+  max_fee_gwei: 50
+chains:
+  - chain_id: 31337
+    rpcs: [http://127.0.0.1:8545]
+    contracts:
+      - address: "0x5FbDB2315678afecb367f032d93F642f64180aa3"
+`
+
+const testTOMLConfig = `
+log_level = "warn"
+
+[relay]
+urls = ["file-a:1", "file-b:1"]
+
+[tx]
+max_fee_gwei = 50
+
+[[chains]]
+chain_id = 31337
+rpcs = ["http://127.0.0.1:8545"]
+
+[[chains.contracts]]
+address = "0x5FbDB2315678afecb367f032d93F642f64180aa3"
+`
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	files := map[string]string{
+		"node.yaml": testYAMLConfig,
+		"node.toml": testTOMLConfig,
+	}
+	tests := []struct {
+		name string
+		args []string
+		env  map[string]string
+
+		logLevel   string
+		relayURLs  []string
+		maxFeeGwei uint64
+		feeBump    uint64
+	}{
+		{
+			name:     "file over default",
+			logLevel: "warn", relayURLs: []string{"file-a:1", "file-b:1"}, maxFeeGwei: 50, feeBump: 20,
+		},
+		{
+			name: "env over file",
+			env: map[string]string{
+				"SUM_NODE_LOG_LEVEL":        "error",
+				"SUM_NODE_RELAY_API_URL":    "env:1",
+				"SUM_NODE_MAX_FEE_GWEI":     "60",
+				"SUM_NODE_FEE_BUMP_PERCENT": "30",
+			},
+			logLevel: "error", relayURLs: []string{"env:1"}, maxFeeGwei: 60, feeBump: 30,
+		},
+		{
+			name: "flag over env",
+			args: []string{"--log-level", "debug", "-r", "flag-a:1,flag-b:1", "--max-fee-gwei", "70"},
+			env: map[string]string{
+				"SUM_NODE_LOG_LEVEL":     "error",
+				"SUM_NODE_RELAY_API_URL": "env:1",
+				"SUM_NODE_MAX_FEE_GWEI":  "60",
+			},
+			logLevel: "debug", relayURLs: []string{"flag-a:1", "flag-b:1"}, maxFeeGwei: 70, feeBump: 20,
+		},
+	}
+	for name, data := range files {
+		path := writeConfig(t, name, data)
+		for _, tt := range tests {
+			t.Run(name+"/"+tt.name, func(t *testing.T) {
+				for k, v := range tt.env {
+					t.Setenv(k, v)
+				}
+				fs := testFlags(t, tt.args...)
+				if err := loadConfig(fs, path); err != nil {
+					t.Fatal(err)
+				}
+				if cfg.logLevel != tt.logLevel {
+					t.Errorf("log level = %q, want %q", cfg.logLevel, tt.logLevel)
+				}
+				if !slices.Equal(cfg.relayURLs, tt.relayURLs) {
+					t.Errorf("relay URLs = %v, want %v", cfg.relayURLs, tt.relayURLs)
+				}
+				if cfg.maxFeeGwei != tt.maxFeeGwei {
+					t.Errorf("max fee = %d, want %d", cfg.maxFeeGwei, tt.maxFeeGwei)
+				}
+				if cfg.feeBumpPercent != tt.feeBump {
+					t.Errorf("fee bump = %d, want %d", cfg.feeBumpPercent, tt.feeBump)
+				}
+				if len(appChains) != 1 || appChains[0].ChainID != 31337 || len(appChains[0].Contracts) != 1 {
+					t.Errorf("app chains = %+v, want chain 31337 with one contract", appChains)
+				}
+			})
+		}
+	}
+}
+
+func TestLoadConfigUnknownKey(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		key  string
+	}{
+		{"node.yaml", testYAMLConfig + "log_levle: debug\n", "log_levle"},
+		{"node.toml", strings.Replace(testTOMLConfig, "[tx]\n", "[tx]\nmax_fee = 1\n", 1), "tx.max_fee"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := loadConfig(testFlags(t), writeConfig(t, tt.name, tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.key) {
+				t.Fatalf("loadConfig() error = %v, want one naming %s", err, tt.key)
+			}
+		})
+	}
+}
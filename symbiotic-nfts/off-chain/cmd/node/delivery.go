@@ -0,0 +1,92 @@
+package main
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-errors/errors"
+)
+
+// deliveryTargets maps an origin app chain to the chains its attested responses are mirrored to,
+// in addition to the origin itself. On a destination the response goes to the single contract of the
+// task's type, which must accept the origin's task IDs.
+var deliveryTargets map[int64][]int64
+
+// parseDeliveryTargets parses '31337=11155111|84532,11155111=31337'.
+func parseDeliveryTargets(s string) (map[int64][]int64, error) {
+	m := make(map[int64][]int64)
+	if strings.TrimSpace(s) == "" {
+		return m, nil
+	}
+	for _, p := range strings.Split(s, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		kv := strings.SplitN(p, "=", 2)
+		if len(kv) != 2 {
+			return nil, errors.Errorf("invalid delivery targets entry %q", p)
+		}
+		origin, err := strconv.ParseInt(strings.TrimSpace(kv[0]), 10, 64)
+		if err == nil && origin <= 0 {
+			err = errors.Errorf("chain IDs must be positive")
+		}
+		if err != nil {
+			return nil, errors.Errorf("invalid origin chain ID in delivery targets entry %q: %w", p, err)
+		}
+		for _, d := range strings.Split(kv[1], "|") {
+			dest, err := strconv.ParseInt(strings.TrimSpace(d), 10, 64)
+			if err == nil && dest <= 0 {
+				err = errors.Errorf("chain IDs must be positive")
+			}
+			if err != nil {
+				return nil, errors.Errorf("invalid destination chain ID in delivery targets entry %q: %w", p, err)
+			}
+			if dest != origin && !slices.Contains(m[origin], dest) {
+				m[origin] = append(m[origin], dest)
+			}
+		}
+	}
+	return m, nil
+}
+
+// validateDeliveryTargets checks that every origin and destination is bound, and that each destination
+// runs exactly one contract of every type on the origin so responses can be routed to it.
+func validateDeliveryTargets() error {
+	for origin, dests := range deliveryTargets {
+		if _, ok := handlers[origin]; !ok {
+			return errors.Errorf("delivery origin chain %d is not bound", origin)
+		}
+		for _, dest := range dests {
+			if _, ok := handlers[dest]; !ok {
+				return errors.Errorf("delivery destination chain %d of origin %d is not bound", dest, origin)
+			}
+			for _, h := range chainHandlers(origin) {
+				if _, ok := handlerOfType(dest, h.Type()); !ok {
+					return errors.Errorf("delivery destination chain %d of origin %d needs exactly one %s contract", dest, origin, h.Type())
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// taskTargets returns the chains the task's response is delivered to, origin first.
+func taskTargets(st TaskState) []int64 {
+	return append([]int64{st.ChainID}, deliveryTargets[st.ChainID]...)
+}
+
+// targetHandler returns the contract receiving the task's response on chainID: the task's own contract
+// on its origin chain, the contract of the task's type on a delivery destination.
+func targetHandler(st TaskState, chainID int64) (TaskHandler, bool) {
+	if chainID == st.ChainID {
+		h, ok := handlers[chainID][st.Contract]
+		return h, ok
+	}
+	return handlerOfType(chainID, st.Type)
+}
+
+func isTaskTarget(st TaskState, chainID int64) bool {
+	return slices.Contains(taskTargets(st), chainID)
+}
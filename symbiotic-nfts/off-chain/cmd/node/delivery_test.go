@@ -0,0 +1,91 @@
+package main
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestParseDeliveryTargets(t *testing.T) {
+	tests := []struct {
+		in   string
+		want map[int64][]int64
+		err  string
+	}{
+		{in: "", want: map[int64][]int64{}},
+		{in: " , ", want: map[int64][]int64{}},
+		{in: "31337=11155111|84532,11155111=31337", want: map[int64][]int64{31337: {11155111, 84532}, 11155111: {31337}}},
+		{in: " 1 = 2 | 3 ", want: map[int64][]int64{1: {2, 3}}},
+		{in: "1=2|2|3|2", want: map[int64][]int64{1: {2, 3}}},
+		{in: "1=2,1=3|2", want: map[int64][]int64{1: {2, 3}}},
+		{in: "1=1", want: map[int64][]int64{}},
+		{in: "1=1|2", want: map[int64][]int64{1: {2}}},
+		{in: "1", err: "invalid delivery targets entry"},
+		{in: "1=", err: "invalid destination chain ID"},
+		{in: "1=2|", err: "invalid destination chain ID"},
+		{in: "=2", err: "invalid origin chain ID"},
+		{in: "x=2", err: "invalid origin chain ID"},
+		{in: "0=2", err: "invalid origin chain ID"},
+		{in: "1=-2", err: "invalid destination chain ID"},
+	}
+	for _, tt := range tests {
+		got, err := parseDeliveryTargets(tt.in)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("parseDeliveryTargets(%q) error = %v, want %q", tt.in, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseDeliveryTargets(%q): %v", tt.in, err)
+			continue
+		}
+		if !maps.EqualFunc(got, tt.want, slices.Equal) {
+			t.Errorf("parseDeliveryTargets(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestValidateDeliveryTargets(t *testing.T) {
+	addr := func(b byte) common.Address { return common.Address{19: b} }
+	bind := func(typ string, address common.Address) TaskHandler {
+		h, err := newHandler(typ, address, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+	handlers = map[int64]map[common.Address]TaskHandler{
+		1: {addr(1): bind(nftOwnershipType, addr(1)), addr(2): bind(sumType, addr(2))},
+		2: {addr(3): bind(nftOwnershipType, addr(3)), addr(4): bind(sumType, addr(4))},
+		3: {addr(5): bind(nftOwnershipType, addr(5))},
+		4: {addr(6): bind(nftOwnershipType, addr(6)), addr(7): bind(nftOwnershipType, addr(7))},
+	}
+	t.Cleanup(func() { handlers, deliveryTargets = nil, nil })
+
+	tests := []struct {
+		targets string
+		err     string
+	}{
+		{"", ""},
+		{"1=2,2=1", ""},
+		{"3=1|2", ""},
+		{"5=1", "origin chain 5 is not bound"},
+		{"1=5", "destination chain 5 of origin 1 is not bound"},
+		{"1=3", "needs exactly one sum contract"},
+		{"3=4", "needs exactly one nft-ownership contract"},
+	}
+	for _, tt := range tests {
+		var err error
+		if deliveryTargets, err = parseDeliveryTargets(tt.targets); err != nil {
+			t.Fatal(err)
+		}
+		err = validateDeliveryTargets()
+		if tt.err == "" && err != nil || tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("validateDeliveryTargets(%q) = %v, want %q", tt.targets, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/go-errors/errors"
+	"golang.org/x/time/rate"
+)
+
+// dialFirst connects to the first of the RPCs that answers eth_chainId and returns its client, URL and chain ID.
+// A positive rateLimit caps the requests per second sent to the chosen RPC. HTTP requests are
+// instrumented under kind, "app" or "nft".
+func dialFirst(ctx context.Context, kind string, urls []string, rateLimit float64, rateBurst int) (*ethclient.Client, string, *big.Int, error) {
+	var errs []error
+	for _, u := range urls {
+		var (
+			opts       []rpc.ClientOption
+			instrument *instrumentedTransport
+		)
+		if strings.HasPrefix(u, "http://") || strings.HasPrefix(u, "https://") {
+			next := http.DefaultTransport
+			if rateLimit > 0 {
+				next = newRateLimitedTransport(rateLimit, rateBurst)
+			}
+			instrument = newInstrumentedTransport(kind, next)
+			opts = append(opts, rpc.WithHTTPClient(&http.Client{Transport: instrument}))
+		}
+		rc, err := rpc.DialOptions(ctx, u, opts...)
+		if err != nil {
+			errs = append(errs, errors.Errorf("failed to connect to RPC '%s': %w", u, err))
+			continue
+		}
+		cli := ethclient.NewClient(rc)
+		chainID, err := cli.ChainID(ctx)
+		if err != nil {
+			cli.Close()
+			errs = append(errs, errors.Errorf("failed to get chain ID from '%s': %w", u, err))
+			continue
+		}
+		if instrument != nil {
+			instrument.setChain(chainID)
+		}
+		return cli, u, chainID, nil
+	}
+	return nil, "", nil, errors.Join(errs...)
+}
+
+// rateLimitedTransport delays HTTP requests to stay under a request rate.
+type rateLimitedTransport struct {
+	limiter *rate.Limiter
+	next    http.RoundTripper
+}
+
+func newRateLimitedTransport(limit float64, burst int) *rateLimitedTransport {
+	return &rateLimitedTransport{
+		limiter: rate.NewLimiter(rate.Limit(limit), max(burst, 1)),
+		next:    http.DefaultTransport,
+	}
+}
+
+func (t *rateLimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.limiter.Wait(req.Context()); err != nil {
+		return nil, err
+	}
+	return t.next.RoundTrip(req)
+}
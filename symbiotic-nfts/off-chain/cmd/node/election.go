@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/go-errors/errors"
+)
+
+// Submitter election: all operators rank the active operators of the task's signing epoch by
+// keccak256(taskId ‖ operator). The first one submits the response as soon as the proof is there,
+// the operator ranked n waits n * --submit-stagger and only submits if the task is still open.
+// Every node computes the same order, so normally a single response tx is sent per task.
+
+var (
+	operatorSets   = make(map[uint64][]common.Address)
+	operatorSetsMu sync.Mutex
+)
+
+// operatorSet returns the active operators of the relay's validator set at the epoch.
+func operatorSet(ctx context.Context, epoch uint64) ([]common.Address, error) {
+	operatorSetsMu.Lock()
+	defer operatorSetsMu.Unlock()
+	if ops, ok := operatorSets[epoch]; ok {
+		return ops, nil
+	}
+	resp, err := relays.validatorSet(ctx, epoch)
+	if err != nil {
+		return nil, errors.Errorf("failed to get validator set of epoch %d: %w", epoch, err)
+	}
+	var ops []common.Address
+	for _, v := range resp.Validators {
+		if v.IsActive && common.IsHexAddress(v.Operator) {
+			ops = append(ops, common.HexToAddress(v.Operator))
+		}
+	}
+	if len(ops) != 0 {
+		operatorSets[epoch] = ops
+	}
+	return ops, nil
+}
+
+// pruneOperatorSets forgets the operator sets of epochs before the given one.
+func pruneOperatorSets(before uint64) {
+	operatorSetsMu.Lock()
+	defer operatorSetsMu.Unlock()
+	maps.DeleteFunc(operatorSets, func(epoch uint64, _ []common.Address) bool { return epoch < before })
+}
+
+// submitterRank returns the position of self in the task's submitter order, false if self is not an operator.
+func submitterRank(taskID common.Hash, operators []common.Address, self common.Address) (int, bool) {
+	type ranked struct {
+		op  common.Address
+		key []byte
+	}
+	order := make([]ranked, 0, len(operators))
+	for _, op := range operators {
+		order = append(order, ranked{op: op, key: crypto.Keccak256(taskID.Bytes(), op.Bytes())})
+	}
+	slices.SortFunc(order, func(a, b ranked) int { return bytes.Compare(a.key, b.key) })
+	for i, r := range order {
+		if r.op == self {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// submitDelay is how long after the proof became available this node waits before submitting the task.
+// Without --operator-address the election is disabled and the node submits right away.
+func submitDelay(ctx context.Context, st TaskState) (time.Duration, error) {
+	if cfg.operatorAddress == "" {
+		return 0, nil
+	}
+	ops, err := operatorSet(ctx, uint64(st.SigEpoch))
+	if err != nil {
+		return 0, err
+	}
+	rank, ok := submitterRank(st.TaskID, ops, common.HexToAddress(cfg.operatorAddress))
+	if !ok {
+		// Not part of the set, only step in after every operator had its turn.
+		rank = len(ops)
+	}
+	return time.Duration(rank) * cfg.submitStagger, nil
+}
+
+// submitterTurn reports whether this node should submit the task's response on the chain now.
+// Backup submitters re-check the task status right before their turn so they don't race the elected one.
+func submitterTurn(ctx context.Context, chainID int64, st TaskState) (bool, error) {
+	delay, err := submitDelay(ctx, st)
+	if err != nil {
+		return false, err
+	}
+	if delay == 0 {
+		return true, nil
+	}
+	if wait := delay - time.Since(st.ProofAt); wait > 0 {
+		slog.DebugContext(ctx, "Waiting for elected submitter", "taskID", st.TaskID, "chainID", chainID, "remaining", wait)
+		return false, nil
+	}
+	h, ok := targetHandler(st, chainID)
+	if !ok {
+		return false, errors.Errorf("no contract bound for task %s on chain %d", st.Key(), chainID)
+	}
+	status, err := h.GetTaskStatus(ctx, st.TaskID)
+	if err != nil {
+		return false, err
+	}
+	// Destination chains of a mirrored response don't know the task, there it stays NOT_FOUND until responded.
+	if status == TaskResponded || status == TaskExpired {
+		updateTask(st.Key(), func(s *TaskState) { s.Statuses[chainID] = status })
+		return false, nil
+	}
+	slog.InfoContext(ctx, "Elected submitters didn't respond, submitting as backup", "taskID", st.TaskID, "chainID", chainID, "delay", delay)
+	return true, nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"maps"
+	"math/big"
+	"slices"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"sum/internal/store"
+)
+
+var (
+	opA = common.HexToAddress("0x1111111111111111111111111111111111111111")
+	opB = common.HexToAddress("0x2222222222222222222222222222222222222222")
+	opC = common.HexToAddress("0x3333333333333333333333333333333333333333")
+)
+
+func TestSubmitterRank(t *testing.T) {
+	ops := []common.Address{opA, opB, opC}
+	tests := []struct {
+		name      string
+		taskID    common.Hash
+		operators []common.Address
+		// order is the expected submitter order, by keccak256(taskId ‖ operator)
+		order []common.Address
+	}{
+		{"task 1", common.HexToHash("0x01"), ops, []common.Address{opC, opB, opA}},
+		{"task 3", common.HexToHash("0x03"), ops, []common.Address{opC, opA, opB}},
+		{"task 4", common.HexToHash("0x04"), ops, []common.Address{opA, opC, opB}},
+		{"task 5", common.HexToHash("0x05"), ops, []common.Address{opB, opA, opC}},
+		{"input order does not matter", common.HexToHash("0x05"), []common.Address{opC, opB, opA}, []common.Address{opB, opA, opC}},
+		{"single operator", common.HexToHash("0x05"), []common.Address{opC}, []common.Address{opC}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for want, op := range tt.order {
+				rank, ok := submitterRank(tt.taskID, tt.operators, op)
+				if !ok || rank != want {
+					t.Errorf("submitterRank(%s) = %d, %v, want %d", op.Hex(), rank, ok, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSubmitterRankNotElected(t *testing.T) {
+	outsider := common.HexToAddress("0x4444444444444444444444444444444444444444")
+	tests := []struct {
+		name      string
+		operators []common.Address
+		self      common.Address
+	}{
+		{"non-member", []common.Address{opA, opB, opC}, outsider},
+		{"empty set", nil, opA},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rank, ok := submitterRank(common.HexToHash("0x01"), tt.operators, tt.self); ok {
+				t.Fatalf("submitterRank() = %d, true, want not ranked", rank)
+			}
+		})
+	}
+}
+
+// Every operator has to derive the same order, so the ranks of a set are a permutation of 0..n-1
+// whichever order the relay returned the validators in.
+func TestSubmitterRankIsPermutation(t *testing.T) {
+	ops := []common.Address{opA, opB, opC, common.HexToAddress("0x4444444444444444444444444444444444444444")}
+	reversed := slices.Clone(ops)
+	slices.Reverse(reversed)
+	for i := range 16 {
+		taskID := common.BigToHash(new(big.Int).Lsh(big.NewInt(1), uint(i)))
+		seen := make([]bool, len(ops))
+		for _, op := range ops {
+			rank, ok := submitterRank(taskID, ops, op)
+			if !ok || seen[rank] {
+				t.Fatalf("task %s: rank %d of %s is missing or taken twice", taskID.Hex(), rank, op.Hex())
+			}
+			seen[rank] = true
+			if r, _ := submitterRank(taskID, reversed, op); r != rank {
+				t.Fatalf("task %s: rank of %s depends on the input order: %d vs %d", taskID.Hex(), op.Hex(), rank, r)
+			}
+		}
+	}
+}
+
+// Operator sets are kept for the epochs tracked tasks are signed at and newer ones only.
+func TestPruneOperatorSets(t *testing.T) {
+	t.Cleanup(func() {
+		operatorSets = make(map[uint64][]common.Address)
+		tasks = nil
+	})
+	operatorSets = map[uint64][]common.Address{3: {opA}, 4: {opA}, 5: {opB}, 6: {opC}}
+	signedAt := func(id int64, epoch int64) TaskState {
+		return TaskState{ChainID: 31337, TaskID: common.BigToHash(big.NewInt(id)), SigEpoch: epoch, SigRequestHash: "0xabc"}
+	}
+	unsigned := TaskState{ChainID: 31337, TaskID: common.HexToHash("0x03"), SigEpoch: 1}
+	tasks = make(map[store.TaskKey]TaskState)
+	for _, st := range []TaskState{signedAt(1, 4), signedAt(2, 6), unsigned} {
+		tasks[st.Key()] = st
+	}
+
+	pruneEpochCaches(6)
+	if got := slices.Sorted(maps.Keys(operatorSets)); !slices.Equal(got, []uint64{4, 5, 6}) {
+		t.Fatalf("operator sets of epochs %v after a task was signed at 6 while another is at 4, want 4-6", got)
+	}
+	delete(tasks, signedAt(1, 4).Key())
+	pruneEpochCaches(6)
+	if got := slices.Sorted(maps.Keys(operatorSets)); !slices.Equal(got, []uint64{6}) {
+		t.Fatalf("operator sets of epochs %v once no task is signed before 6, want only 6", got)
+	}
+}
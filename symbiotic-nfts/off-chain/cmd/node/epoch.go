@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/go-errors/errors"
+)
+
+// Reasons a signature attempt was retired
+const (
+	retiredEpochExpiring      = "epoch-expiring"
+	retiredInvalidEpoch       = "invalid-verifying-epoch"
+	retiredAggregationTimeout = "aggregation-timeout"
+)
+
+// SignAttempt records one signature request of a task.
+type SignAttempt struct {
+	Epoch       int64
+	RequestHash string
+	// Relay is the relay endpoint that accepted the sign request, asked first for the proof.
+	Relay    string `json:",omitempty"`
+	SignedAt time.Time
+	// Retired is why the attempt was abandoned, empty while it's the current one.
+	Retired   string `json:",omitempty"`
+	RetiredAt time.Time
+}
+
+// A response signed at epoch E is accepted by respondTask until TASK_EXPIRY seconds after the
+// capture timestamp of epoch E+1, as read from the app chain's settlement.
+
+type settlementInfo struct {
+	address common.Address
+	expiry  uint64
+}
+
+// contractRef is a task contract on an app chain. Deployments on one chain may use different settlements.
+type contractRef struct {
+	chainID int64
+	address common.Address
+}
+
+var (
+	settlements  = make(map[contractRef]settlementInfo)
+	deadlines    = make(map[contractRef]map[int64]uint64)
+	settlementMu sync.Mutex
+)
+
+// contractSettlement returns the settlement and TASK_EXPIRY of a task contract.
+func contractSettlement(ctx context.Context, ref contractRef) (settlementInfo, error) {
+	settlementMu.Lock()
+	defer settlementMu.Unlock()
+	if s, ok := settlements[ref]; ok {
+		return s, nil
+	}
+	const abiJSON = `[{"name":"settlement","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"address"}]},{"name":"TASK_EXPIRY","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint32"}]}]`
+	cli, addr := appClients[ref.chainID], ref.address
+	out, err := callView(ctx, cli, abiJSON, addr, nil, "settlement")
+	if err != nil {
+		return settlementInfo{}, errors.Errorf("failed to get settlement: %w", err)
+	}
+	settlement := out[0].(common.Address)
+	out, err = callView(ctx, cli, abiJSON, addr, nil, "TASK_EXPIRY")
+	if err != nil {
+		return settlementInfo{}, errors.Errorf("failed to get TASK_EXPIRY: %w", err)
+	}
+	s := settlementInfo{address: settlement, expiry: uint64(out[0].(uint32))}
+	settlements[ref] = s
+	return s, nil
+}
+
+// epochDeadline returns the chain timestamp from which the contract rejects responses signed at epoch,
+// or 0 while the next epoch isn't captured yet and the deadline is unknown.
+func epochDeadline(ctx context.Context, ref contractRef, epoch int64) (uint64, error) {
+	settlementMu.Lock()
+	d, ok := deadlines[ref][epoch]
+	settlementMu.Unlock()
+	if ok {
+		return d, nil
+	}
+
+	s, err := contractSettlement(ctx, ref)
+	if err != nil {
+		return 0, err
+	}
+	const abiJSON = `[{"name":"getCaptureTimestampFromValSetHeaderAt","type":"function","stateMutability":"view","inputs":[{"name":"epoch","type":"uint48"}],"outputs":[{"name":"","type":"uint48"}]}]`
+	out, err := callView(ctx, appClients[ref.chainID], abiJSON, s.address, nil, "getCaptureTimestampFromValSetHeaderAt", big.NewInt(epoch+1))
+	if err != nil {
+		return 0, errors.Errorf("failed to get capture timestamp of epoch %d: %w", epoch+1, err)
+	}
+	capture := out[0].(*big.Int).Uint64()
+	if capture == 0 {
+		return 0, nil
+	}
+	d = capture + s.expiry
+
+	// Committed headers don't change, so the deadline is final.
+	settlementMu.Lock()
+	if deadlines[ref] == nil {
+		deadlines[ref] = make(map[int64]uint64)
+	}
+	deadlines[ref][epoch] = d
+	settlementMu.Unlock()
+	return d, nil
+}
+
+// epochExpiring reports whether the task's signing epoch stops being accepted by the contract
+// within --resign-margin.
+func epochExpiring(ctx context.Context, ref contractRef, epoch int64) (bool, error) {
+	deadline, err := epochDeadline(ctx, ref, epoch)
+	if err != nil || deadline == 0 {
+		return false, err
+	}
+	head, err := appClients[ref.chainID].HeaderByNumber(ctx, nil)
+	if err != nil {
+		return false, errors.Errorf("failed to get head: %w", err)
+	}
+	return head.Time+uint64(cfg.resignMargin/time.Second) >= deadline, nil
+}
+
+// checkSignatureValidity retires the task's signature if it can't be used on the chain anymore,
+// so the origin chain's worker signs it again under a fresh epoch.
+func checkSignatureValidity(ctx context.Context, chainID int64, st TaskState) (bool, error) {
+	h, ok := targetHandler(st, chainID)
+	if !ok {
+		return false, errors.Errorf("no contract bound for task %s on chain %d", st.Key(), chainID)
+	}
+	expiring, err := epochExpiring(ctx, contractRef{chainID, h.Address()}, st.SigEpoch)
+	if err != nil || !expiring {
+		return true, err
+	}
+	slog.WarnContext(ctx, "Signing epoch expiring, re-signing", "taskID", st.TaskID, "chainID", chainID, "epoch", st.SigEpoch)
+	updateTask(st.Key(), func(s *TaskState) { retireSignature(s, st.SigRequestHash, retiredEpochExpiring) })
+	return false, nil
+}
+
+// retireSignature abandons the task's current signature if it's still the one with requestHash.
+// The next signature has to use a newer epoch: the relay identifies a request by its key tag, epoch
+// and message, so signing the same message at the same epoch would only repeat the retired request.
+func retireSignature(st *TaskState, requestHash, reason string) {
+	if st.SigRequestHash == "" || st.SigRequestHash != requestHash {
+		return
+	}
+	if n := len(st.Attempts); n != 0 && st.Attempts[n-1].RequestHash == requestHash {
+		st.Attempts[n-1].Retired = reason
+		st.Attempts[n-1].RetiredAt = time.Now()
+	}
+	st.MinEpoch = st.SigEpoch + 1
+	setSignature(st, TaskState{Attempts: st.Attempts})
+}
+
+// signingEpoch picks the epoch for a new signature of the task: the relay's suggestion,
+// or the current epoch if the suggestion is older than the task requires.
+func signingEpoch(ctx context.Context, st *TaskState) (uint64, error) {
+	suggested, err := relays.suggestedEpoch(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if int64(suggested) >= st.MinEpoch {
+		return suggested, nil
+	}
+	current, err := relays.currentEpoch(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if int64(current) >= st.MinEpoch {
+		return current, nil
+	}
+	return 0, errors.Errorf("no epoch >= %d available yet (suggested %d, current %d)", st.MinEpoch, suggested, current)
+}
+
+// pruneEpochCaches drops cached data of epochs no task needs anymore: those before the epoch a task
+// was just signed at and before the oldest epoch any tracked task is still signed at.
+func pruneEpochCaches(signed uint64) {
+	before := signed
+	for _, st := range taskSnapshot() {
+		if st.SigRequestHash != "" && uint64(st.SigEpoch) < before {
+			before = uint64(st.SigEpoch)
+		}
+	}
+	pruneOperatorSets(before)
+
+	settlementMu.Lock()
+	defer settlementMu.Unlock()
+	for _, byEpoch := range deadlines {
+		maps.DeleteFunc(byEpoch, func(epoch int64, _ uint64) bool { return epoch < int64(before) })
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"maps"
+	"math/big"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"sum/internal/store"
+)
+
+var epochTestSettlement = common.HexToAddress("0x9fE46736679d2D9a65F0992F2272dE9f3c7fa6e0")
+
+// setupEpochTest serves app chain 31337 with its head at time 99 and a task contract whose
+// settlement captured the next epoch at capture, with a TASK_EXPIRY of 50 seconds.
+func setupEpochTest(t *testing.T, capture int64, margin time.Duration) contractRef {
+	t.Helper()
+	chain, cli := newFakeChain(t, 100)
+	ref := contractRef{31337, reorgTestContract}
+	chain.deploy(ref.address, "settlement()", packOutput(t, "address", epochTestSettlement), nil)
+	chain.deploy(ref.address, "TASK_EXPIRY()", packOutput(t, "uint32", uint32(50)), nil)
+	chain.deploy(epochTestSettlement, "getCaptureTimestampFromValSetHeaderAt(uint48)", packOutput(t, "uint48", big.NewInt(capture)), nil)
+	appClients = map[int64]*ethclient.Client{31337: cli}
+	cfg.resignMargin = margin
+	t.Cleanup(func() {
+		appClients, cfg = nil, config{}
+		settlements = make(map[contractRef]settlementInfo)
+		deadlines = make(map[contractRef]map[int64]uint64)
+	})
+	return ref
+}
+
+func TestEpochExpiring(t *testing.T) {
+	tests := []struct {
+		name         string
+		capture      int64
+		margin       time.Duration
+		want         bool
+		wantDeadline uint64
+	}{
+		// The deadline is capture + TASK_EXPIRY = 110, the head is at 99.
+		{"outside the margin", 60, 10 * time.Second, false, 110},
+		{"margin reaches the deadline", 60, 11 * time.Second, true, 110},
+		{"deadline passed", 40, 0, true, 90},
+		{"next epoch not captured", 0, time.Hour, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref := setupEpochTest(t, tt.capture, tt.margin)
+			got, err := epochExpiring(context.Background(), ref, 7)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("epochExpiring() = %t, want %t", got, tt.want)
+			}
+			if s := settlements[ref]; s.address != epochTestSettlement || s.expiry != 50 {
+				t.Errorf("settlement = %s with expiry %d, want %s with 50", s.address, s.expiry, epochTestSettlement)
+			}
+			// An unknown deadline is looked up again, a known one is final.
+			d, cached := deadlines[ref][7]
+			if cached != (tt.wantDeadline != 0) || d != tt.wantDeadline {
+				t.Errorf("cached deadline = %d, %t, want %d", d, cached, tt.wantDeadline)
+			}
+		})
+	}
+}
+
+func TestRetireSignature(t *testing.T) {
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	signed := func() TaskState {
+		return TaskState{
+			Payload:        []byte{1},
+			SigEpoch:       5,
+			SigRequestHash: "0xbbb",
+			AggProof:       []byte{2},
+			ProofAt:        at,
+			MinEpoch:       4,
+			Attempts: []SignAttempt{
+				{Epoch: 4, RequestHash: "0xaaa", SignedAt: at, Retired: retiredInvalidEpoch, RetiredAt: at},
+				{Epoch: 5, RequestHash: "0xbbb", SignedAt: at},
+			},
+		}
+	}
+
+	st := signed()
+	retireSignature(&st, "0xbbb", retiredAggregationTimeout)
+	if st.MinEpoch != 6 || st.SigEpoch != 0 || st.SigRequestHash != "" || st.Payload != nil || st.AggProof != nil {
+		t.Fatalf("state after retiring the signature of epoch 5 = %+v, want unsigned with MinEpoch 6", st)
+	}
+	if len(st.Attempts) != 2 || st.Attempts[0].Retired != retiredInvalidEpoch {
+		t.Fatalf("attempts = %+v, want the earlier attempt unchanged", st.Attempts)
+	}
+	if last := st.Attempts[1]; last.Retired != retiredAggregationTimeout || last.RetiredAt.IsZero() {
+		t.Fatalf("retired attempt = %+v", last)
+	}
+
+	// A signature that was replaced in the meantime is left alone.
+	st = signed()
+	retireSignature(&st, "0xaaa", retiredAggregationTimeout)
+	if st.SigRequestHash != "0xbbb" || st.MinEpoch != 4 || st.Attempts[1].Retired != "" {
+		t.Fatalf("retiring a stale request hash changed the task: %+v", st)
+	}
+}
+
+func TestSigningEpoch(t *testing.T) {
+	tests := []struct {
+		name               string
+		suggested, current uint64
+		minEpoch           int64
+		want               uint64
+		wantErr            bool
+	}{
+		{name: "suggested", suggested: 5, current: 6, want: 5},
+		{name: "suggested at the minimum", suggested: 6, current: 6, minEpoch: 6, want: 6},
+		{name: "current when the suggestion is too old", suggested: 5, current: 6, minEpoch: 6, want: 6},
+		{name: "no epoch yet", suggested: 5, current: 5, minEpoch: 6, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestRelays(t, epochRelay(tt.suggested, tt.current))
+			got, err := signingEpoch(context.Background(), &TaskState{MinEpoch: tt.minEpoch})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("signingEpoch() = %d, want an error", got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("signingEpoch() = %d, %v, want %d", got, err, tt.want)
+			}
+		})
+	}
+}
+
+// After an aggregation timeout at the suggested epoch the task is signed again at a newer one.
+func TestResignAfterAggregationTimeout(t *testing.T) {
+	setupTestRelays(t, epochRelay(5, 6))
+	st := TaskState{SigEpoch: 5, SigRequestHash: "0xbbb", Attempts: []SignAttempt{{Epoch: 5, RequestHash: "0xbbb"}}}
+	retireSignature(&st, "0xbbb", retiredAggregationTimeout)
+	if st.MinEpoch != 6 {
+		t.Fatalf("MinEpoch = %d, want 6", st.MinEpoch)
+	}
+	if got, err := signingEpoch(context.Background(), &st); err != nil || got != 6 {
+		t.Fatalf("signingEpoch() = %d, %v, want 6", got, err)
+	}
+}
+
+func TestPruneDeadlines(t *testing.T) {
+	t.Cleanup(func() {
+		deadlines = make(map[contractRef]map[int64]uint64)
+		tasks = nil
+	})
+	refA, refB := contractRef{1, reorgTestContract}, contractRef{2, reorgTestContract}
+	deadlines = map[contractRef]map[int64]uint64{
+		refA: {3: 100, 4: 200, 5: 300},
+		refB: {2: 50, 5: 300},
+	}
+	tasks = map[store.TaskKey]TaskState{}
+	pruneEpochCaches(4)
+	if got := slices.Sorted(maps.Keys(deadlines[refA])); !slices.Equal(got, []int64{4, 5}) {
+		t.Errorf("deadlines of chain 1 kept for epochs %v, want 4 and 5", got)
+	}
+	if got := slices.Sorted(maps.Keys(deadlines[refB])); !slices.Equal(got, []int64{5}) {
+		t.Errorf("deadlines of chain 2 kept for epochs %v, want 5", got)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"math/big"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/go-errors/errors"
+)
+
+// TaskHandler adapts one deployed task contract to the node's task pipeline:
+// decode the creation event, compute the payload to attest, encode the message operators sign
+// and submit the aggregated response back to the contract.
+type TaskHandler interface {
+	// Type is the registry key of the handler, e.g. "nft-ownership".
+	Type() string
+	Address() common.Address
+	// CreatedEventID is the topic0 of the event announcing a new task.
+	CreatedEventID() common.Hash
+	// DecodeCreated parses a task creation log into the task ID and the JSON-encoded request.
+	DecodeCreated(log types.Log) (common.Hash, json.RawMessage, error)
+	// ComputePayload evaluates the request and returns the response payload.
+	// It returns errAbstain if the node can't determine a definitive answer.
+	ComputePayload(ctx context.Context, taskID common.Hash, req json.RawMessage) ([]byte, error)
+	// EncodeMessage returns the message the relay signs for the payload.
+	EncodeMessage(taskID common.Hash, payload []byte) ([]byte, error)
+	GetTaskStatus(ctx context.Context, taskID common.Hash) (uint8, error)
+	// PackRespond returns the respondTask calldata, used to simulate a response before sending it.
+	PackRespond(taskID common.Hash, payload []byte, epoch *big.Int, proof []byte) ([]byte, error)
+	// ABI is the contract ABI, including the custom errors respondTask reverts with.
+	ABI() *abi.ABI
+	RespondTask(opts *bind.TransactOpts, taskID common.Hash, payload []byte, epoch *big.Int, proof []byte) (*types.Transaction, error)
+}
+
+// handlers binds the task contracts of each app chain by address. The maps are not modified after startup.
+var handlers map[int64]map[common.Address]TaskHandler
+
+// chainHandlers returns the contracts bound on the chain, ordered by address.
+func chainHandlers(chainID int64) []TaskHandler {
+	hs := slices.Collect(maps.Values(handlers[chainID]))
+	slices.SortFunc(hs, func(a, b TaskHandler) int { return a.Address().Cmp(b.Address()) })
+	return hs
+}
+
+// handlerOfType returns the chain's contract of the given type, false unless there is exactly one.
+func handlerOfType(chainID int64, typ string) (TaskHandler, bool) {
+	var found TaskHandler
+	for _, h := range handlers[chainID] {
+		if h.Type() != typ {
+			continue
+		}
+		if found != nil {
+			return nil, false
+		}
+		found = h
+	}
+	return found, found != nil
+}
+
+// createdEventsQuery filters the creation events of all contracts of the chain in one request.
+// It may match an event of one contract type emitted by a contract of another, processNewTasks drops those.
+func createdEventsQuery(chainID int64) ethereum.FilterQuery {
+	var q ethereum.FilterQuery
+	var topics []common.Hash
+	for _, h := range chainHandlers(chainID) {
+		q.Addresses = append(q.Addresses, h.Address())
+		if !slices.Contains(topics, h.CreatedEventID()) {
+			topics = append(topics, h.CreatedEventID())
+		}
+	}
+	q.Topics = [][]common.Hash{topics}
+	return q
+}
+
+type handlerFactory func(address common.Address, cli *ethclient.Client) (TaskHandler, error)
+
+// handlerRegistry maps contract types accepted by --contract-types to their handler constructors.
+var handlerRegistry = map[string]handlerFactory{}
+
+func registerHandler(typ string, factory handlerFactory) {
+	if _, ok := handlerRegistry[typ]; ok {
+		panic("task handler registered twice: " + typ)
+	}
+	handlerRegistry[typ] = factory
+}
+
+func newHandler(typ string, address common.Address, cli *ethclient.Client) (TaskHandler, error) {
+	factory, ok := handlerRegistry[typ]
+	if !ok {
+		return nil, errors.Errorf("unknown contract type %q (supported: %s)", typ, strings.Join(handlerTypes(), ", "))
+	}
+	return factory(address, cli)
+}
+
+func handlerTypes() []string {
+	types := make([]string, 0, len(handlerRegistry))
+	for typ := range handlerRegistry {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+	return types
+}
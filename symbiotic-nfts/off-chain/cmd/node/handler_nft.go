@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/go-errors/errors"
+	"go.opentelemetry.io/otel/attribute"
+
+	"sum/internal/contracts"
+)
+
+const nftOwnershipType = "nft-ownership"
+
+func init() {
+	registerHandler(nftOwnershipType, newNftOwnershipHandler)
+}
+
+type nftOwnershipHandler struct {
+	address  common.Address
+	contract *contracts.NftOwnershipTask
+	abi      *abi.ABI
+	eventID  common.Hash
+}
+
+func newNftOwnershipHandler(address common.Address, cli *ethclient.Client) (TaskHandler, error) {
+	c, err := contracts.NewNftOwnershipTask(address, cli)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := contracts.NftOwnershipTaskMetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+	return &nftOwnershipHandler{address: address, contract: c, abi: parsed, eventID: parsed.Events["TaskCreated"].ID}, nil
+}
+
+func (h *nftOwnershipHandler) Type() string                { return nftOwnershipType }
+func (h *nftOwnershipHandler) Address() common.Address     { return h.address }
+func (h *nftOwnershipHandler) CreatedEventID() common.Hash { return h.eventID }
+
+func (h *nftOwnershipHandler) DecodeCreated(log types.Log) (common.Hash, json.RawMessage, error) {
+	evt, err := h.contract.ParseTaskCreated(log)
+	if err != nil {
+		return common.Hash{}, nil, err
+	}
+	req, err := json.Marshal(evt.Req)
+	if err != nil {
+		return common.Hash{}, nil, err
+	}
+	return evt.TaskId, req, nil
+}
+
+func (h *nftOwnershipHandler) ComputePayload(ctx context.Context, taskID common.Hash, raw json.RawMessage) ([]byte, error) {
+	var req contracts.NftOwnershipTaskRequest
+	if err := json.Unmarshal(raw, &req); err != nil {
+		return nil, errors.Errorf("failed to decode request: %w", err)
+	}
+	slog.InfoContext(ctx, "Verifying ownership",
+		"taskID", taskID,
+		"chainId", req.ChainId,
+		"collection", req.Collection,
+		"tokenId", req.TokenId,
+		"owner", req.Owner,
+		"checkedBlock", req.CheckedBlock,
+		"standard", req.Standard,
+		"minAmount", req.MinAmount,
+	)
+
+	spanCtx, span := tracer.Start(ctx, "verifyOwnership")
+	span.SetAttributes(
+		attribute.String("nft.chain_id", req.ChainId.String()),
+		attribute.String("nft.collection", req.Collection.Hex()),
+		attribute.String("nft.token_id", req.TokenId.String()),
+		attribute.String("nft.owner", req.Owner.Hex()),
+	)
+	res, err := verifyOwnership(spanCtx, req)
+	if err != nil {
+		endSpan(span, err)
+		ownershipChecks.WithLabelValues("error").Inc()
+		return nil, errors.Errorf("verifyOwnership failed: %w", err)
+	}
+	span.SetAttributes(attribute.String("nft.reason", reasonName(res.Reason)), attribute.Int64("nft.observed_block", int64(res.ObservedBlock)))
+	span.End()
+	ownershipChecks.WithLabelValues(reasonName(res.Reason)).Inc()
+	slog.InfoContext(ctx, "Ownership verification",
+		"taskID", taskID,
+		"isOwner", res.IsOwner(),
+		"reason", reasonName(res.Reason),
+		"ownerAtBlock", res.OwnerAtBlock.Hex(),
+		"observedBlock", res.ObservedBlock,
+		"observedAmount", res.ObservedAmount,
+		"decimals", res.Decimals,
+	)
+	if !res.Verified() {
+		return nil, errAbstain
+	}
+	return encodeOwnershipPayload(res)
+}
+
+func (h *nftOwnershipHandler) EncodeMessage(taskID common.Hash, payload []byte) ([]byte, error) {
+	bytes32T, _ := abi.NewType("bytes32", "", nil)
+	bytesT, _ := abi.NewType("bytes", "", nil)
+	msgArgs := abi.Arguments{{Type: bytes32T}, {Type: bytesT}}
+	return msgArgs.Pack(taskID, payload)
+}
+
+func (h *nftOwnershipHandler) GetTaskStatus(ctx context.Context, taskID common.Hash) (uint8, error) {
+	return h.contract.GetTaskStatus(&bind.CallOpts{Context: ctx}, taskID)
+}
+
+func (h *nftOwnershipHandler) PackRespond(taskID common.Hash, payload []byte, epoch *big.Int, proof []byte) ([]byte, error) {
+	return h.abi.Pack("respondTask", taskID, payload, epoch, proof)
+}
+
+func (h *nftOwnershipHandler) ABI() *abi.ABI { return h.abi }
+
+func (h *nftOwnershipHandler) RespondTask(opts *bind.TransactOpts, taskID common.Hash, payload []byte, epoch *big.Int, proof []byte) (*types.Transaction, error) {
+	return h.contract.RespondTask(opts, taskID, payload, epoch, proof)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/go-errors/errors"
+
+	"sum/internal/contracts"
+)
+
+const sumType = "sum"
+
+func init() {
+	registerHandler(sumType, newSumHandler)
+}
+
+// sumHandler serves SumTask contracts. The payload is the abi-encoded uint256 result,
+// so abi.encode(taskId, payload) matches the abi.encode(taskId, result) the contract verifies.
+type sumHandler struct {
+	address  common.Address
+	contract *contracts.SumTask
+	abi      *abi.ABI
+	eventID  common.Hash
+}
+
+func newSumHandler(address common.Address, cli *ethclient.Client) (TaskHandler, error) {
+	c, err := contracts.NewSumTask(address, cli)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := contracts.SumTaskMetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+	return &sumHandler{address: address, contract: c, abi: parsed, eventID: parsed.Events["CreateTask"].ID}, nil
+}
+
+func (h *sumHandler) Type() string                { return sumType }
+func (h *sumHandler) Address() common.Address     { return h.address }
+func (h *sumHandler) CreatedEventID() common.Hash { return h.eventID }
+
+func (h *sumHandler) DecodeCreated(log types.Log) (common.Hash, json.RawMessage, error) {
+	evt, err := h.contract.ParseCreateTask(log)
+	if err != nil {
+		return common.Hash{}, nil, err
+	}
+	req, err := json.Marshal(evt.Task)
+	if err != nil {
+		return common.Hash{}, nil, err
+	}
+	return evt.TaskId, req, nil
+}
+
+func (h *sumHandler) ComputePayload(ctx context.Context, taskID common.Hash, raw json.RawMessage) ([]byte, error) {
+	var task contracts.SumTaskTask
+	if err := json.Unmarshal(raw, &task); err != nil {
+		return nil, errors.Errorf("failed to decode request: %w", err)
+	}
+	result := new(big.Int).Add(task.NumberA, task.NumberB)
+	if result.BitLen() > 256 {
+		return nil, errors.Errorf("sum of %s and %s overflows uint256", task.NumberA, task.NumberB)
+	}
+	slog.InfoContext(ctx, "Computed sum", "taskID", taskID, "numberA", task.NumberA, "numberB", task.NumberB, "result", result)
+	return common.LeftPadBytes(result.Bytes(), 32), nil
+}
+
+func (h *sumHandler) EncodeMessage(taskID common.Hash, payload []byte) ([]byte, error) {
+	bytes32T, _ := abi.NewType("bytes32", "", nil)
+	uint256T, _ := abi.NewType("uint256", "", nil)
+	msgArgs := abi.Arguments{{Type: bytes32T}, {Type: uint256T}}
+	return msgArgs.Pack(taskID, new(big.Int).SetBytes(payload))
+}
+
+func (h *sumHandler) GetTaskStatus(ctx context.Context, taskID common.Hash) (uint8, error) {
+	return h.contract.GetTaskStatus(&bind.CallOpts{Context: ctx}, taskID)
+}
+
+func (h *sumHandler) PackRespond(taskID common.Hash, payload []byte, epoch *big.Int, proof []byte) ([]byte, error) {
+	return h.abi.Pack("respondTask", taskID, new(big.Int).SetBytes(payload), epoch, proof)
+}
+
+func (h *sumHandler) ABI() *abi.ABI { return h.abi }
+
+func (h *sumHandler) RespondTask(opts *bind.TransactOpts, taskID common.Hash, payload []byte, epoch *big.Int, proof []byte) (*types.Transaction, error) {
+	return h.contract.RespondTask(opts, taskID, new(big.Int).SetBytes(payload), epoch, proof)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestHandlerRegistry(t *testing.T) {
+	if got, want := handlerTypes(), []string{nftOwnershipType, sumType}; !slices.Equal(got, want) {
+		t.Fatalf("handlerTypes() = %v, want %v", got, want)
+	}
+	addr := common.HexToAddress("0x5FbDB2315678afecb367f032d93F642f64180aa3")
+	for _, typ := range handlerTypes() {
+		h, err := newHandler(typ, addr, nil)
+		if err != nil {
+			t.Fatalf("newHandler(%q): %v", typ, err)
+		}
+		if h.Type() != typ || h.Address() != addr {
+			t.Errorf("newHandler(%q) = %s handler at %s", typ, h.Type(), h.Address())
+		}
+	}
+	_, err := newHandler("erc721", addr, nil)
+	if err == nil || !strings.Contains(err.Error(), nftOwnershipType+", "+sumType) {
+		t.Fatalf("newHandler() of an unknown type = %v, want an error listing the supported types", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("registering a type twice did not panic")
+		}
+	}()
+	registerHandler(sumType, handlerRegistry[sumType])
+}
+
+func TestChainHandlers(t *testing.T) {
+	nftAddr := common.HexToAddress("0x5FbDB2315678afecb367f032d93F642f64180aa3")
+	sumA := common.HexToAddress("0xe7f1725E7734CE288F8367e1Bb143E90bb3F0512")
+	sumB := common.HexToAddress("0x9fE46736679d2D9a65F0992F2272dE9f3c7fa6e0")
+	newTestHandler := func(typ string, address common.Address) TaskHandler {
+		h, err := newHandler(typ, address, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+	handlers = map[int64]map[common.Address]TaskHandler{
+		1: {
+			sumB:    newTestHandler(sumType, sumB),
+			nftAddr: newTestHandler(nftOwnershipType, nftAddr),
+			sumA:    newTestHandler(sumType, sumA),
+		},
+		2: {sumA: newTestHandler(sumType, sumA)},
+	}
+	t.Cleanup(func() { handlers = nil })
+
+	var order []common.Address
+	for _, h := range chainHandlers(1) {
+		order = append(order, h.Address())
+	}
+	if want := []common.Address{nftAddr, sumB, sumA}; !slices.Equal(order, want) {
+		t.Errorf("chainHandlers() = %v, want %v", order, want)
+	}
+
+	if h, ok := handlerOfType(1, nftOwnershipType); !ok || h.Address() != nftAddr {
+		t.Errorf("handlerOfType(1, %q) = %v, %t", nftOwnershipType, h, ok)
+	}
+	if _, ok := handlerOfType(1, sumType); ok {
+		t.Errorf("handlerOfType(1, %q) picked one of two contracts", sumType)
+	}
+	if h, ok := handlerOfType(2, sumType); !ok || h.Address() != sumA {
+		t.Errorf("handlerOfType(2, %q) = %v, %t", sumType, h, ok)
+	}
+	if _, ok := handlerOfType(3, sumType); ok {
+		t.Errorf("handlerOfType() found a contract on an unbound chain")
+	}
+
+	q := createdEventsQuery(1)
+	if !slices.Equal(q.Addresses, order) {
+		t.Errorf("query addresses = %v, want %v", q.Addresses, order)
+	}
+	topics := []common.Hash{handlers[1][nftAddr].CreatedEventID(), handlers[1][sumA].CreatedEventID()}
+	if len(q.Topics) != 1 || !slices.Equal(q.Topics[0], topics) {
+		t.Errorf("query topics = %v, want each creation event once: %v", q.Topics, topics)
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/go-errors/errors"
+)
+
+const healthCheckTimeout = 3 * time.Second
+
+const (
+	checkOK   = "ok"
+	checkFail = "fail"
+)
+
+func init() {
+	httpMux.HandleFunc("GET /healthz", handleHealthz)
+	httpMux.HandleFunc("GET /readyz", handleReadyz)
+}
+
+// checkResult is the outcome of one probe in the /healthz and /readyz breakdown.
+type checkResult struct {
+	Status    string `json:"status"`
+	LatencyMs int64  `json:"latencyMs"`
+	Detail    any    `json:"detail,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+type healthReport struct {
+	Status string                 `json:"status"`
+	Checks map[string]checkResult `json:"checks"`
+}
+
+// probe is a named check returning its detail, failing with an error.
+type probe struct {
+	name string
+	run  func(ctx context.Context) (any, error)
+}
+
+// runProbes runs the probes concurrently, each with healthCheckTimeout, and reports failure if any fails.
+func runProbes(ctx context.Context, probes []probe) healthReport {
+	report := healthReport{Status: checkOK, Checks: make(map[string]checkResult, len(probes))}
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, p := range probes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+			defer cancel()
+			start := time.Now()
+			detail, err := p.run(ctx)
+			res := checkResult{Status: checkOK, LatencyMs: time.Since(start).Milliseconds(), Detail: detail}
+			if err != nil {
+				res.Status = checkFail
+				res.Error = err.Error()
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[p.name] = res
+			if err != nil {
+				report.Status = checkFail
+			}
+		}()
+	}
+	wg.Wait()
+	return report
+}
+
+func writeReport(w http.ResponseWriter, report healthReport) {
+	status := http.StatusOK
+	if report.Status != checkOK {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, report)
+}
+
+// handleHealthz is the liveness probe. It only looks at the chain workers, so failing dependencies
+// don't get the node restarted; it fails once a worker exhausted its error budget.
+func handleHealthz(w http.ResponseWriter, r *http.Request) {
+	var probes []probe
+	for chainID, wk := range workers {
+		probes = append(probes, probe{fmt.Sprintf("worker:%d", chainID), func(context.Context) (any, error) {
+			h := wk.Health()
+			if h.Status == chainUnhealthy {
+				return h, errors.Errorf("chain %d worker is unhealthy after %d failures", chainID, h.ConsecutiveFailures)
+			}
+			return h, nil
+		}})
+	}
+	writeReport(w, runProbes(r.Context(), probes))
+}
+
+// handleReadyz is the readiness probe: the app and NFT chain RPCs answer, a relay is reachable,
+// and per app chain the responder balance and the scan lag are within --min-balance-eth and --max-scan-lag.
+func handleReadyz(w http.ResponseWriter, r *http.Request) {
+	var probes []probe
+	for chainID, cli := range appClients {
+		probes = append(probes,
+			probe{fmt.Sprintf("app_rpc:%d", chainID), func(ctx context.Context) (any, error) {
+				head, err := cli.BlockNumber(ctx)
+				if err != nil {
+					return nil, errors.Errorf("failed to get block number: %w", err)
+				}
+				return map[string]uint64{"head": head}, nil
+			}},
+			probe{fmt.Sprintf("wallet:%d", chainID), func(ctx context.Context) (any, error) {
+				from := txManagers[chainID].From()
+				bal, err := cli.BalanceAt(ctx, from, nil)
+				if err != nil {
+					return nil, errors.Errorf("failed to get balance of %s: %w", from.Hex(), err)
+				}
+				eth := weiToEth(bal)
+				walletBalance.WithLabelValues(chainLabel(chainID)).Set(eth)
+				detail := map[string]any{"address": from, "balanceEth": eth, "minBalanceEth": cfg.minBalanceEth}
+				if eth < cfg.minBalanceEth {
+					return detail, errors.Errorf("balance %g ETH is below %g ETH", eth, cfg.minBalanceEth)
+				}
+				return detail, nil
+			}},
+		)
+	}
+	for chainID, wk := range workers {
+		probes = append(probes, probe{fmt.Sprintf("scan:%d", chainID), func(context.Context) (any, error) {
+			p, h := wk.Progress(), wk.Health()
+			detail := map[string]any{"progress": p, "health": h.Status, "maxScanLag": cfg.maxScanLag}
+			switch {
+			case h.Status == chainUnhealthy:
+				return detail, errors.Errorf("chain %d worker is unhealthy: %s", chainID, h.LastError)
+			case p.UpdatedAt.IsZero():
+				return detail, errors.Errorf("chain %d has not been scanned yet", chainID)
+			case cfg.maxScanLag > 0 && p.Lag > cfg.maxScanLag:
+				return detail, errors.Errorf("chain %d scan is %d blocks behind, max %d", chainID, p.Lag, cfg.maxScanLag)
+			}
+			return detail, nil
+		}})
+	}
+	for chainID := range nftChains {
+		if _, ok := appClients[int64(chainID)]; ok {
+			continue
+		}
+		probes = append(probes, probe{fmt.Sprintf("nft_rpc:%d", chainID), func(ctx context.Context) (any, error) {
+			cli, err := getNFTClient(ctx, chainID)
+			if err != nil {
+				return nil, err
+			}
+			head, err := cli.BlockNumber(ctx)
+			if err != nil {
+				return nil, errors.Errorf("failed to get block number: %w", err)
+			}
+			return map[string]uint64{"head": head}, nil
+		}})
+	}
+	probes = append(probes, probe{"relay", func(ctx context.Context) (any, error) {
+		views := relays.probe(ctx)
+		if !slices.ContainsFunc(views, func(v relayView) bool { return v.Reachable }) {
+			return views, errors.Errorf("none of the %d relays is reachable", len(views))
+		}
+		return views, nil
+	}})
+	writeReport(w, runProbes(r.Context(), probes))
+}
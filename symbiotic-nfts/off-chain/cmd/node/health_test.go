@@ -0,0 +1,139 @@
+package main
+
+import (
+	"maps"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/params"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	"sum/internal/txmgr"
+)
+
+// setupHealthTest runs chain 1 with head 100, scanned up to block 95, a responder holding 2 ETH
+// and one relay at epoch 5. /readyz requires 1 ETH and a scan lag of at most 10 blocks.
+func setupHealthTest(t *testing.T) (*fakeChain, *chainWorker, *fakeRelay) {
+	t.Helper()
+	chain, cli := newFakeChain(t, 101)
+	chain.balance = new(big.Int).Mul(big.NewInt(2), big.NewInt(params.Ether))
+	txm, err := txmgr.New(cli, big.NewInt(1), common.HexToAddress("0x70997970C51812dc3A010C7d01b50e0d17dc79C8"), nil, txmgr.Config{BumpPercent: 10, BaseFeeMultiplier: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wk := newChainWorker(1, cli, 96, false)
+	wk.recordProgress(100)
+	appClients = map[int64]*ethclient.Client{1: cli}
+	txManagers = map[int64]*txmgr.Manager{1: txm}
+	workers = map[int64]*chainWorker{1: wk}
+	cfg.minBalanceEth, cfg.maxScanLag = 1, 10
+	t.Cleanup(func() {
+		appClients, txManagers, workers = nil, nil, nil
+		cfg = config{}
+	})
+
+	relay := epochRelay(5, 5)
+	setupTestRelays(t, relay)
+	// The probe reports the connection state; the connection is never used, calls go to the fake relay.
+	conn, err := grpc.NewClient("passthrough:///relay-0", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	relays.endpoints[0].conn = conn
+	return chain, wk, relay
+}
+
+func TestHandleReadyz(t *testing.T) {
+	chain, wk, relay := setupHealthTest(t)
+	balance, handle := chain.balance, relay.handle
+
+	tests := []struct {
+		name    string
+		breaks  func()
+		failing string
+		want    string
+	}{
+		{name: "ready"},
+		{
+			name:    "low balance",
+			breaks:  func() { chain.balance = big.NewInt(params.Ether / 2) },
+			failing: "wallet:1",
+			want:    "balance 0.5 ETH is below 1 ETH",
+		},
+		{
+			name:    "scan lag",
+			breaks:  func() { wk.recordProgress(106) },
+			failing: "scan:1",
+			want:    "chain 1 scan is 11 blocks behind, max 10",
+		},
+		{
+			name:    "unhealthy worker",
+			breaks:  func() { wk.health = chainHealth{Status: chainUnhealthy, LastError: "boom"} },
+			failing: "scan:1",
+			want:    "chain 1 worker is unhealthy: boom",
+		},
+		{
+			name: "relay down",
+			breaks: func() {
+				relay.handle = func(string, any, any) error { return status.Error(codes.Unavailable, "connection refused") }
+			},
+			failing: "relay",
+			want:    "none of the 1 relays is reachable",
+		},
+	}
+	for _, tt := range tests {
+		chain.balance, relay.handle = balance, handle
+		wk.recordProgress(100)
+		wk.health = chainHealth{Status: chainHealthy}
+		if tt.breaks != nil {
+			tt.breaks()
+		}
+
+		var report healthReport
+		code := serveAdmin(t, "/readyz", &report)
+		names := slices.Sorted(maps.Keys(report.Checks))
+		if want := []string{"app_rpc:1", "relay", "scan:1", "wallet:1"}; !slices.Equal(names, want) {
+			t.Fatalf("%s: checks = %v, want %v", tt.name, names, want)
+		}
+		wantCode, wantStatus := http.StatusOK, checkOK
+		if tt.failing != "" {
+			wantCode, wantStatus = http.StatusServiceUnavailable, checkFail
+		}
+		if code != wantCode || report.Status != wantStatus {
+			t.Errorf("%s: GET /readyz = %d %s, want %d %s", tt.name, code, report.Status, wantCode, wantStatus)
+		}
+		for name, res := range report.Checks {
+			if name == tt.failing {
+				if res.Status != checkFail || !strings.Contains(res.Error, tt.want) {
+					t.Errorf("%s: check %s = %+v, want a failure %q", tt.name, name, res, tt.want)
+				}
+			} else if res.Status != checkOK {
+				t.Errorf("%s: check %s = %+v, want ok", tt.name, name, res)
+			}
+		}
+	}
+}
+
+// The liveness probe only fails for a worker that exhausted its error budget, not for a failing dependency.
+func TestHandleHealthz(t *testing.T) {
+	chain, wk, _ := setupHealthTest(t)
+	chain.balance = new(big.Int)
+
+	var report healthReport
+	if code := serveAdmin(t, "/healthz", &report); code != http.StatusOK || report.Checks["worker:1"].Status != checkOK {
+		t.Fatalf("GET /healthz = %d %+v, want 200", code, report)
+	}
+	wk.health = chainHealth{Status: chainUnhealthy, ConsecutiveFailures: 5}
+	if code := serveAdmin(t, "/healthz", &report); code != http.StatusServiceUnavailable || report.Checks["worker:1"].Error != "chain 1 worker is unhealthy after 5 failures" {
+		t.Fatalf("GET /healthz = %d %+v, want 503", code, report)
+	}
+}
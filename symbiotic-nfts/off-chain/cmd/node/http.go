@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const httpShutdownTimeout = 5 * time.Second
+
+// httpMux serves the node's HTTP endpoints on --http-addr: metrics and the status API.
+var httpMux = http.NewServeMux()
+
+func init() {
+	httpMux.Handle("GET /metrics", promhttp.Handler())
+}
+
+// serveHTTP listens on addr and serves httpMux until ctx is done.
+func serveHTTP(ctx context.Context, addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return errors.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	srv := &http.Server{
+		Handler:           httpMux,
+		ReadHeaderTimeout: 10 * time.Second,
+		// Streaming requests end with the node instead of holding up the shutdown.
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), httpShutdownTimeout)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.ErrorContext(ctx, "HTTP server failed", "addr", addr, "err", err)
+		}
+	}()
+	slog.InfoContext(ctx, "Serving HTTP", "addr", ln.Addr().String())
+	return nil
+}
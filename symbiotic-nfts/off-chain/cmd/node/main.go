@@ -2,29 +2,38 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log/slog"
+	"maps"
 	"math/big"
 	"os"
 	"os/signal"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"sum/internal/utils"
 
-	"github.com/ethereum/go-ethereum" 
-	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
-	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/go-errors/errors"
 	"github.com/spf13/cobra"
-	v1 "github.com/symbioticfi/relay/api/client/v1"
+	"github.com/spf13/pflag"
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
-	"sum/internal/contracts"
+	"sum/internal/signer"
+	"sum/internal/store"
+	"sum/internal/txmgr"
 )
 
 const (
@@ -34,43 +43,109 @@ const (
 	TaskNotFound
 )
 
-// NFT standards
-// todo - support erc20s
+// Token standards
 const (
 	StdERC721  = uint8(0)
 	StdERC1155 = uint8(1)
+	StdERC20   = uint8(2)
+	// StdERC721Collection checks balanceOf(owner) over the whole collection instead of a single token.
+	StdERC721Collection = uint8(3)
 )
 
 type config struct {
-	relayApiURL       string
-	evmRpcURLs        []string
-	contractAddresses []string 
-	privateKey        string
-	logLevel          string
-	nftRpcMap string
+	configFile         string
+	relayURLs          []string
+	relayHealthEvery   time.Duration
+	relayTLS           bool
+	relayCAFile        string
+	relayServerName    string
+	relayCertFile      string
+	relayKeyFile       string
+	relayTokenFile     string
+	relayMaxRetries    uint
+	relayRetryBackoff  time.Duration
+	relayBackoff       string
+	relayKeepalive     time.Duration
+	relayKeepaliveWait time.Duration
+	evmRpcURLs         []string
+	contractAddresses  []string
+	contractTypes      []string
+	privateKey         string
+	signerType         string
+	keystoreFile       string
+	keystorePassword   string
+	externalSigner     string
+	signerAddress      string
+	pkcs11Module       string
+	pkcs11TokenLabel   string
+	pkcs11KeyLabel     string
+	pkcs11PinFile      string
+	logLevel           string
+	nftRpcMap          string
+	dbPath             string
+	httpAddr           string
+	maxScanLag         uint64
+	minBalanceEth      float64
+	otlpEndpoint       string
+	otlpInsecure       bool
+	blockTag           string
+	confirmations      uint64
+	chainConfirmations string
+	maxBlockRange      uint64
+	chainBlockRanges   string
+	startBlocks        []string
+	chainErrorBudget   int
+	chainMaxBackoff    time.Duration
+	maxFeeGwei         uint64
+	maxTipGwei         uint64
+	baseFeeMultiplier  uint64
+	feeBumpPercent     uint64
+	txStuckAfter       time.Duration
+	txMaxReplacements  int
+	txConfirmations    uint64
+	operatorAddress    string
+	submitStagger      time.Duration
+	deliveryTargets    string
+	resignMargin       time.Duration
+	aggregationTimeout time.Duration
 }
 
 var cfg config
 
 var (
-	relayClient  *v1.SymbioticClient
-	appClients   map[int64]*ethclient.Client      
-	nftClients   map[uint64]*ethclient.Client         
-	nftContracts map[int64]*contracts.NftOwnershipTask 
-	lastBlocks   map[int64]uint64                     
-	tasks        map[common.Hash]TaskState            
-	nftRPCs      map[uint64]string                   
+	appClients         map[int64]*ethclient.Client
+	nftClients         map[uint64]*ethclient.Client
+	txManagers         map[int64]*txmgr.Manager
+	tasks              map[store.TaskKey]TaskState
+	tasksMu            sync.Mutex
+	nftClientsMu       sync.Mutex
+	db                 *store.Store
+	chainConfirmations map[int64]uint64
+	chainBlockRanges   map[int64]uint64
 )
 
 type TaskState struct {
 	ChainID        int64
+	Contract       common.Address
 	TaskID         common.Hash
-	Req            contracts.NftOwnershipTaskRequest
+	Type           string
+	Req            json.RawMessage
 	Payload        []byte
 	SigEpoch       int64
 	SigRequestHash string
 	AggProof       []byte
-	Statuses       map[int64]uint8 
+	ProofAt        time.Time
+	MinEpoch       int64
+	Attempts       []SignAttempt
+	Statuses       map[int64]uint8
+	Submissions    map[int64]txmgr.Tx
+	// FailedSubmissions keeps the failed response txs per chain, dropped from Submissions to be resubmitted.
+	FailedSubmissions map[int64][]txmgr.Tx `json:",omitempty"`
+	// Rejections counts the reverted response simulations per chain, see preflightResponse.
+	Rejections       map[int64]simRejection `json:",omitempty"`
+	CreatedBlock     uint64
+	CreatedBlockHash common.Hash
+	CreatedAt        time.Time
 }
 
 func main() {
@@ -84,34 +159,78 @@ func main() {
 }
 
 func run() error {
-	rootCmd.PersistentFlags().StringVarP(&cfg.relayApiURL, "relay-api-url", "r", "", "Relay API URL (gRPC)")
-	rootCmd.PersistentFlags().StringSliceVarP(&cfg.evmRpcURLs, "evm-rpc-urls", "e", []string{}, "EVM RPC URLs for app chains (comma-separated)")
-	rootCmd.PersistentFlags().StringSliceVarP(&cfg.contractAddresses, "contract-addresses", "a", []string{}, "NftOwnershipTask contract addresses (comma-separated; must align with --evm-rpc-urls)")
-	rootCmd.PersistentFlags().StringVarP(&cfg.privateKey, "private-key", "p", "", "Task response private key (hex, no 0x)")
-	rootCmd.PersistentFlags().StringVarP(&cfg.logLevel, "log-level", "l", "info", "Log level: debug|info|warn|error")
-	rootCmd.PersistentFlags().StringVar(&cfg.nftRpcMap, "nft-rpc-map", "", "NFT chain RPC map: '1=https://...,11155111=https://...,31337=http://127.0.0.1:8545'")
-
-	if err := rootCmd.MarkPersistentFlagRequired("relay-api-url"); err != nil {
-		return errors.Errorf("failed to mark relay-api-url as required: %w", err)
-	}
-	if err := rootCmd.MarkPersistentFlagRequired("evm-rpc-urls"); err != nil {
-		return errors.Errorf("failed to mark evm-rpc-urls as required: %w", err)
-	}
-	if err := rootCmd.MarkPersistentFlagRequired("contract-addresses"); err != nil {
-		return errors.Errorf("failed to mark contract-addresses as required: %w", err)
-	}
-	if err := rootCmd.MarkPersistentFlagRequired("private-key"); err != nil {
-		return errors.Errorf("failed to mark private-key as required: %w", err)
-	}
-
+	registerFlags(rootCmd.PersistentFlags())
 	return rootCmd.Execute()
 }
 
+// registerFlags binds the node's flags to cfg.
+func registerFlags(fs *pflag.FlagSet) {
+	fs.StringVarP(&cfg.configFile, "config", "c", "", "YAML or TOML config file; flags and "+envPrefix+"* environment variables override its settings")
+	fs.StringSliceVarP(&cfg.relayURLs, "relay-api-url", "r", []string{}, "Relay API URLs (gRPC, comma-separated); calls fail over to the next healthy relay in order")
+	fs.DurationVar(&cfg.relayHealthEvery, "relay-health-interval", 10*time.Second, "Interval of the relay health probes")
+	grpcDefaults := utils.DefaultGRPCOptions()
+	fs.BoolVar(&cfg.relayTLS, "relay-tls", false, "Connect to the relay over TLS, verified against the system roots or --relay-ca-file")
+	fs.StringVar(&cfg.relayCAFile, "relay-ca-file", "", "PEM CA certificates the relay's TLS certificate is verified against (implies --relay-tls)")
+	fs.StringVar(&cfg.relayServerName, "relay-server-name", "", "Host name the relay's TLS certificate is verified against (default: host of each --relay-api-url)")
+	fs.StringVar(&cfg.relayCertFile, "relay-cert-file", "", "PEM client certificate for mTLS to the relay (implies --relay-tls)")
+	fs.StringVar(&cfg.relayKeyFile, "relay-key-file", "", "PEM key of --relay-cert-file")
+	fs.StringVar(&cfg.relayTokenFile, "relay-token-file", "", "File holding a bearer token sent with every relay call, re-read per call; requires TLS")
+	fs.UintVar(&cfg.relayMaxRetries, "relay-max-retries", grpcDefaults.MaxRetries, "Retries of a relay call failing with Unavailable or ResourceExhausted (0 = no retries)")
+	fs.DurationVar(&cfg.relayRetryBackoff, "relay-retry-backoff", grpcDefaults.RetryBackoff, "Wait before retrying a relay call")
+	fs.StringVar(&cfg.relayBackoff, "relay-backoff", grpcDefaults.Backoff, "Relay retry backoff: "+utils.BackoffLinear+"|"+utils.BackoffExponential)
+	fs.DurationVar(&cfg.relayKeepalive, "relay-keepalive-time", grpcDefaults.KeepaliveTime, "Idle time after which the relay connection is pinged (0 = no keepalive pings)")
+	fs.DurationVar(&cfg.relayKeepaliveWait, "relay-keepalive-timeout", grpcDefaults.KeepaliveTimeout, "Time a relay keepalive ping may go unanswered before the connection is closed")
+	fs.StringSliceVarP(&cfg.evmRpcURLs, "evm-rpc-urls", "e", []string{}, "EVM RPC URLs for app chains (comma-separated)")
+	fs.StringSliceVarP(&cfg.contractAddresses, "contract-addresses", "a", []string{}, "Task contract addresses (comma-separated; must align with --evm-rpc-urls, repeat an RPC URL to watch several contracts on its chain)")
+	fs.StringSliceVarP(&cfg.contractTypes, "contract-types", "t", []string{}, "Task contract types (comma-separated; must align with --contract-addresses, default nft-ownership): "+strings.Join(handlerTypes(), "|"))
+	fs.StringVarP(&cfg.privateKey, "private-key", "p", "", "Task response private key (hex, no 0x); development only, prefer --keystore or --external-signer")
+	fs.StringVar(&cfg.signerType, "signer", "", "Transaction signer backend: raw|keystore|external|pkcs11 (default: inferred from the signer flags)")
+	fs.StringVar(&cfg.keystoreFile, "keystore", "", "Encrypted go-ethereum keystore file of the response key")
+	fs.StringVar(&cfg.keystorePassword, "keystore-password-file", "", "File holding the --keystore passphrase")
+	fs.StringVar(&cfg.externalSigner, "external-signer", "", "Clef compatible external signer endpoint (IPC path or HTTP URL)")
+	fs.StringVar(&cfg.signerAddress, "signer-address", "", "Account of the external signer to use, required if it manages several")
+	fs.StringVar(&cfg.pkcs11Module, "pkcs11-module", "", "PKCS#11 library of the HSM holding the response key, e.g. /usr/lib/softhsm/libsofthsm2.so")
+	fs.StringVar(&cfg.pkcs11TokenLabel, "pkcs11-token-label", "", "Label of the PKCS#11 token, required if the module exposes several")
+	fs.StringVar(&cfg.pkcs11KeyLabel, "pkcs11-key-label", "", "Label of the secp256k1 key pair on the PKCS#11 token")
+	fs.StringVar(&cfg.pkcs11PinFile, "pkcs11-pin-file", "", "File holding the PKCS#11 user PIN")
+	fs.StringVarP(&cfg.logLevel, "log-level", "l", "info", "Log level: debug|info|warn|error")
+	fs.StringVar(&cfg.nftRpcMap, "nft-rpc-map", "", "NFT chain RPC map: '1=https://...,11155111=https://...,31337=http://127.0.0.1:8545'")
+	fs.StringVar(&cfg.dbPath, "db-path", "nft-node.db", "Path to the on-disk task store")
+	fs.StringVar(&cfg.httpAddr, "http-addr", "", "Address of the HTTP server exposing Prometheus metrics on /metrics, the status API and /healthz and /readyz probes, e.g. :9090 (empty = disabled)")
+	fs.Uint64Var(&cfg.maxScanLag, "max-scan-lag", 100, "Blocks an app chain scan may trail its --block-tag head, confirmation depth included, before /readyz fails (0 = unchecked)")
+	fs.Float64Var(&cfg.minBalanceEth, "min-balance-eth", 0, "Responder balance per app chain below which /readyz fails (0 = unchecked)")
+	fs.StringVar(&cfg.otlpEndpoint, "otlp-endpoint", "", "OTLP gRPC endpoint (host:port) receiving one trace per task (empty = tracing disabled)")
+	fs.BoolVar(&cfg.otlpInsecure, "otlp-insecure", false, "Connect to --otlp-endpoint without TLS")
+	fs.StringVar(&cfg.blockTag, "block-tag", "latest", "App chain head used for TaskCreated ingestion: latest|safe|finalized")
+	fs.Uint64Var(&cfg.confirmations, "confirmations", 0, "Confirmation depth below --block-tag before a TaskCreated event is ingested")
+	fs.StringVar(&cfg.chainConfirmations, "chain-confirmations", "", "Per app chain confirmation depth overrides: '31337=0,11155111=5'")
+	fs.Uint64Var(&cfg.maxBlockRange, "max-block-range", 10000, "Max blocks per eth_getLogs request when scanning app chains (0 = unlimited)")
+	fs.StringVar(&cfg.chainBlockRanges, "chain-max-block-ranges", "", "Per app chain --max-block-range overrides: '1=2000,11155111=500'")
+	fs.StringSliceVar(&cfg.startBlocks, "start-block", []string{}, "Block to start scanning each task contract from when no checkpoint exists (comma-separated; must align with --contract-addresses)")
+	fs.IntVar(&cfg.chainErrorBudget, "chain-error-budget", 5, "Consecutive failures after which an app chain is reported unhealthy")
+	fs.DurationVar(&cfg.chainMaxBackoff, "chain-max-backoff", time.Minute, "Max retry backoff of a failing app chain")
+	fs.Uint64Var(&cfg.maxFeeGwei, "max-fee-gwei", 0, "Cap on the max fee per gas of response txs in gwei (0 = uncapped)")
+	fs.Uint64Var(&cfg.maxTipGwei, "max-tip-gwei", 0, "Cap on the priority fee per gas of response txs in gwei (0 = uncapped)")
+	fs.Uint64Var(&cfg.baseFeeMultiplier, "base-fee-multiplier", 2, "Max fee per gas is base fee times this plus the priority fee")
+	fs.Uint64Var(&cfg.feeBumpPercent, "fee-bump-percent", 20, "Fee increase in percent when replacing a stuck response tx (min 10)")
+	fs.DurationVar(&cfg.txStuckAfter, "tx-stuck-after", 45*time.Second, "Time a response tx may stay pending before it is replaced")
+	fs.IntVar(&cfg.txMaxReplacements, "tx-max-replacements", 5, "Max replacements of a stuck response tx")
+	fs.Uint64Var(&cfg.txConfirmations, "tx-confirmations", 1, "Blocks a response tx receipt must be deep before the response is confirmed")
+	fs.StringVar(&cfg.operatorAddress, "operator-address", "", "This node's operator address in the relay validator set; enables submitter election")
+	fs.DurationVar(&cfg.submitStagger, "submit-stagger", 15*time.Second, "Delay per election rank before a backup operator submits a response")
+	fs.StringVar(&cfg.deliveryTargets, "delivery-targets", "", "Chains responses are mirrored to besides their origin chain: '31337=11155111|84532'")
+	fs.DurationVar(&cfg.resignMargin, "resign-margin", 5*time.Minute, "Re-sign a task when its signing epoch stops being accepted within this time")
+	fs.DurationVar(&cfg.aggregationTimeout, "aggregation-timeout", 10*time.Minute, "Re-sign a task at a newer epoch when no aggregation proof arrived within this time")
+}
+
 var rootCmd = &cobra.Command{
 	Use:           "nft-verify-node",
 	SilenceUsage:  true,
 	SilenceErrors: true,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := loadConfig(cmd.Flags(), cfg.configFile); err != nil {
+			return err
+		}
 		switch cfg.logLevel {
 		case "debug":
 			slog.SetLogLoggerLevel(slog.LevelDebug)
@@ -121,392 +240,771 @@ var rootCmd = &cobra.Command{
 			slog.SetLogLoggerLevel(slog.LevelWarn)
 		case "error":
 			slog.SetLogLoggerLevel(slog.LevelError)
+		default:
+			return errors.Errorf("unknown log level %q", cfg.logLevel)
 		}
 
 		ctx := signalContext(context.Background())
 
-		conn, err := utils.GetGRPCConnection(cfg.relayApiURL)
+		shutdownTracing, err := setupTracing(ctx)
 		if err != nil {
-			return errors.Errorf("failed to create relay client: %w", err)
+			return err
 		}
-		relayClient = v1.NewSymbioticClient(conn)
+		defer func() {
+			flushCtx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+			defer cancel()
+			if err := shutdownTracing(flushCtx); err != nil {
+				slog.Warn("Failed to flush traces", "err", err)
+			}
+		}()
 
-		if len(cfg.evmRpcURLs) == 0 {
-			return errors.Errorf("no app chain RPC URLs provided")
+		if len(cfg.relayURLs) == 0 {
+			return errors.Errorf("relay API URL is required, set --relay-api-url or relay.urls in --config")
 		}
-		if len(cfg.contractAddresses) != len(cfg.evmRpcURLs) {
-			return errors.Errorf("mismatched lengths: evm-rpc-urls=%d, contract-addresses=%d", len(cfg.evmRpcURLs), len(cfg.contractAddresses))
+		if cfg.relayHealthEvery <= 0 {
+			return errors.Errorf("relay-health-interval must be positive, got %s", cfg.relayHealthEvery)
+		}
+		relays, err = newRelayPool(cfg.relayURLs, relayGRPCOptions())
+		if err != nil {
+			return err
+		}
+		defer relays.close()
+
+		if cfg.chainErrorBudget < 1 {
+			return errors.Errorf("chain-error-budget must be at least 1, got %d", cfg.chainErrorBudget)
+		}
+		if cfg.chainMaxBackoff < workerMinBackoff {
+			return errors.Errorf("chain-max-backoff must be at least %s, got %s", workerMinBackoff, cfg.chainMaxBackoff)
+		}
+		if cfg.minBalanceEth < 0 {
+			return errors.Errorf("min-balance-eth must not be negative, got %g", cfg.minBalanceEth)
+		}
+
+		if cfg.operatorAddress != "" && !common.IsHexAddress(cfg.operatorAddress) {
+			return errors.Errorf("invalid operator address %q", cfg.operatorAddress)
+		}
+
+		txSigner, err := openSigner()
+		if err != nil {
+			return err
+		}
+		defer txSigner.Close()
+		slog.Info("loaded transaction signer", "backend", cfg.signerType, "address", txSigner.Address().Hex())
+		traceNode = txSigner.Address()
+		if cfg.operatorAddress != "" {
+			traceNode = common.HexToAddress(cfg.operatorAddress)
+		}
+		txConfig := txmgr.Config{
+			MaxFeeCap:         gweiToWei(cfg.maxFeeGwei),
+			MaxTipCap:         gweiToWei(cfg.maxTipGwei),
+			BaseFeeMultiplier: cfg.baseFeeMultiplier,
+			BumpPercent:       cfg.feeBumpPercent,
+			StuckAfter:        cfg.txStuckAfter,
+			MaxReplacements:   cfg.txMaxReplacements,
+			Confirmations:     cfg.txConfirmations,
 		}
 
-		nftRPCs = parseRPCMap(cfg.nftRpcMap)
 		appClients = make(map[int64]*ethclient.Client)
-		nftContracts = make(map[int64]*contracts.NftOwnershipTask)
+		handlers = make(map[int64]map[common.Address]TaskHandler)
+		txManagers = make(map[int64]*txmgr.Manager)
 		nftClients = make(map[uint64]*ethclient.Client)
-		tasks = make(map[common.Hash]TaskState)
-		lastBlocks = make(map[int64]uint64)
 
-		for i, evmRpcURL := range cfg.evmRpcURLs {
-			appCli, err := ethclient.DialContext(ctx, evmRpcURL)
+		db, err = store.Open(cfg.dbPath)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		var legacyTasks []TaskState
+		tasks, legacyTasks, err = loadTasks()
+		if err != nil {
+			return errors.Errorf("failed to load tasks: %w", err)
+		}
+		checkpoints, err := db.Checkpoints()
+		if err != nil {
+			return errors.Errorf("failed to load block checkpoints: %w", err)
+		}
+		slog.Info("loaded node state", "path", cfg.dbPath, "tasks", len(tasks), "checkpoints", checkpoints)
+
+		workers = make(map[int64]*chainWorker)
+		// Specs of the same chain, e.g. --evm-rpc-urls repeating an RPC for a second contract, share one client and worker.
+		startBlocks := make(map[int64]uint64)
+		wsChains := make(map[int64]bool)
+		for _, spec := range appChains {
+			appCli, rpcURL, chainID, err := dialFirst(ctx, "app", spec.RPCs, 0, 0)
 			if err != nil {
-				return errors.Errorf("failed to connect to app RPC '%s': %w", evmRpcURL, err)
+				return errors.Errorf("failed to connect to app chain: %w", err)
 			}
-			chainID, err := appCli.ChainID(ctx)
-			if err != nil {
-				return errors.Errorf("failed to get chain ID from '%s': %w", evmRpcURL, err)
+			if spec.ChainID != 0 && chainID.Int64() != spec.ChainID {
+				return errors.Errorf("app chain RPC '%s' reports chain ID %d, configured as %d", rpcURL, chainID, spec.ChainID)
 			}
-			addr := common.HexToAddress(cfg.contractAddresses[i])
-			nc, err := contracts.NewNftOwnershipTask(addr, appCli)
-			if err != nil {
-				return errors.Errorf("failed to bind NftOwnershipTask at %s on chain %d: %w", addr.Hex(), chainID, err)
+			id := chainID.Int64()
+			if cli, ok := appClients[id]; ok {
+				appCli.Close()
+				appCli = cli
+			} else {
+				txm, err := txmgr.New(appCli, chainID, txSigner.Address(), signer.SignerFn(txSigner, chainID), txConfig)
+				if err != nil {
+					return errors.Errorf("invalid tx policy: %w", err)
+				}
+				appClients[id] = appCli
+				txManagers[id] = txm
+				handlers[id] = make(map[common.Address]TaskHandler)
+				wsChains[id] = isWebsocketURL(rpcURL)
+				startBlocks[id] = spec.Contracts[0].StartBlock
 			}
-			appClients[chainID.Int64()] = appCli
-			nftContracts[chainID.Int64()] = nc
-			slog.Info("bound app contract", "chainID", chainID, "address", addr.Hex())
-		}
-
-		ticker := time.NewTicker(1 * time.Second)
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-ticker.C:
-				for chainID, appCli := range appClients {
-					endBlock, err := appCli.BlockByNumber(ctx, nil)
-					if err != nil {
-						return errors.Errorf("failed to get latest block: %w", err)
-					}
-					end := endBlock.NumberU64()
-					start := lastBlocks[chainID]
-
-					slog.DebugContext(ctx, "Fetching TaskCreated events", "chainID", chainID, "fromBlock", start, "toBlock", end)
-
-					iter, err := nftContracts[chainID].FilterTaskCreated(&bind.FilterOpts{
-						Context: ctx,
-						Start:   start,
-						End:     &end,
-					}, [][32]byte{})
-					if err != nil {
-						return errors.Errorf("failed to filter TaskCreated events: %w", err)
-					}
-
-					lastBlocks[chainID] = end + 1
-
-					if err := processNewTasks(ctx, chainID, iter); err != nil {
-						slog.Error("Error processing new task events", "err", err)
-					}
+			for _, contract := range spec.Contracts {
+				if _, dup := handlers[id][contract.Address]; dup {
+					return errors.Errorf("contract %s on chain %d is configured twice", contract.Address.Hex(), chainID)
 				}
-				if err := fetchResults(ctx); err != nil {
-					slog.Error("Error fetching results", "err", err)
+				h, err := newHandler(contract.Type, contract.Address, appCli)
+				if err != nil {
+					return errors.Errorf("failed to bind %s contract at %s on chain %d: %w", contract.Type, contract.Address.Hex(), chainID, err)
 				}
-			case <-ctx.Done():
-				return nil
+				handlers[id][contract.Address] = h
+				// One checkpoint covers all contracts of the chain, so scanning starts at the lowest start block.
+				startBlocks[id] = min(startBlocks[id], contract.StartBlock)
+				slog.Info("bound app contract", "chainID", chainID, "rpc", rpcURL, "address", contract.Address.Hex(), "type", contract.Type)
 			}
 		}
-	},
-}
+		for id, appCli := range appClients {
+			next := max(checkpoints[id], startBlocks[id])
+			workers[id] = newChainWorker(id, appCli, next, wsChains[id])
+		}
 
-func fetchResults(ctx context.Context) error {
-	for taskID, state := range tasks {
-		for chainID := range nftContracts {
-			if state.Statuses[chainID] == TaskResponded {
-				continue
-			}
-			status, err := nftContracts[chainID].GetTaskStatus(&bind.CallOpts{Context: ctx}, taskID)
-			if err != nil {
+		migrateLegacyTasks(legacyTasks)
+
+		if err := validateDeliveryTargets(); err != nil {
+			return err
+		}
+
+		if cfg.httpAddr != "" {
+			if err := serveHTTP(ctx, cfg.httpAddr); err != nil {
 				return err
 			}
-			state.Statuses[chainID] = status
 		}
-		slog.InfoContext(ctx, "Task statuses", "taskID", taskID, "statuses", state.Statuses)
 
-		allNotFoundOrExpired := true
-		allResponded := true
-		for _, status := range state.Statuses {
-			if status != TaskNotFound && status != TaskExpired {
-				allNotFoundOrExpired = false
+		go relays.watch(ctx, cfg.relayHealthEvery)
+
+		var wg sync.WaitGroup
+		for _, w := range workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				w.supervise(ctx)
+			}()
+		}
+		<-ctx.Done()
+		wg.Wait()
+		return nil
+	},
+}
+
+// processTasks advances the tasks delivered to the given app chain. The task's origin chain signs it and fetches
+// the aggregation proof, every target chain polls the task status and submits the response once the proof is there.
+func processTasks(ctx context.Context, chainID int64) error {
+	for _, state := range taskSnapshot() {
+		taskID, key := state.TaskID, state.Key()
+		if !isTaskTarget(state, chainID) {
+			continue
+		}
+		h, ok := targetHandler(state, chainID)
+		if !ok {
+			slog.DebugContext(ctx, "No contract bound for task", "task", key, "chainID", chainID)
+			continue
+		}
+		if state.Statuses[chainID] != TaskResponded {
+			status, err := h.GetTaskStatus(ctx, taskID)
+			if err != nil {
+				return err
 			}
-			if status != TaskResponded {
-				allResponded = false
+			state, ok = updateTask(key, func(st *TaskState) { st.Statuses[chainID] = status })
+			if !ok {
+				continue
 			}
 		}
-		if allNotFoundOrExpired || allResponded {
-			delete(tasks, taskID)
+		slog.InfoContext(ctx, "Task statuses", "taskID", taskID, "chainID", chainID, "statuses", state.Statuses)
+
+		if taskSettled(state) {
+			removeTask(key)
 			continue
 		}
 
-		if state.AggProof == nil {
-			resp, err := relayClient.GetAggregationProof(ctx, &v1.GetAggregationProofRequest{
-				RequestHash: state.SigRequestHash,
+		if state.ChainID == chainID {
+			state = prepareResponse(ctx, state)
+		}
+		if sub, ok := state.Submissions[chainID]; ok {
+			checked, err := checkSubmission(ctx, chainID, state, sub)
+			if err != nil {
+				slog.Error("Error checking response tx", "taskID", taskID, "chainID", chainID, "tx", sub.Hash(), "err", err)
+				continue
+			}
+			if checked.State != txmgr.StateFailed {
+				continue
+			}
+			// Drop the failed response and go on with the current task status, so that it's resubmitted
+			// while the task is open and the signature valid.
+			status, err := h.GetTaskStatus(ctx, taskID)
+			if err != nil {
+				return err
+			}
+			state, ok = updateTask(key, func(st *TaskState) {
+				if st.FailedSubmissions == nil {
+					st.FailedSubmissions = map[int64][]txmgr.Tx{}
+				}
+				st.FailedSubmissions[chainID] = append(st.FailedSubmissions[chainID], checked)
+				delete(st.Submissions, chainID)
+				st.Statuses[chainID] = status
 			})
+			if !ok {
+				continue
+			}
+			if taskSettled(state) {
+				removeTask(key)
+				continue
+			}
+		}
+		if state.SigRequestHash != "" && state.Statuses[chainID] != TaskResponded {
+			valid, err := checkSignatureValidity(ctx, chainID, state)
 			if err != nil {
+				slog.Error("Error checking signing epoch", "taskID", taskID, "chainID", chainID, "epoch", state.SigEpoch, "err", err)
+				continue
+			}
+			if !valid {
 				continue
 			}
-			state.AggProof = resp.AggregationProof.Proof
-			slog.InfoContext(ctx, "Got aggregation proof", "taskID", taskID, "proof", hexutil.Encode(resp.AggregationProof.Proof))
 		}
-
-		tasks[taskID] = state
-
-		if err := processProof(ctx, taskID); err != nil {
-			slog.Error("Error processing proof", "taskID", taskID, "err", err)
+		if state.AggProof == nil || state.Statuses[chainID] == TaskResponded {
+			continue
+		}
+		turn, err := submitterTurn(ctx, chainID, state)
+		if err != nil {
+			slog.Error("Error checking submitter turn", "taskID", taskID, "chainID", chainID, "err", err)
+			continue
+		}
+		if !turn {
+			continue
+		}
+		submit, err := preflightResponse(ctx, chainID, state)
+		if err != nil {
+			slog.Error("Error simulating response", "taskID", taskID, "chainID", chainID, "err", err)
+			continue
+		}
+		if !submit {
+			continue
+		}
+		if err := processProof(ctx, chainID, state); err != nil {
+			slog.Error("Error processing proof", "taskID", taskID, "chainID", chainID, "err", err)
 		}
 	}
 	return nil
 }
 
-func processProof(ctx context.Context, taskID common.Hash) error {
-	pk, err := crypto.HexToECDSA(cfg.privateKey)
-	if err != nil {
-		return errors.Errorf("failed to parse private key: %w", err)
+// taskSettled reports whether the task needs no more work: it was responded on every target chain,
+// or it expired or vanished on its origin chain. Destinations don't know the task, so only the origin decides that.
+func taskSettled(st TaskState) bool {
+	if status, ok := st.Statuses[st.ChainID]; ok && (status == TaskNotFound || status == TaskExpired) {
+		return true
 	}
+	for _, chainID := range taskTargets(st) {
+		if st.Statuses[chainID] != TaskResponded {
+			return false
+		}
+	}
+	return true
+}
 
-	st := tasks[taskID]
-	for chainID, status := range st.Statuses {
-		if status == TaskResponded {
-			continue
+// prepareResponse signs the task if that didn't succeed yet and fetches its aggregation proof.
+// It returns the updated state; failures are logged and retried on the next tick.
+func prepareResponse(ctx context.Context, state TaskState) TaskState {
+	taskID, key := state.TaskID, state.Key()
+	if state.SigRequestHash == "" {
+		signed := state
+		if err := signTask(ctx, &signed); err != nil {
+			slog.WarnContext(ctx, "Task not signed yet", "taskID", taskID, "err", err)
+			return state
 		}
-		txOpts, err := bind.NewKeyedTransactorWithChainID(pk, big.NewInt(chainID))
-		if err != nil {
-			return errors.Errorf("failed to create transactor: %w", err)
+		updated, ok := updateTask(key, func(st *TaskState) { setSignature(st, signed) })
+		if !ok {
+			return state
 		}
-		txOpts.Context = ctx
+		state = updated
+	}
 
-		tx, err := nftContracts[chainID].RespondTask(txOpts, taskID, st.Payload, big.NewInt(st.SigEpoch), st.AggProof)
+	if state.AggProof == nil {
+		proofCtx, span := startTaskSpan(ctx, state, "task.aggregation_proof", attribute.String("task.request_hash", state.SigRequestHash))
+		var signedBy string
+		if n := len(state.Attempts); n != 0 {
+			signedBy = state.Attempts[n-1].Relay
+		}
+		resp, err := relays.aggregationProof(proofCtx, signedBy, state.SigRequestHash)
+		span.SetAttributes(attribute.Bool("task.proof_found", err == nil))
+		if status.Code(err) == codes.NotFound {
+			// Not aggregated yet, the normal outcome of a poll.
+			span.End()
+		} else {
+			endSpan(span, err)
+		}
 		if err != nil {
-			return errors.Errorf("failed to respond task: %w", err)
+			if n := len(state.Attempts); n != 0 && time.Since(state.Attempts[n-1].SignedAt) > cfg.aggregationTimeout {
+				slog.WarnContext(ctx, "Aggregation timed out, re-signing", "taskID", taskID, "epoch", state.SigEpoch, "requestHash", state.SigRequestHash)
+				updateTask(key, func(st *TaskState) { retireSignature(st, state.SigRequestHash, retiredAggregationTimeout) })
+			}
+			return state
+		}
+		slog.InfoContext(ctx, "Got aggregation proof", "taskID", taskID, "proof", hexutil.Encode(resp.AggregationProof.Proof))
+		updated, ok := updateTask(key, func(st *TaskState) {
+			st.AggProof = resp.AggregationProof.Proof
+			st.ProofAt = time.Now()
+		})
+		if !ok {
+			return state
 		}
+		state = updated
+		observeSince(taskProofDuration, state, state.ChainID)
+	}
+	return state
+}
 
-		slog.InfoContext(ctx, "Submitted response tx", "taskID", taskID, "tx", tx.Hash().String(), "gas", tx.Gas())
+// processProof submits the proven response of the task on the given app chain through the chain's tx manager.
+func processProof(ctx context.Context, chainID int64, st TaskState) (err error) {
+	ctx, span := startTaskSpan(ctx, st, "task.respond", attribute.Int64("response.chain_id", chainID))
+	defer func() { endSpan(span, err) }()
+
+	h, ok := targetHandler(st, chainID)
+	if !ok {
+		return errors.Errorf("no contract bound for task %s on chain %d", st.Key(), chainID)
+	}
+	tx, err := txManagers[chainID].Send(ctx, func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return h.RespondTask(opts, st.TaskID, st.Payload, big.NewInt(st.SigEpoch), st.AggProof)
+	})
+	if err != nil {
+		return errors.Errorf("failed to respond task: %w", err)
 	}
+
+	span.SetAttributes(attribute.String("response.tx", tx.Hash().Hex()), attribute.Int64("response.nonce", int64(tx.Nonce)))
+	slog.InfoContext(ctx, "Submitted response tx", "taskID", st.TaskID, "chainID", chainID, "tx", tx.Hash().String(), "nonce", tx.Nonce, "gas", tx.Gas, "gasFeeCap", tx.GasFeeCap, "gasTipCap", tx.GasTipCap)
+
+	updateTask(st.Key(), func(s *TaskState) { s.Submissions[chainID] = tx })
 	return nil
 }
 
-func processNewTasks(ctx context.Context, appChainID int64, iter *contracts.NftOwnershipTaskTaskCreatedIterator) error {
-	for iter.Next() {
-		evt := iter.Event
+// checkSubmission follows a sent response tx until it is confirmed or failed, replacing it while it's stuck.
+// It returns the checked tx; a failed one is left to the caller to retry.
+func checkSubmission(ctx context.Context, chainID int64, st TaskState, sub txmgr.Tx) (checked txmgr.Tx, err error) {
+	key := st.Key()
+	if sub.State != txmgr.StateSubmitted {
+		return sub, nil
+	}
+	ctx, span := startTaskSpan(ctx, st, "task.check_response", attribute.Int64("response.chain_id", chainID), attribute.String("response.tx", sub.Hash().Hex()))
+	defer func() { endSpan(span, err) }()
 
-		status, err := nftContracts[appChainID].GetTaskStatus(&bind.CallOpts{Context: ctx}, evt.TaskId)
-		if err != nil {
-			return err
-		}
-		if status != TaskCreated {
+	checked, err = txManagers[chainID].Check(ctx, sub)
+	if err != nil {
+		return sub, err
+	}
+	span.SetAttributes(attribute.String("response.state", string(checked.State)))
+	switch {
+	case checked.State == txmgr.StateConfirmed:
+		slog.InfoContext(ctx, "Response tx confirmed", "task", key, "chainID", chainID, "tx", checked.Hash(), "block", checked.MinedBlock)
+		recordResponseTx(chainID, checked)
+		observeSince(taskResponseDuration, st, chainID)
+	case checked.State == txmgr.StateFailed:
+		slog.ErrorContext(ctx, "Response tx failed", "task", key, "chainID", chainID, "tx", checked.Hash(), "err", checked.Error)
+		recordResponseTx(chainID, checked)
+	case checked.Replacements != sub.Replacements:
+		slog.WarnContext(ctx, "Replaced stuck response tx", "task", key, "chainID", chainID, "tx", checked.Hash(), "gasFeeCap", checked.GasFeeCap, "gasTipCap", checked.GasTipCap)
+	case checked.MinedBlock == sub.MinedBlock && checked.NonceTakenAt == sub.NonceTakenAt:
+		return checked, nil
+	}
+	updateTask(key, func(s *TaskState) { s.Submissions[chainID] = checked })
+	return checked, nil
+}
+
+// processNewTasks ingests task creation logs of the chain's contracts. The batched log filter matches
+// every contract against every creation event, so logs are dispatched by address and checked against the contract's event.
+func processNewTasks(ctx context.Context, appChainID int64, logs []types.Log) error {
+	for _, lg := range logs {
+		h, ok := handlers[appChainID][lg.Address]
+		if !ok || len(lg.Topics) == 0 || lg.Topics[0] != h.CreatedEventID() {
 			continue
 		}
-
-		req := evt.Req
-		slog.InfoContext(ctx, "Received new task",
-			"taskID", evt.TaskId,
-			"chainId", req.ChainId,
-			"collection", req.Collection,
-			"tokenId", req.TokenId,
-			"owner", req.Owner,
-			"checkedBlock", req.CheckedBlock,
-			"standard", req.Standard,
-		)
-
-		isOwner, ownerAtBlock, observedBlock, err := verifyOwnership(ctx, req)
+		taskID, req, err := h.DecodeCreated(lg)
 		if err != nil {
-			slog.Error("verifyOwnership failed", "err", err)
+			return errors.Errorf("failed to decode task event of %s in tx %s: %w", lg.Address.Hex(), lg.TxHash.Hex(), err)
+		}
+		key := store.TaskKey{ChainID: appChainID, Contract: lg.Address, TaskID: taskID}
+		if _, ok := getTask(key); ok {
 			continue
 		}
-		slog.InfoContext(ctx, "Ownership verification",
-			"isOwner", isOwner,
-			"ownerAtBlock", ownerAtBlock.Hex(),
-			"observedBlock", observedBlock,
-		)
-
-		boolT, _ := abi.NewType("bool", "", nil)
-		addrT, _ := abi.NewType("address", "", nil)
-		u64T, _ := abi.NewType("uint64", "", nil)
-		payloadArgs := abi.Arguments{{Type: boolT}, {Type: addrT}, {Type: u64T}}
-		payload, err := payloadArgs.Pack(isOwner, ownerAtBlock, observedBlock)
+
+		status, err := h.GetTaskStatus(ctx, taskID)
 		if err != nil {
 			return err
 		}
+		if status != TaskCreated {
+			continue
+		}
 
-		bytes32T, _ := abi.NewType("bytes32", "", nil)
-		bytesT, _ := abi.NewType("bytes", "", nil)
-		msgArgs := abi.Arguments{{Type: bytes32T}, {Type: bytesT}}
-		msg, err := msgArgs.Pack(evt.TaskId, payload)
-		if err != nil {
-			return err
+		slog.InfoContext(ctx, "Received new task", "taskID", taskID, "chainID", appChainID, "contract", lg.Address, "type", h.Type(), "request", string(req))
+
+		createdAt := time.Now()
+		if header, err := appClients[appChainID].HeaderByNumber(ctx, new(big.Int).SetUint64(lg.BlockNumber)); err == nil {
+			createdAt = time.Unix(int64(header.Time), 0)
 		}
-		slog.InfoContext(ctx, "Message to sign", "msg", hexutil.Encode(msg))
 
-		suggestedEpoch, err := relayClient.GetSuggestedEpoch(ctx, &v1.GetSuggestedEpochRequest{})
-		if err != nil {
-			return err
+		st := TaskState{
+			ChainID:          appChainID,
+			Contract:         lg.Address,
+			TaskID:           taskID,
+			Type:             h.Type(),
+			Req:              req,
+			Statuses:         map[int64]uint8{},
+			Submissions:      map[int64]txmgr.Tx{},
+			CreatedBlock:     lg.BlockNumber,
+			CreatedBlockHash: lg.BlockHash,
+			CreatedAt:        createdAt,
 		}
-		signResp, err := relayClient.SignMessage(ctx, &v1.SignMessageRequest{
-			KeyTag:        15,
-			Message:       msg,
-			RequiredEpoch: &suggestedEpoch.Epoch,
-		})
+		taskCtx, span := startTaskRoot(ctx, st)
+		if err := signTask(taskCtx, &st); err != nil {
+			// Keep the unsigned task around, prepareResponse retries signing on later ticks until it settles.
+			slog.WarnContext(ctx, "Task not signed yet", "taskID", taskID, "err", err)
+		}
+		err = addTask(st)
+		span.End()
 		if err != nil {
 			return err
 		}
-
-		tasks[evt.TaskId] = TaskState{
-			ChainID:        appChainID,
-			TaskID:         evt.TaskId,
-			Req:            req,
-			Payload:        payload,
-			SigEpoch:       int64(signResp.Epoch),
-			SigRequestHash: signResp.RequestHash,
-			AggProof:       nil,
-			Statuses:       map[int64]uint8{},
-		}
-
-		slog.InfoContext(ctx, "Signed message", "taskID", evt.TaskId, "epoch", signResp.Epoch, "requestHash", signResp.RequestHash)
 	}
 	return nil
 }
 
-func verifyOwnership(ctx context.Context, req contracts.NftOwnershipTaskRequest) (bool, common.Address, uint64, error) {
-	targetChainID := req.ChainId.Uint64()
+// signTask computes the task's response payload with its handler and requests a signature from the relay.
+// It returns errAbstain without signing if the handler could not determine the answer.
+func signTask(ctx context.Context, st *TaskState) (err error) {
+	ctx, span := startTaskSpan(ctx, *st, "task.sign", attribute.Int("task.attempt", len(st.Attempts)+1))
+	defer func() { endSpan(span, err) }()
 
-	cli, err := getNFTClient(ctx, targetChainID)
-	if err != nil {
-		return false, common.Address{}, 0, err
+	h, ok := handlers[st.ChainID][st.Contract]
+	if !ok || h.Type() != st.Type {
+		return errors.Errorf("no %s contract %s bound on chain %d", st.Type, st.Contract.Hex(), st.ChainID)
 	}
 
-	var blockNum *big.Int
-	if req.CheckedBlock != 0 {
-		blockNum = new(big.Int).SetUint64(req.CheckedBlock)
+	payload, err := h.ComputePayload(ctx, st.TaskID, st.Req)
+	if err != nil {
+		return err
 	}
+	msg, err := h.EncodeMessage(st.TaskID, payload)
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "Message to sign", "msg", hexutil.Encode(msg))
 
-	switch req.Standard {
-	case StdERC721:
-		owner, observed, err := erc721OwnerOf(ctx, cli, req.Collection, req.TokenId, blockNum)
-		if err != nil {
-			return false, common.Address{}, observed, nil
-		}
-		return strings.EqualFold(owner.Hex(), req.Owner.Hex()), owner, observed, nil
+	epoch, err := signingEpoch(ctx, st)
+	if err != nil {
+		return err
+	}
+	signResp, relayURL, err := relays.signMessage(ctx, epoch, msg)
+	if err != nil {
+		return err
+	}
 
-	case StdERC1155:
-		ok, observed, err := erc1155HasBalance(ctx, cli, req.Collection, req.Owner, req.TokenId, blockNum)
-		if err != nil {
-			return false, common.Address{}, observed, nil
-		}
-		return ok, req.Owner, observed, nil
+	attempts := append(slices.Clone(st.Attempts), SignAttempt{
+		Epoch:       int64(signResp.Epoch),
+		RequestHash: signResp.RequestHash,
+		Relay:       relayURL,
+		SignedAt:    time.Now(),
+	})
+	setSignature(st, TaskState{Payload: payload, SigEpoch: int64(signResp.Epoch), SigRequestHash: signResp.RequestHash, Attempts: attempts})
 
-	default:
-		return false, common.Address{}, 0, fmt.Errorf("unknown standard %d", req.Standard)
+	span.SetAttributes(attribute.Int64("task.epoch", int64(signResp.Epoch)), attribute.String("task.request_hash", signResp.RequestHash), attribute.String("task.relay", relayURL))
+	slog.InfoContext(ctx, "Signed message", "taskID", st.TaskID, "epoch", signResp.Epoch, "requestHash", signResp.RequestHash, "relay", relayURL, "attempt", len(attempts))
+	if len(attempts) == 1 {
+		observeSince(taskSignDuration, *st, st.ChainID)
 	}
+	pruneEpochCaches(signResp.Epoch)
+	return nil
 }
 
-func erc721OwnerOf(ctx context.Context, cli *ethclient.Client, collection common.Address, tokenId *big.Int, block *big.Int) (common.Address, uint64, error) {
-	const abiJSON = `[{"name":"ownerOf","type":"function","stateMutability":"view","inputs":[{"name":"tokenId","type":"uint256"}],"outputs":[{"name":"owner","type":"address"}]}]`
-	pa, err := abi.JSON(strings.NewReader(abiJSON))
+// loadTasks restores in-flight task states persisted by a previous run. Tasks stored before contracts
+// were part of the task key are returned separately, see migrateLegacyTasks.
+func loadTasks() (map[store.TaskKey]TaskState, []TaskState, error) {
+	m := make(map[store.TaskKey]TaskState)
+	var legacy []TaskState
+	err := db.ForEachTask(func(key store.TaskKey, isLegacy bool, data []byte) error {
+		var st TaskState
+		if err := json.Unmarshal(data, &st); err != nil {
+			return errors.Errorf("failed to decode task %s: %w", key, err)
+		}
+		if st.Statuses == nil {
+			st.Statuses = map[int64]uint8{}
+		}
+		if st.Submissions == nil {
+			st.Submissions = map[int64]txmgr.Tx{}
+		}
+		if st.Type == "" {
+			st.Type = nftOwnershipType
+		}
+		if isLegacy {
+			legacy = append(legacy, st)
+			return nil
+		}
+		m[key] = st
+		return nil
+	})
 	if err != nil {
-		return common.Address{}, 0, err
+		return nil, nil, err
 	}
-	data, err := pa.Pack("ownerOf", tokenId)
-	if err != nil {
-		return common.Address{}, 0, err
+	return m, legacy, nil
+}
+
+// migrateLegacyTasks assigns tasks stored under a bare task ID to the only contract of their type bound
+// on their chain and re-keys them. Tasks that can't be assigned unambiguously stay in the store untouched.
+func migrateLegacyTasks(legacy []TaskState) {
+	for _, st := range legacy {
+		h, ok := handlerOfType(st.ChainID, st.Type)
+		if !ok {
+			slog.Error("Can't assign stored task to a contract, skipping", "taskID", st.TaskID, "chainID", st.ChainID, "type", st.Type)
+			continue
+		}
+		st.Contract = h.Address()
+		if err := db.MigrateTask(st.TaskID, st.Key(), st); err != nil {
+			slog.Error("Failed to migrate stored task", "task", st.Key(), "err", err)
+			continue
+		}
+		tasks[st.Key()] = st
+		slog.Info("Migrated stored task", "task", st.Key())
 	}
-	out, err := cli.CallContract(ctx, ethereum.CallMsg{To: &collection, Data: data}, block)
-	if err != nil {
-		return common.Address{}, 0, err
+}
+
+func saveTask(st TaskState) {
+	if err := db.PutTask(st.Key(), st); err != nil {
+		slog.Error("Failed to persist task", "task", st.Key(), "err", err)
+	}
+}
+
+// setSignature copies the signature and attempt history of src into st, dropping any proof of a previous signature.
+func setSignature(st *TaskState, src TaskState) {
+	st.Payload = src.Payload
+	st.SigEpoch = src.SigEpoch
+	st.SigRequestHash = src.SigRequestHash
+	st.Attempts = src.Attempts
+	st.AggProof = nil
+	st.ProofAt = time.Time{}
+}
+
+// Key identifies the task across chains and contracts.
+func (st TaskState) Key() store.TaskKey {
+	return store.TaskKey{ChainID: st.ChainID, Contract: st.Contract, TaskID: st.TaskID}
+}
+
+// clone returns a copy of the state that shares no maps with st.
+func (st TaskState) clone() TaskState {
+	st.Statuses = maps.Clone(st.Statuses)
+	st.Attempts = slices.Clone(st.Attempts)
+	st.Submissions = maps.Clone(st.Submissions)
+	for chainID, tx := range st.Submissions {
+		tx.Hashes = slices.Clone(tx.Hashes)
+		st.Submissions[chainID] = tx
 	}
-	var outVals []interface{}
-	if err := pa.UnpackIntoInterface(&outVals, "ownerOf", out); err != nil {
-		return common.Address{}, 0, err
+	st.Rejections = maps.Clone(st.Rejections)
+	st.FailedSubmissions = maps.Clone(st.FailedSubmissions)
+	for chainID, txs := range st.FailedSubmissions {
+		st.FailedSubmissions[chainID] = slices.Clone(txs)
 	}
-	owner := outVals[0].(common.Address)
+	return st
+}
 
-	var observed uint64
-	if block != nil {
-		observed = block.Uint64()
-	} else {
-		h, err := cli.HeaderByNumber(ctx, nil)
-		if err != nil {
-			return common.Address{}, 0, err
-		}
-		observed = h.Number.Uint64()
+// Tasks are shared by all chain workers; they are only accessed through the helpers below.
+
+func getTask(key store.TaskKey) (TaskState, bool) {
+	tasksMu.Lock()
+	defer tasksMu.Unlock()
+	st, ok := tasks[key]
+	return st.clone(), ok
+}
+
+func taskSnapshot() []TaskState {
+	tasksMu.Lock()
+	defer tasksMu.Unlock()
+	out := make([]TaskState, 0, len(tasks))
+	for _, st := range tasks {
+		out = append(out, st.clone())
 	}
-	return owner, observed, nil
+	return out
 }
 
-func erc1155HasBalance(ctx context.Context, cli *ethclient.Client, collection, owner common.Address, tokenId *big.Int, block *big.Int) (bool, uint64, error) {
-	const abiJSON = `[{"name":"balanceOf","type":"function","stateMutability":"view","inputs":[{"name":"account","type":"address"},{"name":"id","type":"uint256"}],"outputs":[{"name":"","type":"uint256"}]}]`
-	pa, err := abi.JSON(strings.NewReader(abiJSON))
-	if err != nil {
-		return false, 0, err
+// addTask stores a new task unless another worker already did.
+func addTask(st TaskState) error {
+	tasksMu.Lock()
+	defer tasksMu.Unlock()
+	if _, ok := tasks[st.Key()]; ok {
+		return nil
 	}
-	data, err := pa.Pack("balanceOf", owner, tokenId)
-	if err != nil {
-		return false, 0, err
+	// A new task is only tracked once it's persisted, the scan checkpoint moves past its event afterwards.
+	if err := db.PutTask(st.Key(), st); err != nil {
+		return errors.Errorf("failed to persist task %s: %w", st.Key(), err)
 	}
-	out, err := cli.CallContract(ctx, ethereum.CallMsg{To: &collection, Data: data}, block)
-	if err != nil {
-		return false, 0, err
+	tasks[st.Key()] = st.clone()
+	taskEvents.publish("", st.clone(), taskPhase(st))
+	return nil
+}
+
+// updateTask applies fn to the stored task and persists it. It returns the updated state,
+// or false if the task is gone.
+func updateTask(key store.TaskKey, fn func(st *TaskState)) (TaskState, bool) {
+	tasksMu.Lock()
+	defer tasksMu.Unlock()
+	st, ok := tasks[key]
+	if !ok {
+		return TaskState{}, false
 	}
-	var outVals []interface{}
-	if err := pa.UnpackIntoInterface(&outVals, "balanceOf", out); err != nil {
-		return false, 0, err
+	from := taskPhase(st)
+	fn(&st)
+	tasks[key] = st
+	saveTask(st)
+	if to := taskPhase(st); to != from {
+		taskEvents.publish(from, st.clone(), to)
 	}
-	bal := outVals[0].(*big.Int)
+	return st.clone(), true
+}
 
-	var observed uint64
-	if block != nil {
-		observed = block.Uint64()
-	} else {
-		h, err := cli.HeaderByNumber(ctx, nil)
-		if err != nil {
-			return false, 0, err
-		}
-		observed = h.Number.Uint64()
+func removeTask(key store.TaskKey) {
+	tasksMu.Lock()
+	defer tasksMu.Unlock()
+	if st, ok := tasks[key]; ok {
+		taskEvents.publish(taskPhase(st), st.clone(), phaseRemoved)
+	}
+	delete(tasks, key)
+	if err := db.DeleteTask(key); err != nil {
+		slog.Error("Failed to delete task from store", "task", key, "err", err)
 	}
-	return bal.Cmp(big.NewInt(0)) > 0, observed, nil
 }
 
 func getNFTClient(ctx context.Context, chainID uint64) (*ethclient.Client, error) {
 	if c, ok := appClients[int64(chainID)]; ok {
 		return c, nil
 	}
-	if c, ok := nftClients[chainID]; ok {
+	nftClientsMu.Lock()
+	c, ok := nftClients[chainID]
+	nftClientsMu.Unlock()
+	if ok {
 		return c, nil
 	}
-	url, ok := nftRPCs[chainID]
-	if !ok || strings.TrimSpace(url) == "" {
-		return nil, fmt.Errorf("no RPC configured for NFT chainId=%d (set --nft-rpc-map)", chainID)
+	spec, ok := nftChains[chainID]
+	if !ok {
+		return nil, fmt.Errorf("no RPC configured for NFT chainId=%d (set --nft-rpc-map or nft_chains in --config)", chainID)
 	}
-	cli, err := ethclient.DialContext(ctx, url)
+	cli, rpcURL, actual, err := dialFirst(ctx, "nft", spec.RPCs, spec.RateLimit, spec.RateBurst)
 	if err != nil {
 		return nil, err
 	}
+	if actual.Uint64() != chainID {
+		cli.Close()
+		return nil, fmt.Errorf("NFT chain RPC '%s' reports chain ID %d, configured as %d", rpcURL, actual, chainID)
+	}
+	// Dialing doesn't hold the lock so a slow RPC doesn't block the other NFT chains;
+	// the client of a concurrent dial may have been stored meanwhile.
+	nftClientsMu.Lock()
+	defer nftClientsMu.Unlock()
+	if c, ok := nftClients[chainID]; ok {
+		cli.Close()
+		return c, nil
+	}
 	nftClients[chainID] = cli
 	return cli, nil
 }
 
-func parseRPCMap(s string) map[uint64]string {
-	m := make(map[uint64]string)
+// openSigner opens the transaction signer backend selected by --signer, or inferred from the
+// signer flags that are set.
+func openSigner() (signer.Signer, error) {
+	if cfg.signerType == "" {
+		switch {
+		case cfg.pkcs11Module != "":
+			cfg.signerType = "pkcs11"
+		case cfg.externalSigner != "":
+			cfg.signerType = "external"
+		case cfg.keystoreFile != "":
+			cfg.signerType = "keystore"
+		case cfg.privateKey != "":
+			cfg.signerType = "raw"
+		default:
+			return nil, errors.Errorf("no transaction signer configured, set --keystore, --external-signer, --pkcs11-module or --private-key")
+		}
+	}
+	switch cfg.signerType {
+	case "raw":
+		if cfg.privateKey == "" {
+			return nil, errors.Errorf("--signer raw requires --private-key")
+		}
+		slog.Warn("Using a raw private key from the command line, for development only")
+		return signer.NewRawKey(cfg.privateKey)
+	case "keystore":
+		if cfg.keystoreFile == "" {
+			return nil, errors.Errorf("--signer keystore requires --keystore")
+		}
+		return signer.NewKeystore(cfg.keystoreFile, cfg.keystorePassword)
+	case "external":
+		if cfg.externalSigner == "" {
+			return nil, errors.Errorf("--signer external requires --external-signer")
+		}
+		var addr common.Address
+		if cfg.signerAddress != "" {
+			if !common.IsHexAddress(cfg.signerAddress) {
+				return nil, errors.Errorf("invalid signer address %q", cfg.signerAddress)
+			}
+			addr = common.HexToAddress(cfg.signerAddress)
+		}
+		return signer.NewExternal(cfg.externalSigner, addr)
+	case "pkcs11":
+		if cfg.pkcs11Module == "" || cfg.pkcs11KeyLabel == "" {
+			return nil, errors.Errorf("--signer pkcs11 requires --pkcs11-module and --pkcs11-key-label")
+		}
+		return signer.NewPKCS11(signer.PKCS11Config{
+			Module:     cfg.pkcs11Module,
+			TokenLabel: cfg.pkcs11TokenLabel,
+			KeyLabel:   cfg.pkcs11KeyLabel,
+			PinFile:    cfg.pkcs11PinFile,
+		})
+	default:
+		return nil, errors.Errorf("unknown signer %q (supported: raw, keystore, external, pkcs11)", cfg.signerType)
+	}
+}
+
+// gweiToWei converts a gwei flag value to wei, 0 meaning unset.
+func gweiToWei(gwei uint64) *big.Int {
+	if gwei == 0 {
+		return nil
+	}
+	return new(big.Int).Mul(new(big.Int).SetUint64(gwei), big.NewInt(params.GWei))
+}
+
+// parseChainValues parses per app chain overrides of the form 'chainID=value,...'; what names the setting in errors.
+func parseChainValues(s, what string) (map[int64]uint64, error) {
+	m := make(map[int64]uint64)
 	if strings.TrimSpace(s) == "" {
-		return m
+		return m, nil
 	}
-	parts := strings.Split(s, ",")
-	for _, p := range parts {
+	for _, p := range strings.Split(s, ",") {
 		p = strings.TrimSpace(p)
 		if p == "" {
 			continue
 		}
 		kv := strings.SplitN(p, "=", 2)
 		if len(kv) != 2 {
-			continue
+			return nil, errors.Errorf("invalid %s entry %q", what, p)
 		}
-		cid := strings.TrimSpace(kv[0])
-		url := strings.TrimSpace(kv[1])
-		if cid == "" || url == "" {
-			continue
+		chainID, err := strconv.ParseInt(strings.TrimSpace(kv[0]), 10, 64)
+		if err != nil {
+			return nil, errors.Errorf("invalid chain ID in %s entry %q: %w", what, p, err)
 		}
-		bi, ok := new(big.Int).SetString(cid, 10)
-		if !ok {
-			continue
+		v, err := strconv.ParseUint(strings.TrimSpace(kv[1]), 10, 64)
+		if err != nil {
+			return nil, errors.Errorf("invalid value in %s entry %q: %w", what, p, err)
 		}
-		m[bi.Uint64()] = url
+		m[chainID] = v
 	}
-	return m
+	return m, nil
 }
 
 func signalContext(ctx context.Context) context.Context {
@@ -520,3 +1018,19 @@ func signalContext(ctx context.Context) context.Context {
 	}()
 	return cnCtx
 }
+
+func relayGRPCOptions() utils.GRPCOptions {
+	return utils.GRPCOptions{
+		TLS:              cfg.relayTLS,
+		CAFile:           cfg.relayCAFile,
+		ServerName:       cfg.relayServerName,
+		CertFile:         cfg.relayCertFile,
+		KeyFile:          cfg.relayKeyFile,
+		TokenFile:        cfg.relayTokenFile,
+		MaxRetries:       cfg.relayMaxRetries,
+		RetryBackoff:     cfg.relayRetryBackoff,
+		Backoff:          cfg.relayBackoff,
+		KeepaliveTime:    cfg.relayKeepalive,
+		KeepaliveTimeout: cfg.relayKeepaliveWait,
+	}
+}
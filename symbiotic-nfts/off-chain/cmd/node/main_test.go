@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"math/big"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	bolt "go.etcd.io/bbolt"
+
+	"sum/internal/store"
+	"sum/internal/txmgr"
+)
+
+// openTestDB points db at a fresh store in a temporary directory and returns its path.
+func openTestDB(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "node.db")
+	reopenTestDB(t, path)
+	t.Cleanup(func() { _ = db.Close() })
+	return path
+}
+
+// reopenTestDB closes db, if open, and opens the store at path again, as a restarted node would.
+func reopenTestDB(t *testing.T, path string) {
+	t.Helper()
+	if db != nil {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var err error
+	if db, err = store.Open(path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTaskStateRoundTrip(t *testing.T) {
+	t.Cleanup(func() { db = nil })
+	path := openTestDB(t)
+
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	nftAddr := common.HexToAddress("0x5FbDB2315678afecb367f032d93F642f64180aa3")
+	st := TaskState{
+		ChainID:        31337,
+		Contract:       nftAddr,
+		TaskID:         common.HexToHash("0x01"),
+		Type:           sumType,
+		Req:            json.RawMessage(`{"a":1,"b":2}`),
+		Payload:        []byte{1, 2, 3},
+		SigEpoch:       7,
+		SigRequestHash: "0xabc",
+		AggProof:       []byte{4, 5},
+		ProofAt:        at,
+		MinEpoch:       6,
+		Attempts: []SignAttempt{
+			{Epoch: 6, RequestHash: "0xdef", Relay: "relay-a:1", SignedAt: at, Retired: "aggregation timeout", RetiredAt: at},
+			{Epoch: 7, RequestHash: "0xabc", Relay: "relay-b:1", SignedAt: at},
+		},
+		Statuses: map[int64]uint8{31337: TaskResponded, 1: 1},
+		Submissions: map[int64]txmgr.Tx{
+			31337: {State: txmgr.StateConfirmed, Nonce: 3, To: nftAddr, Data: []byte{0xde, 0xad}, Gas: 21000, GasFeeCap: big.NewInt(2e9), GasTipCap: big.NewInt(1e9),
+				Hashes: []common.Hash{common.HexToHash("0xaa")}, SentAt: at, MinedBlock: 12, GasUsed: 20000, EffectiveGasPrice: big.NewInt(15e8)},
+		},
+		FailedSubmissions: map[int64][]txmgr.Tx{
+			1: {{State: txmgr.StateFailed, Nonce: 9, To: nftAddr, Data: []byte{0xbe, 0xef}, Gas: 30000, GasFeeCap: big.NewInt(3e9), GasTipCap: big.NewInt(1e9),
+				Hashes: []common.Hash{common.HexToHash("0xbb"), common.HexToHash("0xcc")}, SentAt: at, Replacements: 1, Error: "reverted"}},
+		},
+		Rejections:       map[int64]simRejection{1: {Count: 2, Error: "InvalidQuorumSignature()", At: at}},
+		CreatedBlock:     11,
+		CreatedBlockHash: common.HexToHash("0x0b"),
+		CreatedAt:        at,
+	}
+	saveTask(st)
+
+	reopenTestDB(t, path)
+	loaded, legacy, err := loadTasks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(legacy) != 0 {
+		t.Fatalf("loaded %d legacy tasks, want none", len(legacy))
+	}
+	if got := loaded[st.Key()]; len(loaded) != 1 || !reflect.DeepEqual(got, st) {
+		t.Fatalf("loaded tasks = %+v, want %+v", loaded, st)
+	}
+}
+
+func TestMigrateLegacyTasks(t *testing.T) {
+	nftAddr := common.HexToAddress("0x5FbDB2315678afecb367f032d93F642f64180aa3")
+	sumA := common.HexToAddress("0xe7f1725E7734CE288F8367e1Bb143E90bb3F0512")
+	sumB := common.HexToAddress("0x9fE46736679d2D9a65F0992F2272dE9f3c7fa6e0")
+	newTestHandler := func(typ string, address common.Address) TaskHandler {
+		h, err := newHandler(typ, address, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+	handlers = map[int64]map[common.Address]TaskHandler{
+		31337: {
+			nftAddr: newTestHandler(nftOwnershipType, nftAddr),
+			sumA:    newTestHandler(sumType, sumA),
+			sumB:    newTestHandler(sumType, sumB),
+		},
+	}
+	t.Cleanup(func() { handlers, tasks, db = nil, nil, nil })
+
+	path := filepath.Join(t.TempDir(), "node.db")
+	// Older versions stored tasks under their bare task ID and without a contract or type.
+	legacyTasks := map[common.Hash]TaskState{
+		common.HexToHash("0x01"): {ChainID: 31337, TaskID: common.HexToHash("0x01"), SigEpoch: 3, SigRequestHash: "0xabc"},
+		common.HexToHash("0x02"): {ChainID: 31337, TaskID: common.HexToHash("0x02"), Type: sumType},
+		common.HexToHash("0x03"): {ChainID: 5, TaskID: common.HexToHash("0x03")},
+	}
+	bdb, err := bolt.Open(path, 0o600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = bdb.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte("tasks"))
+		if err != nil {
+			return err
+		}
+		for taskID, st := range legacyTasks {
+			data, err := json.Marshal(st)
+			if err != nil {
+				return err
+			}
+			if err := b.Put(taskID.Bytes(), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopenTestDB(t, path)
+	t.Cleanup(func() { _ = db.Close() })
+	var legacy []TaskState
+	tasks, legacy, err = loadTasks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 0 || len(legacy) != 3 {
+		t.Fatalf("loaded %d tasks and %d legacy tasks, want 0 and 3", len(tasks), len(legacy))
+	}
+	migrateLegacyTasks(legacy)
+
+	// Only the NFT task can be assigned: chain 31337 binds two sum contracts and chain 5 none.
+	migrated := store.TaskKey{ChainID: 31337, Contract: nftAddr, TaskID: common.HexToHash("0x01")}
+	check := func(stage string) {
+		t.Helper()
+		st, ok := tasks[migrated]
+		if len(tasks) != 1 || !ok {
+			t.Fatalf("%s: tasks = %v, want only %s", stage, tasks, migrated)
+		}
+		if st.Type != nftOwnershipType || st.SigEpoch != 3 || st.SigRequestHash != "0xabc" {
+			t.Fatalf("%s: migrated task = %+v", stage, st)
+		}
+	}
+	check("after migration")
+
+	reopenTestDB(t, path)
+	tasks, legacy, err = loadTasks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("after restart")
+	var left []common.Hash
+	for _, st := range legacy {
+		left = append(left, st.TaskID)
+	}
+	if want := []common.Hash{common.HexToHash("0x02"), common.HexToHash("0x03")}; !slices.Equal(left, want) {
+		t.Fatalf("legacy tasks after restart = %v, want %v", left, want)
+	}
+}
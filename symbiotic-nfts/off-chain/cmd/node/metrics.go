@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/go-errors/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/status"
+
+	"sum/internal/txmgr"
+)
+
+const metricsNamespace = "sum_node"
+
+// balanceRefreshInterval throttles the responder wallet balance queries of a chain worker.
+const balanceRefreshInterval = 30 * time.Second
+
+// Lifecycle phases of a tracked task, as exported by sum_node_tasks and used by the admin API filters.
+const (
+	phaseUnsigned   = "unsigned"
+	phaseAwaitProof = "awaiting_proof"
+	phaseProven     = "proven"
+	phaseSubmitted  = "submitted"
+	phaseConfirmed  = "confirmed"
+	phaseFailed     = "failed"
+)
+
+var taskDurationBuckets = []float64{1, 2, 5, 10, 20, 30, 60, 120, 300, 600, 1200, 3600}
+
+var (
+	chainHeadBlock = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "chain_head_block",
+		Help:      "Block selected by --block-tag on the app chain.",
+	}, []string{"chain"})
+	chainScannedBlock = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "chain_scanned_block",
+		Help:      "Last app chain block whose task events were ingested.",
+	}, []string{"chain"})
+	chainScanLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "chain_scan_lag_blocks",
+		Help:      "Blocks between the app chain head and the last ingested block.",
+	}, []string{"chain"})
+	chainTickFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "chain_tick_failures_total",
+		Help:      "Failed chain worker ticks.",
+	}, []string{"chain"})
+	rpcRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "rpc_request_duration_seconds",
+		Help:      "Latency of JSON-RPC HTTP requests to app and NFT chains.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"kind", "chain"})
+	rpcRequestErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "rpc_request_errors_total",
+		Help:      "JSON-RPC HTTP requests to app and NFT chains that failed or got a non-2xx response.",
+	}, []string{"kind", "chain"})
+
+	taskSignDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "task_sign_seconds",
+		Help:      "Time from the TaskCreated block to the first signature request of the task.",
+		Buckets:   taskDurationBuckets,
+	}, []string{"chain", "type"})
+	taskProofDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "task_proof_seconds",
+		Help:      "Time from the TaskCreated block to the aggregation proof of the task.",
+		Buckets:   taskDurationBuckets,
+	}, []string{"chain", "type"})
+	taskResponseDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "task_response_seconds",
+		Help:      "Time from the TaskCreated block to the confirmed response tx, by target chain.",
+		Buckets:   taskDurationBuckets,
+	}, []string{"chain", "type"})
+	ownershipChecks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "ownership_checks_total",
+		Help:      "verifyOwnership outcomes by reason, error if the request couldn't be evaluated.",
+	}, []string{"reason"})
+
+	relayErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "relay_errors_total",
+		Help:      "Failed relay calls by method, relay endpoint and gRPC code. GetAggregationProof fails with NotFound until the proof is aggregated.",
+	}, []string{"method", "endpoint", "code"})
+	relayUp = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "relay_endpoint_up",
+		Help:      "Whether the relay endpoint answered its last call or health probe.",
+	}, []string{"endpoint"})
+
+	walletBalance = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "wallet_balance_eth",
+		Help:      "Native balance of the responder account on the app chain.",
+	}, []string{"chain"})
+	responseGasSpent = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "response_gas_spent_eth_total",
+		Help:      "Fees paid by mined response txs, successful or reverted.",
+	}, []string{"chain"})
+	responseTxs = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "response_txs_total",
+		Help:      "Response txs by final state.",
+	}, []string{"chain", "state"})
+)
+
+func init() {
+	prometheus.MustRegister(taskCollector{
+		desc: prometheus.NewDesc(metricsNamespace+"_tasks", "Tracked tasks by origin chain and lifecycle phase.", []string{"chain", "phase"}, nil),
+	})
+}
+
+// taskCollector counts the tracked tasks at scrape time.
+type taskCollector struct {
+	desc *prometheus.Desc
+}
+
+func (c taskCollector) Describe(ch chan<- *prometheus.Desc) { ch <- c.desc }
+
+func (c taskCollector) Collect(ch chan<- prometheus.Metric) {
+	type bucket struct {
+		chain int64
+		phase string
+	}
+	counts := make(map[bucket]int)
+	for _, st := range taskSnapshot() {
+		counts[bucket{st.ChainID, taskPhase(st)}]++
+	}
+	for b, n := range counts {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(n), chainLabel(b.chain), b.phase)
+	}
+}
+
+// taskPhase is how far the task got: its submissions decide once there are any, otherwise the signature and proof.
+// A task whose response failed is failed until it's resubmitted.
+func taskPhase(st TaskState) string {
+	if len(st.Submissions) == 0 && len(st.FailedSubmissions) != 0 {
+		return phaseFailed
+	}
+	if len(st.Submissions) != 0 {
+		phase := phaseConfirmed
+		for _, sub := range st.Submissions {
+			switch sub.State {
+			case txmgr.StateSubmitted:
+				return phaseSubmitted
+			case txmgr.StateFailed:
+				phase = phaseFailed
+			}
+		}
+		return phase
+	}
+	switch {
+	case st.SigRequestHash == "":
+		return phaseUnsigned
+	case st.AggProof == nil:
+		return phaseAwaitProof
+	default:
+		return phaseProven
+	}
+}
+
+func chainLabel(chainID int64) string {
+	return strconv.FormatInt(chainID, 10)
+}
+
+func weiToEth(wei *big.Int) float64 {
+	f, _ := new(big.Float).Quo(new(big.Float).SetInt(wei), big.NewFloat(params.Ether)).Float64()
+	return f
+}
+
+// observeSince records the time since the task's creation block, if known.
+func observeSince(h *prometheus.HistogramVec, st TaskState, chainID int64) {
+	if st.CreatedAt.IsZero() {
+		return
+	}
+	h.WithLabelValues(chainLabel(chainID), st.Type).Observe(time.Since(st.CreatedAt).Seconds())
+}
+
+func recordRelayError(method, endpoint string, err error) {
+	relayErrors.WithLabelValues(method, endpoint, status.Code(err).String()).Inc()
+}
+
+// recordScanProgress updates the scan gauges after the chain was ingested up to scanned.
+func recordScanProgress(chainID int64, head, scanned uint64) {
+	label := chainLabel(chainID)
+	chainHeadBlock.WithLabelValues(label).Set(float64(head))
+	chainScannedBlock.WithLabelValues(label).Set(float64(scanned))
+	chainScanLag.WithLabelValues(label).Set(float64(head - min(head, scanned)))
+}
+
+// recordResponseTx accounts a response tx that reached a final state.
+func recordResponseTx(chainID int64, tx txmgr.Tx) {
+	label := chainLabel(chainID)
+	responseTxs.WithLabelValues(label, string(tx.State)).Inc()
+	if tx.GasUsed != 0 && tx.EffectiveGasPrice != nil {
+		fee := new(big.Int).Mul(new(big.Int).SetUint64(tx.GasUsed), tx.EffectiveGasPrice)
+		responseGasSpent.WithLabelValues(label).Add(weiToEth(fee))
+	}
+}
+
+// refreshBalance updates the responder balance gauge of the worker's chain at most every balanceRefreshInterval.
+func (w *chainWorker) refreshBalance(ctx context.Context) {
+	if time.Since(w.balanceAt) < balanceRefreshInterval {
+		return
+	}
+	w.balanceAt = time.Now()
+	bal, err := w.cli.BalanceAt(ctx, txManagers[w.chainID].From(), nil)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to get responder balance", "chainID", w.chainID, "err", err)
+		return
+	}
+	walletBalance.WithLabelValues(chainLabel(w.chainID)).Set(weiToEth(bal))
+}
+
+// instrumentedTransport records latency and failures of the RPC requests of a chain.
+// The chain label is set once the chain ID is known.
+type instrumentedTransport struct {
+	kind  string
+	chain atomic.Value
+	next  http.RoundTripper
+}
+
+func newInstrumentedTransport(kind string, next http.RoundTripper) *instrumentedTransport {
+	t := &instrumentedTransport{kind: kind, next: next}
+	t.chain.Store("unknown")
+	return t
+}
+
+func (t *instrumentedTransport) setChain(chainID *big.Int) {
+	t.chain.Store(chainID.String())
+}
+
+// RoundTrip also traces the request if it is made for a traced task step.
+func (t *instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	chain := t.chain.Load().(string)
+	var span trace.Span
+	if trace.SpanContextFromContext(req.Context()).IsValid() {
+		_, span = tracer.Start(req.Context(), "rpc."+t.kind, trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(attribute.String("rpc.chain_id", chain), attribute.String("server.address", req.URL.Host)))
+	}
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	rpcRequestDuration.WithLabelValues(t.kind, chain).Observe(time.Since(start).Seconds())
+	failure := err
+	if err == nil && resp.StatusCode >= 300 {
+		failure = errors.Errorf("HTTP status %s", resp.Status)
+	}
+	if failure != nil {
+		rpcRequestErrors.WithLabelValues(t.kind, chain).Inc()
+	}
+	if span != nil {
+		endSpan(span, failure)
+	}
+	return resp, err
+}
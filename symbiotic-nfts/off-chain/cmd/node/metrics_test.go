@@ -0,0 +1,85 @@
+package main
+
+import (
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/go-errors/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"sum/internal/txmgr"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// scrapeMetrics returns the text exposition served by GET /metrics.
+func scrapeMetrics(t *testing.T) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	httpMux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /metrics = %d: %s", rec.Code, rec.Body)
+	}
+	return rec.Body.String()
+}
+
+// The metrics are process wide, so the series below use a chain and relay endpoint no other test does.
+// Counters and histograms keep counting across test runs, only their label sets are checked.
+func TestMetricLabels(t *testing.T) {
+	setupAdminTest(t)
+	const chainID = 7
+
+	recordScanProgress(chainID, 120, 100)
+	recordRelayError("GetAggregationProof", "metrics-relay", status.Error(codes.NotFound, "not aggregated yet"))
+	recordRelayError("SignMessage", "metrics-relay", errors.New("connection reset"))
+	(&relayEndpoint{url: "metrics-relay", healthy: true}).setHealth(errors.New("connection reset"))
+	recordResponseTx(chainID, txmgr.Tx{State: txmgr.StateConfirmed, GasUsed: 21000, EffectiveGasPrice: big.NewInt(params.GWei)})
+	recordResponseTx(chainID, txmgr.Tx{State: txmgr.StateFailed})
+	observeSince(taskSignDuration, TaskState{Type: sumType, CreatedAt: time.Now().Add(-3 * time.Second)}, chainID)
+	// Tasks of unknown age are not observed.
+	observeSince(taskProofDuration, TaskState{Type: sumType}, chainID)
+
+	tr := newInstrumentedTransport("nft", roundTripFunc(func(*http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusTooManyRequests, Status: "429 Too Many Requests", Body: http.NoBody}, nil
+	}))
+	tr.setChain(big.NewInt(chainID))
+	if _, err := tr.RoundTrip(httptest.NewRequest(http.MethodPost, "http://rpc.test", nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	body := scrapeMetrics(t)
+	lines := strings.Split(body, "\n")
+	for _, series := range []string{
+		`sum_node_tasks{chain="1",phase="awaiting_proof"} 1`,
+		`sum_node_tasks{chain="1",phase="unsigned"} 1`,
+		`sum_node_tasks{chain="2",phase="confirmed"} 1`,
+		`sum_node_chain_head_block{chain="7"} 120`,
+		`sum_node_chain_scanned_block{chain="7"} 100`,
+		`sum_node_chain_scan_lag_blocks{chain="7"} 20`,
+		`sum_node_relay_errors_total{code="NotFound",endpoint="metrics-relay",method="GetAggregationProof"}`,
+		`sum_node_relay_errors_total{code="Unknown",endpoint="metrics-relay",method="SignMessage"}`,
+		`sum_node_relay_endpoint_up{endpoint="metrics-relay"} 0`,
+		`sum_node_response_txs_total{chain="7",state="confirmed"}`,
+		`sum_node_response_txs_total{chain="7",state="failed"}`,
+		`sum_node_response_gas_spent_eth_total{chain="7"}`,
+		`sum_node_task_sign_seconds_count{chain="7",type="sum"}`,
+		`sum_node_rpc_request_duration_seconds_count{chain="7",kind="nft"}`,
+		`sum_node_rpc_request_errors_total{chain="7",kind="nft"}`,
+	} {
+		if !slices.ContainsFunc(lines, func(l string) bool { return l == series || strings.HasPrefix(l, series+" ") }) {
+			t.Errorf("/metrics lacks %s", series)
+		}
+	}
+	if strings.Contains(body, `sum_node_task_proof_seconds_count{chain="7"`) {
+		t.Error("/metrics has a proof duration of a task of unknown age")
+	}
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/go-errors/errors"
+
+	"sum/internal/contracts"
+)
+
+// Ownership verification outcomes, mirroring NftOwnershipTask.Reason
+const (
+	ReasonOwner            = uint8(0)
+	ReasonNotOwner         = uint8(1)
+	ReasonNonexistentToken = uint8(2)
+	ReasonNotAContract     = uint8(3)
+	ReasonStateUnavailable = uint8(4)
+	ReasonInvalidResponse  = uint8(5)
+)
+
+// payloadVersion is the layout version of the signed response payload, see NftOwnershipTask.PAYLOAD_VERSION.
+const payloadVersion = uint8(2)
+
+var errAbstain = errors.New("ownership could not be verified, abstaining")
+
+type OwnershipResult struct {
+	Reason        uint8
+	OwnerAtBlock  common.Address
+	ObservedBlock uint64
+	// ObservedAmount is the balance seen at ObservedBlock: the ERC20 amount or token count held by the owner,
+	// or 1/0 for a single ERC721 token.
+	ObservedAmount *big.Int
+	// Decimals of the ERC20 token, 0 for NFTs.
+	Decimals uint8
+}
+
+func (r OwnershipResult) IsOwner() bool {
+	return r.Reason == ReasonOwner
+}
+
+// Verified reports whether the result is definitive and may be attested.
+// STATE_UNAVAILABLE means the node could not tell, so it must not sign anything.
+func (r OwnershipResult) Verified() bool {
+	return r.Reason != ReasonStateUnavailable
+}
+
+func reasonName(reason uint8) string {
+	switch reason {
+	case ReasonOwner:
+		return "OWNER"
+	case ReasonNotOwner:
+		return "NOT_OWNER"
+	case ReasonNonexistentToken:
+		return "NONEXISTENT_TOKEN"
+	case ReasonNotAContract:
+		return "NOT_A_CONTRACT"
+	case ReasonStateUnavailable:
+		return "STATE_UNAVAILABLE"
+	case ReasonInvalidResponse:
+		return "INVALID_RESPONSE"
+	default:
+		return fmt.Sprintf("UNKNOWN(%d)", reason)
+	}
+}
+
+// encodeOwnershipPayload packs the versioned payload abi.encode(uint8 version, uint8 reason, bool isOwner,
+// address ownerAtBlock, uint64 observedBlock, uint256 observedAmount, uint8 decimals).
+func encodeOwnershipPayload(r OwnershipResult) ([]byte, error) {
+	u8T, _ := abi.NewType("uint8", "", nil)
+	boolT, _ := abi.NewType("bool", "", nil)
+	addrT, _ := abi.NewType("address", "", nil)
+	u64T, _ := abi.NewType("uint64", "", nil)
+	u256T, _ := abi.NewType("uint256", "", nil)
+	payloadArgs := abi.Arguments{{Type: u8T}, {Type: u8T}, {Type: boolT}, {Type: addrT}, {Type: u64T}, {Type: u256T}, {Type: u8T}}
+	amount := r.ObservedAmount
+	if amount == nil {
+		amount = new(big.Int)
+	}
+	return payloadArgs.Pack(payloadVersion, r.Reason, r.IsOwner(), r.OwnerAtBlock, r.ObservedBlock, amount, r.Decimals)
+}
+
+// verifyOwnership checks the request against the NFT chain. RPC failures and missing state are reported
+// as ReasonStateUnavailable rather than "not owner"; the returned error is reserved for requests the node
+// can't handle at all.
+func verifyOwnership(ctx context.Context, req contracts.NftOwnershipTaskRequest) (OwnershipResult, error) {
+	targetChainID := req.ChainId.Uint64()
+
+	cli, err := getNFTClient(ctx, targetChainID)
+	if err != nil {
+		return OwnershipResult{}, err
+	}
+
+	observed, err := resolveCheckedBlock(ctx, cli, req)
+	if err != nil {
+		return OwnershipResult{Reason: ReasonStateUnavailable}, nil
+	}
+	res := OwnershipResult{ObservedBlock: observed, ObservedAmount: new(big.Int)}
+	blockNum := new(big.Int).SetUint64(observed)
+
+	code, err := cli.CodeAt(ctx, req.Collection, blockNum)
+	if err != nil {
+		res.Reason = ReasonStateUnavailable
+		return res, nil
+	}
+	if len(code) == 0 {
+		res.Reason = ReasonNotAContract
+		return res, nil
+	}
+
+	switch req.Standard {
+	case StdERC721:
+		owner, err := erc721OwnerOf(ctx, cli, req.Collection, req.TokenId, blockNum)
+		if err != nil {
+			res.Reason = classifyCallError(err, ReasonNonexistentToken)
+			return res, nil
+		}
+		res.OwnerAtBlock = owner
+		if strings.EqualFold(owner.Hex(), req.Owner.Hex()) {
+			res.Reason = ReasonOwner
+			res.ObservedAmount.SetUint64(1)
+		} else {
+			res.Reason = ReasonNotOwner
+		}
+		return res, nil
+
+	case StdERC1155:
+		bal, err := erc1155BalanceOf(ctx, cli, req.Collection, req.Owner, req.TokenId, blockNum)
+		if err != nil {
+			res.Reason = classifyCallError(err, ReasonInvalidResponse)
+			return res, nil
+		}
+		res.OwnerAtBlock = req.Owner
+		res.ObservedAmount = bal
+		if bal.Cmp(minAmountThreshold(req.MinAmount)) >= 0 {
+			res.Reason = ReasonOwner
+		} else {
+			res.Reason = ReasonNotOwner
+		}
+		return res, nil
+
+	case StdERC721Collection:
+		bal, err := erc721BalanceOf(ctx, cli, req.Collection, req.Owner, blockNum)
+		if err != nil {
+			res.Reason = classifyCallError(err, ReasonInvalidResponse)
+			return res, nil
+		}
+		res.OwnerAtBlock = req.Owner
+		res.ObservedAmount = bal
+		if bal.Cmp(minAmountThreshold(req.MinAmount)) >= 0 {
+			res.Reason = ReasonOwner
+		} else {
+			res.Reason = ReasonNotOwner
+		}
+		return res, nil
+
+	case StdERC20:
+		bal, err := erc20BalanceOf(ctx, cli, req.Collection, req.Owner, blockNum)
+		if err != nil {
+			res.Reason = classifyCallError(err, ReasonInvalidResponse)
+			return res, nil
+		}
+		decimals, err := erc20Decimals(ctx, cli, req.Collection, blockNum)
+		if err != nil {
+			// decimals() is optional in ERC20, only a transport failure makes the answer unavailable.
+			if classifyCallError(err, ReasonInvalidResponse) == ReasonStateUnavailable {
+				res.Reason = ReasonStateUnavailable
+				return res, nil
+			}
+			decimals = 0
+		}
+		res.OwnerAtBlock = req.Owner
+		res.ObservedAmount = bal
+		res.Decimals = decimals
+		if bal.Cmp(minAmountThreshold(req.MinAmount)) >= 0 {
+			res.Reason = ReasonOwner
+		} else {
+			res.Reason = ReasonNotOwner
+		}
+		return res, nil
+
+	default:
+		return OwnershipResult{}, fmt.Errorf("unknown standard %d", req.Standard)
+	}
+}
+
+// errMalformedOutput marks a call that succeeded but returned data that doesn't match the expected ABI.
+var errMalformedOutput = errors.New("malformed call output")
+
+// classifyCallError maps a failed contract call to a reason. A revert is a definitive answer from the
+// contract at the checked block and is reported as onRevert, malformed output as INVALID_RESPONSE.
+// Anything else (transport errors, pruned state, missing headers) means the state was unavailable.
+func classifyCallError(err error, onRevert uint8) uint8 {
+	if errors.Is(err, errMalformedOutput) {
+		return ReasonInvalidResponse
+	}
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == 3 {
+		return onRevert
+	}
+	if strings.Contains(err.Error(), "execution reverted") {
+		return onRevert
+	}
+	return ReasonStateUnavailable
+}
+
+// minAmountThreshold is the balance a request requires. A zero minAmount means any positive balance.
+func minAmountThreshold(minAmount *big.Int) *big.Int {
+	if minAmount == nil || minAmount.Sign() == 0 {
+		return big.NewInt(1)
+	}
+	return minAmount
+}
+
+// callView calls a view method described by abiJSON on the contract at the given block and unpacks its outputs.
+func callView(ctx context.Context, cli *ethclient.Client, abiJSON string, contract common.Address, block *big.Int, method string, args ...interface{}) ([]interface{}, error) {
+	pa, err := abi.JSON(strings.NewReader(abiJSON))
+	if err != nil {
+		return nil, err
+	}
+	data, err := pa.Pack(method, args...)
+	if err != nil {
+		return nil, err
+	}
+	out, err := cli.CallContract(ctx, ethereum.CallMsg{To: &contract, Data: data}, block)
+	if err != nil {
+		return nil, err
+	}
+	outVals, err := pa.Unpack(method, out)
+	if err != nil {
+		return nil, errors.Errorf("%w: %w", errMalformedOutput, err)
+	}
+	return outVals, nil
+}
+
+func erc721OwnerOf(ctx context.Context, cli *ethclient.Client, collection common.Address, tokenId *big.Int, block *big.Int) (common.Address, error) {
+	const abiJSON = `[{"name":"ownerOf","type":"function","stateMutability":"view","inputs":[{"name":"tokenId","type":"uint256"}],"outputs":[{"name":"owner","type":"address"}]}]`
+	outVals, err := callView(ctx, cli, abiJSON, collection, block, "ownerOf", tokenId)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return outVals[0].(common.Address), nil
+}
+
+func erc721BalanceOf(ctx context.Context, cli *ethclient.Client, collection, owner common.Address, block *big.Int) (*big.Int, error) {
+	const abiJSON = `[{"name":"balanceOf","type":"function","stateMutability":"view","inputs":[{"name":"owner","type":"address"}],"outputs":[{"name":"","type":"uint256"}]}]`
+	outVals, err := callView(ctx, cli, abiJSON, collection, block, "balanceOf", owner)
+	if err != nil {
+		return nil, err
+	}
+	return outVals[0].(*big.Int), nil
+}
+
+func erc1155BalanceOf(ctx context.Context, cli *ethclient.Client, collection, owner common.Address, tokenId *big.Int, block *big.Int) (*big.Int, error) {
+	const abiJSON = `[{"name":"balanceOf","type":"function","stateMutability":"view","inputs":[{"name":"account","type":"address"},{"name":"id","type":"uint256"}],"outputs":[{"name":"","type":"uint256"}]}]`
+	outVals, err := callView(ctx, cli, abiJSON, collection, block, "balanceOf", owner, tokenId)
+	if err != nil {
+		return nil, err
+	}
+	return outVals[0].(*big.Int), nil
+}
+
+func erc20BalanceOf(ctx context.Context, cli *ethclient.Client, token, owner common.Address, block *big.Int) (*big.Int, error) {
+	const abiJSON = `[{"name":"balanceOf","type":"function","stateMutability":"view","inputs":[{"name":"account","type":"address"}],"outputs":[{"name":"","type":"uint256"}]}]`
+	outVals, err := callView(ctx, cli, abiJSON, token, block, "balanceOf", owner)
+	if err != nil {
+		return nil, err
+	}
+	return outVals[0].(*big.Int), nil
+}
+
+func erc20Decimals(ctx context.Context, cli *ethclient.Client, token common.Address, block *big.Int) (uint8, error) {
+	const abiJSON = `[{"name":"decimals","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint8"}]}]`
+	outVals, err := callView(ctx, cli, abiJSON, token, block, "decimals")
+	if err != nil {
+		return 0, err
+	}
+	return outVals[0].(uint8), nil
+}
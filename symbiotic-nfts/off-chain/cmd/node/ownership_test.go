@@ -0,0 +1,365 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/go-errors/errors"
+
+	"sum/internal/contracts"
+)
+
+var ownershipTestCollection = common.HexToAddress("0xe7f1725E7734CE288F8367e1Bb143E90bb3F0512")
+
+// setupOwnershipTest serves NFT chain 1 from a fake chain with no contracts deployed.
+func setupOwnershipTest(t *testing.T) *fakeChain {
+	t.Helper()
+	chain, cli := newFakeChain(t, 20)
+	nftClients = map[uint64]*ethclient.Client{1: cli}
+	t.Cleanup(func() { nftClients = nil })
+	return chain
+}
+
+// ownershipRequest asks whether opA holds token 7 of the test collection at block 10.
+func ownershipRequest(standard uint8, minAmount int64) contracts.NftOwnershipTaskRequest {
+	return contracts.NftOwnershipTaskRequest{
+		ChainId:      big.NewInt(1),
+		Collection:   ownershipTestCollection,
+		TokenId:      big.NewInt(7),
+		Owner:        opA,
+		CheckedBlock: 10,
+		Standard:     standard,
+		MinAmount:    big.NewInt(minAmount),
+		Nonce:        big.NewInt(1),
+		CreatedAt:    big.NewInt(0),
+	}
+}
+
+// packOutput ABI-encodes a single return value of the given type.
+func packOutput(t *testing.T, typ string, v any) []byte {
+	t.Helper()
+	ty, err := abi.NewType(typ, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := abi.Arguments{{Type: ty}}.Pack(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestClassifyCallError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want uint8
+	}{
+		{"revert with code 3", revertError{}, ReasonNonexistentToken},
+		{"wrapped revert", errors.Errorf("call failed: %w", revertError{data: []byte{1, 2, 3, 4}}), ReasonNonexistentToken},
+		{"revert message only", errors.New("execution reverted: ERC721: invalid token ID"), ReasonNonexistentToken},
+		{"malformed output", errors.Errorf("%w: %w", errMalformedOutput, errors.New("abi: cannot marshal")), ReasonInvalidResponse},
+		{"transport error", errors.New("dial tcp 127.0.0.1:8545: connection refused"), ReasonStateUnavailable},
+		{"missing state", errors.New("missing trie node"), ReasonStateUnavailable},
+		{"deadline", context.DeadlineExceeded, ReasonStateUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyCallError(tt.err, ReasonNonexistentToken); got != tt.want {
+				t.Fatalf("classifyCallError() = %s, want %s", reasonName(got), reasonName(tt.want))
+			}
+		})
+	}
+}
+
+func TestVerifyOwnershipReasons(t *testing.T) {
+	standards := []struct {
+		name     string
+		standard uint8
+		method   string
+		owned    func(t *testing.T) []byte
+		onRevert uint8
+	}{
+		{"ERC721", StdERC721, "ownerOf(uint256)", func(t *testing.T) []byte { return packOutput(t, "address", opA) }, ReasonNonexistentToken},
+		{"ERC1155", StdERC1155, "balanceOf(address,uint256)", func(t *testing.T) []byte { return packOutput(t, "uint256", big.NewInt(1)) }, ReasonInvalidResponse},
+		{"ERC721 collection", StdERC721Collection, "balanceOf(address)", func(t *testing.T) []byte { return packOutput(t, "uint256", big.NewInt(1)) }, ReasonInvalidResponse},
+		{"ERC20", StdERC20, "balanceOf(address)", func(t *testing.T) []byte { return packOutput(t, "uint256", big.NewInt(1)) }, ReasonInvalidResponse},
+	}
+	for _, std := range standards {
+		tests := []struct {
+			name  string
+			setup func(c *fakeChain)
+			want  uint8
+		}{
+			{"owner", func(c *fakeChain) { c.deploy(ownershipTestCollection, std.method, std.owned(t), nil) }, ReasonOwner},
+			{"revert", func(c *fakeChain) { c.deploy(ownershipTestCollection, std.method, nil, revertError{}) }, std.onRevert},
+			{"missing method", func(c *fakeChain) { c.deploy(ownershipTestCollection, "", nil, nil) }, std.onRevert},
+			{"malformed output", func(c *fakeChain) { c.deploy(ownershipTestCollection, std.method, []byte{1, 2, 3}, nil) }, ReasonInvalidResponse},
+			{"call unavailable", func(c *fakeChain) {
+				c.deploy(ownershipTestCollection, std.method, std.owned(t), nil)
+				c.callErr = errors.New("header not found")
+			}, ReasonStateUnavailable},
+			{"code unavailable", func(c *fakeChain) {
+				c.deploy(ownershipTestCollection, std.method, std.owned(t), nil)
+				c.codeErr = errors.New("connection reset by peer")
+			}, ReasonStateUnavailable},
+			{"not a contract", func(*fakeChain) {}, ReasonNotAContract},
+		}
+		for _, tt := range tests {
+			t.Run(std.name+"/"+tt.name, func(t *testing.T) {
+				chain := setupOwnershipTest(t)
+				tt.setup(chain)
+				res, err := verifyOwnership(context.Background(), ownershipRequest(std.standard, 0))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if res.Reason != tt.want {
+					t.Fatalf("reason = %s, want %s", reasonName(res.Reason), reasonName(tt.want))
+				}
+				if res.Reason != ReasonStateUnavailable && res.ObservedBlock != 10 {
+					t.Errorf("observed block = %d, want 10", res.ObservedBlock)
+				}
+			})
+		}
+	}
+}
+
+func TestVerifyOwnershipNotOwner(t *testing.T) {
+	chain := setupOwnershipTest(t)
+	chain.deploy(ownershipTestCollection, "ownerOf(uint256)", packOutput(t, "address", opB), nil)
+	res, err := verifyOwnership(context.Background(), ownershipRequest(StdERC721, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Reason != ReasonNotOwner || res.OwnerAtBlock != opB {
+		t.Fatalf("result = %s, owner %s, want NOT_OWNER, owner %s", reasonName(res.Reason), res.OwnerAtBlock, opB)
+	}
+}
+
+// The node signs every definitive answer, including a negative one, and abstains when it can't tell.
+func TestComputePayloadAbstains(t *testing.T) {
+	tests := []struct {
+		name        string
+		setup       func(c *fakeChain)
+		wantAbstain bool
+	}{
+		{"owner", func(c *fakeChain) {
+			c.deploy(ownershipTestCollection, "ownerOf(uint256)", packOutput(t, "address", opA), nil)
+		}, false},
+		{"nonexistent token", func(c *fakeChain) { c.deploy(ownershipTestCollection, "ownerOf(uint256)", nil, revertError{}) }, false},
+		{"not a contract", func(*fakeChain) {}, false},
+		{"state unavailable", func(c *fakeChain) {
+			c.deploy(ownershipTestCollection, "ownerOf(uint256)", packOutput(t, "address", opA), nil)
+			c.callErr = errors.New("header not found")
+		}, true},
+	}
+	h, err := newHandler(nftOwnershipType, common.HexToAddress("0x5FbDB2315678afecb367f032d93F642f64180aa3"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := json.Marshal(ownershipRequest(StdERC721, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup(setupOwnershipTest(t))
+			payload, err := h.ComputePayload(context.Background(), common.HexToHash("0x01"), req)
+			if tt.wantAbstain {
+				if !errors.Is(err, errAbstain) {
+					t.Fatalf("ComputePayload() = %x, %v, want errAbstain", payload, err)
+				}
+				return
+			}
+			if err != nil || len(payload) == 0 {
+				t.Fatalf("ComputePayload() = %x, %v, want a payload", payload, err)
+			}
+		})
+	}
+}
+
+// payloadVersionRe finds the payload version the contract accepts in its source.
+var payloadVersionRe = regexp.MustCompile(`uint8 public constant PAYLOAD_VERSION = (\d+);`)
+
+// The payload must decode as NftOwnershipTask.respondTask does:
+// abi.decode(payload, (uint8, Reason, bool, address, uint64, uint256, uint8)), version first.
+func TestEncodeOwnershipPayload(t *testing.T) {
+	src, err := os.ReadFile(filepath.Join("..", "..", "..", "src", "NftOwnershipTask.sol"))
+	switch {
+	case os.IsNotExist(err):
+		t.Log("contract source not found, not checking PAYLOAD_VERSION")
+	case err != nil:
+		t.Fatal(err)
+	default:
+		m := payloadVersionRe.FindSubmatch(src)
+		if m == nil {
+			t.Fatal("PAYLOAD_VERSION not found in NftOwnershipTask.sol")
+		}
+		if got := strconv.Itoa(int(payloadVersion)); got != string(m[1]) {
+			t.Fatalf("payloadVersion = %s, contract PAYLOAD_VERSION = %s", got, m[1])
+		}
+	}
+
+	// The types after the version are those of the stored Response, taken from the contract ABI.
+	nftABI, err := contracts.NftOwnershipTaskMetaData.GetAbi()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := map[string]abi.Type{}
+	for _, out := range nftABI.Methods["responses"].Outputs {
+		fields[out.Name] = out.Type
+	}
+	u8T, _ := abi.NewType("uint8", "", nil)
+	layout := abi.Arguments{{Name: "version", Type: u8T}}
+	for _, name := range []string{"reason", "isOwner", "ownerAtBlock", "observedBlock", "observedAmount", "decimals"} {
+		typ, ok := fields[name]
+		if !ok {
+			t.Fatalf("Response has no field %s", name)
+		}
+		layout = append(layout, abi.Argument{Name: name, Type: typ})
+	}
+
+	amount, _ := new(big.Int).SetString("1606938044258990275541962092341162602522202993782792835301376", 10) // 2^200
+	tests := []struct {
+		name string
+		res  OwnershipResult
+	}{
+		{"ERC721 owner", OwnershipResult{Reason: ReasonOwner, OwnerAtBlock: opA, ObservedBlock: 10, ObservedAmount: big.NewInt(1)}},
+		{"not owner", OwnershipResult{Reason: ReasonNotOwner, OwnerAtBlock: opB, ObservedBlock: 1 << 40, ObservedAmount: big.NewInt(0)}},
+		{"ERC20 balance", OwnershipResult{Reason: ReasonOwner, OwnerAtBlock: opA, ObservedBlock: 12, ObservedAmount: amount, Decimals: 18}},
+		{"no observed amount", OwnershipResult{Reason: ReasonNotAContract, ObservedBlock: 3}},
+		{"invalid response", OwnershipResult{Reason: ReasonInvalidResponse, ObservedBlock: 3, ObservedAmount: big.NewInt(0)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, err := encodeOwnershipPayload(tt.res)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(payload) != 7*32 {
+				t.Fatalf("payload is %d bytes, want 7 words", len(payload))
+			}
+			vals, err := layout.Unpack(payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantAmount := tt.res.ObservedAmount
+			if wantAmount == nil {
+				wantAmount = new(big.Int)
+			}
+			if vals[0].(uint8) != 2 {
+				t.Errorf("version = %d, want 2", vals[0])
+			}
+			if vals[1].(uint8) != tt.res.Reason || vals[2].(bool) != tt.res.IsOwner() || vals[3].(common.Address) != tt.res.OwnerAtBlock ||
+				vals[4].(uint64) != tt.res.ObservedBlock || vals[5].(*big.Int).Cmp(wantAmount) != 0 || vals[6].(uint8) != tt.res.Decimals {
+				t.Errorf("decoded %v from the payload of %+v", vals, tt.res)
+			}
+		})
+	}
+}
+
+// decimals() is optional in ERC20: a token without it is attested with 0 decimals.
+func TestERC20DecimalsFallback(t *testing.T) {
+	tests := []struct {
+		name         string
+		decimals     []byte
+		decimalsErr  error
+		noDecimals   bool
+		wantReason   uint8
+		wantDecimals uint8
+	}{
+		{name: "decimals", decimals: packOutput(t, "uint8", uint8(6)), wantReason: ReasonOwner, wantDecimals: 6},
+		{name: "no decimals method", noDecimals: true, wantReason: ReasonOwner},
+		{name: "decimals reverts", decimalsErr: revertError{}, wantReason: ReasonOwner},
+		{name: "malformed decimals", decimals: []byte{6}, wantReason: ReasonOwner},
+		{name: "decimals unavailable", decimalsErr: errors.New("header not found"), wantReason: ReasonStateUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain := setupOwnershipTest(t)
+			chain.deploy(ownershipTestCollection, "balanceOf(address)", packOutput(t, "uint256", big.NewInt(5e6)), nil)
+			if !tt.noDecimals {
+				chain.deploy(ownershipTestCollection, "decimals()", tt.decimals, tt.decimalsErr)
+			}
+			res, err := verifyOwnership(context.Background(), ownershipRequest(StdERC20, 1e6))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Reason != tt.wantReason || res.Decimals != tt.wantDecimals {
+				t.Fatalf("result = %s with %d decimals, want %s with %d", reasonName(res.Reason), res.Decimals, reasonName(tt.wantReason), tt.wantDecimals)
+			}
+			if res.Reason == ReasonOwner && res.ObservedAmount.Cmp(big.NewInt(5e6)) != 0 {
+				t.Errorf("observed amount = %s, want 5000000", res.ObservedAmount)
+			}
+		})
+	}
+}
+
+func TestMinAmountThreshold(t *testing.T) {
+	tests := []struct {
+		minAmount *big.Int
+		want      int64
+	}{
+		{nil, 1},
+		{big.NewInt(0), 1},
+		{big.NewInt(1), 1},
+		{big.NewInt(5), 5},
+	}
+	for _, tt := range tests {
+		if got := minAmountThreshold(tt.minAmount); got.Cmp(big.NewInt(tt.want)) != 0 {
+			t.Errorf("minAmountThreshold(%v) = %s, want %d", tt.minAmount, got, tt.want)
+		}
+	}
+}
+
+// Balance standards count as owned from the minimum amount on, or from 1 if it is 0.
+func TestVerifyOwnershipBalanceThreshold(t *testing.T) {
+	standards := []struct {
+		name     string
+		standard uint8
+		method   string
+	}{
+		{"ERC1155", StdERC1155, "balanceOf(address,uint256)"},
+		{"ERC721 collection", StdERC721Collection, "balanceOf(address)"},
+		{"ERC20", StdERC20, "balanceOf(address)"},
+	}
+	tests := []struct {
+		balance   int64
+		minAmount int64
+		want      uint8
+	}{
+		{0, 0, ReasonNotOwner},
+		{1, 0, ReasonOwner},
+		{2, 3, ReasonNotOwner},
+		{3, 3, ReasonOwner},
+		{4, 3, ReasonOwner},
+	}
+	for _, std := range standards {
+		for _, tt := range tests {
+			t.Run(fmt.Sprintf("%s/%d of %d", std.name, tt.balance, tt.minAmount), func(t *testing.T) {
+				chain := setupOwnershipTest(t)
+				chain.deploy(ownershipTestCollection, std.method, packOutput(t, "uint256", big.NewInt(tt.balance)), nil)
+				res, err := verifyOwnership(context.Background(), ownershipRequest(std.standard, tt.minAmount))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if res.Reason != tt.want {
+					t.Fatalf("reason = %s, want %s", reasonName(res.Reason), reasonName(tt.want))
+				}
+				if res.OwnerAtBlock != opA || res.ObservedAmount.Cmp(big.NewInt(tt.balance)) != 0 {
+					t.Errorf("observed %s holding %s, want %s holding %d", res.OwnerAtBlock, res.ObservedAmount, opA, tt.balance)
+				}
+			})
+		}
+	}
+}
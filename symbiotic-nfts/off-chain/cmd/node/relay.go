@@ -0,0 +1,290 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"math/big"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/go-errors/errors"
+	v1 "github.com/symbioticfi/relay/api/client/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"sum/internal/utils"
+)
+
+// The node may talk to several relay sidecars of the same network. Calls go to the first healthy
+// endpoint in --relay-api-url order and fail over to the next one when an endpoint is down.
+// Sign request hashes only depend on the key tag, epoch and message, so every relay aggregates
+// the signatures of a request under the same hash and any of them can serve its proof.
+
+// signKeyTag is the relay key tag task responses are signed with.
+const signKeyTag = 15
+
+const relayProbeTimeout = 3 * time.Second
+
+var relays *relayPool
+
+type relayEndpoint struct {
+	url    string
+	conn   *grpc.ClientConn
+	client *v1.SymbioticClient
+
+	mu      sync.Mutex
+	healthy bool
+}
+
+// setHealth records the outcome of a call or probe, err is nil on success.
+func (e *relayEndpoint) setHealth(err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.healthy != (err == nil) {
+		if err == nil {
+			slog.Info("Relay is healthy", "relay", e.url)
+		} else {
+			slog.Warn("Relay is unhealthy", "relay", e.url, "err", err)
+		}
+	}
+	e.healthy = err == nil
+	up := 0.0
+	if e.healthy {
+		up = 1
+	}
+	relayUp.WithLabelValues(e.url).Set(up)
+}
+
+func (e *relayEndpoint) isHealthy() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.healthy
+}
+
+type relayPool struct {
+	endpoints []*relayEndpoint
+}
+
+// newRelayPool connects to the relay URLs. Endpoints start out healthy, the first failing call or
+// health probe marks them otherwise.
+func newRelayPool(urls []string, opts utils.GRPCOptions) (*relayPool, error) {
+	p := &relayPool{}
+	for _, u := range urls {
+		if slices.ContainsFunc(p.endpoints, func(e *relayEndpoint) bool { return e.url == u }) {
+			p.close()
+			return nil, errors.Errorf("relay %s is listed twice", u)
+		}
+		conn, err := utils.GetGRPCConnection(u, opts)
+		if err != nil {
+			p.close()
+			return nil, errors.Errorf("failed to create relay client for %s: %w", u, err)
+		}
+		p.endpoints = append(p.endpoints, &relayEndpoint{url: u, conn: conn, client: v1.NewSymbioticClient(conn), healthy: true})
+		relayUp.WithLabelValues(u).Set(1)
+	}
+	return p, nil
+}
+
+func (p *relayPool) close() {
+	for _, e := range p.endpoints {
+		_ = e.conn.Close()
+	}
+}
+
+// ordered returns the endpoints in the order calls try them: the endpoint named prefer first if it's
+// healthy, then the healthy ones, then the unhealthy ones as a last resort since their state may be stale.
+func (p *relayPool) ordered(prefer string) []*relayEndpoint {
+	var healthy, unhealthy []*relayEndpoint
+	for _, e := range p.endpoints {
+		switch {
+		case !e.isHealthy():
+			unhealthy = append(unhealthy, e)
+		case e.url == prefer:
+			healthy = append([]*relayEndpoint{e}, healthy...)
+		default:
+			healthy = append(healthy, e)
+		}
+	}
+	return append(healthy, unhealthy...)
+}
+
+// relayDown reports whether a relay call failed because the endpoint is unreachable or overloaded
+// rather than because of the request, in which case another endpoint may succeed.
+func relayDown(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted:
+		return true
+	}
+	return false
+}
+
+// call runs fn against the endpoints in turn until it succeeds or fails with an error next rejects,
+// and returns the URL of the endpoint that answered last. Endpoints failing with relayDown errors
+// are marked unhealthy.
+func (p *relayPool) call(ctx context.Context, method, prefer string, next func(error) bool, fn func(*v1.SymbioticClient) error) (string, error) {
+	var (
+		url  string
+		err  error
+		errs []error
+	)
+	for _, e := range p.ordered(prefer) {
+		url = e.url
+		if err = fn(e.client); err == nil {
+			e.setHealth(nil)
+			return url, nil
+		}
+		recordRelayError(method, url, err)
+		if relayDown(err) && ctx.Err() == nil {
+			e.setHealth(err)
+		}
+		if ctx.Err() != nil || !next(err) {
+			return url, err
+		}
+		errs = append(errs, errors.Errorf("%s: %w", url, err))
+	}
+	if len(errs) > 1 {
+		// The last error is wrapped first so that callers see its gRPC code.
+		return url, errors.Errorf("all %d relays failed: %w (before: %w)", len(errs), errs[len(errs)-1], errors.Join(errs[:len(errs)-1]...))
+	}
+	return url, err
+}
+
+// signMessage requests a signature of msg at the epoch from the first healthy relay. It checks the
+// returned request hash against the one computed locally, so tasks track the same hash whichever relay signed.
+func (p *relayPool) signMessage(ctx context.Context, epoch uint64, msg []byte) (*v1.SignMessageResponse, string, error) {
+	var resp *v1.SignMessageResponse
+	url, err := p.call(ctx, "SignMessage", "", relayDown, func(c *v1.SymbioticClient) (err error) {
+		resp, err = c.SignMessage(ctx, &v1.SignMessageRequest{
+			KeyTag:        signKeyTag,
+			Message:       msg,
+			RequiredEpoch: &epoch,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, url, err
+	}
+	if resp.Epoch != epoch {
+		return nil, url, errors.Errorf("relay %s signed at epoch %d, requested %d", url, resp.Epoch, epoch)
+	}
+	if want := signRequestHash(epoch, msg); common.HexToHash(resp.RequestHash) != want {
+		return nil, url, errors.Errorf("relay %s returned request hash %s, expected %s", url, resp.RequestHash, want.Hex())
+	}
+	return resp, url, nil
+}
+
+// aggregationProof fetches the proof of the request, asking the relay that signed it first. A relay
+// that hasn't aggregated the request yet answers NotFound, so the other relays are asked as well.
+func (p *relayPool) aggregationProof(ctx context.Context, prefer, requestHash string) (*v1.GetAggregationProofResponse, error) {
+	var resp *v1.GetAggregationProofResponse
+	_, err := p.call(ctx, "GetAggregationProof", prefer, func(err error) bool {
+		return relayDown(err) || status.Code(err) == codes.NotFound
+	}, func(c *v1.SymbioticClient) (err error) {
+		resp, err = c.GetAggregationProof(ctx, &v1.GetAggregationProofRequest{RequestHash: requestHash})
+		return err
+	})
+	return resp, err
+}
+
+func (p *relayPool) suggestedEpoch(ctx context.Context) (uint64, error) {
+	var resp *v1.GetSuggestedEpochResponse
+	_, err := p.call(ctx, "GetSuggestedEpoch", "", relayDown, func(c *v1.SymbioticClient) (err error) {
+		resp, err = c.GetSuggestedEpoch(ctx, &v1.GetSuggestedEpochRequest{})
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return resp.Epoch, nil
+}
+
+func (p *relayPool) currentEpoch(ctx context.Context) (uint64, error) {
+	var resp *v1.GetCurrentEpochResponse
+	_, err := p.call(ctx, "GetCurrentEpoch", "", relayDown, func(c *v1.SymbioticClient) (err error) {
+		resp, err = c.GetCurrentEpoch(ctx, &v1.GetCurrentEpochRequest{})
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return resp.Epoch, nil
+}
+
+func (p *relayPool) validatorSet(ctx context.Context, epoch uint64) (*v1.GetValidatorSetResponse, error) {
+	var resp *v1.GetValidatorSetResponse
+	_, err := p.call(ctx, "GetValidatorSet", "", relayDown, func(c *v1.SymbioticClient) (err error) {
+		resp, err = c.GetValidatorSet(ctx, &v1.GetValidatorSetRequest{Epoch: &epoch})
+		return err
+	})
+	return resp, err
+}
+
+// signRequestHash is the hash the relay identifies a sign request by, see the relay's SignatureRequest.Hash.
+func signRequestHash(epoch uint64, msg []byte) common.Hash {
+	return crypto.Keccak256Hash([]byte{signKeyTag}, new(big.Int).SetUint64(epoch).Bytes(), msg)
+}
+
+type relayView struct {
+	URL          string    `json:"url"`
+	State        string    `json:"state"`
+	Reachable    bool      `json:"reachable"`
+	CurrentEpoch uint64    `json:"currentEpoch,omitempty"`
+	LatencyMs    int64     `json:"latencyMs"`
+	Error        string    `json:"error,omitempty"`
+	CheckedAt    time.Time `json:"checkedAt"`
+}
+
+// probe checks every endpoint concurrently with GetCurrentEpoch and updates its health.
+func (p *relayPool) probe(ctx context.Context) []relayView {
+	views := make([]relayView, len(p.endpoints))
+	var wg sync.WaitGroup
+	for i, e := range p.endpoints {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			views[i] = e.probe(ctx)
+		}()
+	}
+	wg.Wait()
+	return views
+}
+
+func (e *relayEndpoint) probe(ctx context.Context) relayView {
+	v := relayView{URL: e.url}
+	ctx, cancel := context.WithTimeout(ctx, relayProbeTimeout)
+	defer cancel()
+	start := time.Now()
+	resp, err := e.client.GetCurrentEpoch(ctx, &v1.GetCurrentEpochRequest{})
+	v.LatencyMs = time.Since(start).Milliseconds()
+	v.State = e.conn.GetState().String()
+	v.CheckedAt = time.Now()
+	if err != nil {
+		recordRelayError("GetCurrentEpoch", e.url, err)
+		e.setHealth(err)
+		v.Error = err.Error()
+		return v
+	}
+	e.setHealth(nil)
+	v.Reachable = true
+	v.CurrentEpoch = resp.Epoch
+	return v
+}
+
+// watch probes the endpoints every interval so that endpoints coming back are used again
+// and failing ones are skipped before a task call runs into them.
+func (p *relayPool) watch(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			p.probe(ctx)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+
+	v1 "github.com/symbioticfi/relay/api/client/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeRelay is a relay API connection whose calls are answered by handle, given the method name
+// (e.g. "GetSuggestedEpoch"), the request and the reply to fill in.
+type fakeRelay struct {
+	handle func(method string, req, reply any) error
+
+	mu    sync.Mutex
+	calls []string
+}
+
+func (r *fakeRelay) Invoke(_ context.Context, method string, req, reply any, _ ...grpc.CallOption) error {
+	name := path.Base(method)
+	r.mu.Lock()
+	r.calls = append(r.calls, name)
+	r.mu.Unlock()
+	return r.handle(name, req, reply)
+}
+
+func (r *fakeRelay) NewStream(context.Context, *grpc.StreamDesc, string, ...grpc.CallOption) (grpc.ClientStream, error) {
+	return nil, status.Error(codes.Unimplemented, "streams are not supported")
+}
+
+// epochRelay is a relay suggesting one epoch while being at another.
+func epochRelay(suggested, current uint64) *fakeRelay {
+	return &fakeRelay{handle: func(method string, _, reply any) error {
+		switch r := reply.(type) {
+		case *v1.GetSuggestedEpochResponse:
+			r.Epoch = suggested
+		case *v1.GetCurrentEpochResponse:
+			r.Epoch = current
+		default:
+			return status.Error(codes.Unimplemented, method)
+		}
+		return nil
+	}}
+}
+
+// setupTestRelays points relays at the fake relays, in order, as the endpoints relay-0, relay-1, ...
+func setupTestRelays(t *testing.T, rs ...*fakeRelay) {
+	t.Helper()
+	p := &relayPool{}
+	for i, r := range rs {
+		p.endpoints = append(p.endpoints, &relayEndpoint{url: fmt.Sprintf("relay-%d", i), client: v1.NewSymbioticClient(r), healthy: true})
+	}
+	relays = p
+	t.Cleanup(func() { relays = nil })
+}
+
+// codeRelay is a relay failing every call with code.
+func codeRelay(code codes.Code) *fakeRelay {
+	return &fakeRelay{handle: func(method string, _, _ any) error { return status.Error(code, method+" failed") }}
+}
+
+// proofRelay is a relay serving proof for every request hash, or NotFound if proof is nil.
+func proofRelay(proof []byte) *fakeRelay {
+	return &fakeRelay{handle: func(method string, _, reply any) error {
+		r, ok := reply.(*v1.GetAggregationProofResponse)
+		switch {
+		case !ok:
+			return status.Error(codes.Unimplemented, method)
+		case proof == nil:
+			return status.Error(codes.NotFound, "not aggregated yet")
+		}
+		r.AggregationProof = &v1.AggregationProof{Proof: proof}
+		return nil
+	}}
+}
+
+func (r *fakeRelay) callCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.calls)
+}
+
+func TestRelayDown(t *testing.T) {
+	for _, code := range []codes.Code{codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted} {
+		if !relayDown(status.Error(code, "")) {
+			t.Errorf("relayDown(%s) = false", code)
+		}
+	}
+	// Errors about the request, or that every relay of the network would answer alike, don't fail over.
+	for _, code := range []codes.Code{codes.OK, codes.Unknown, codes.InvalidArgument, codes.NotFound, codes.Unauthenticated,
+		codes.PermissionDenied, codes.Unimplemented, codes.FailedPrecondition, codes.Internal} {
+		if relayDown(status.Error(code, "")) {
+			t.Errorf("relayDown(%s) = true", code)
+		}
+	}
+}
+
+func TestRelayFailover(t *testing.T) {
+	down, up := codeRelay(codes.Unavailable), epochRelay(5, 5)
+	setupTestRelays(t, down, up)
+	ctx := t.Context()
+
+	// relay-0 is down: the call fails over to relay-1 and relay-0 moves to the end of the order.
+	if epoch, err := relays.suggestedEpoch(ctx); err != nil || epoch != 5 {
+		t.Fatalf("suggestedEpoch() = %d, %v, want 5 from relay-1", epoch, err)
+	}
+	if relays.endpoints[0].isHealthy() || !relays.endpoints[1].isHealthy() {
+		t.Fatal("the down relay is still healthy, or the answering one is not")
+	}
+	if epoch, err := relays.currentEpoch(ctx); err != nil || epoch != 5 || down.callCount() != 1 || up.callCount() != 2 {
+		t.Fatalf("currentEpoch() = %d, %v after %d and %d calls, want relay-1 to answer first", epoch, err, down.callCount(), up.callCount())
+	}
+
+	// Unhealthy endpoints are still tried as a last resort, the one answering is healthy again.
+	up.handle = codeRelay(codes.DeadlineExceeded).handle
+	down.handle = epochRelay(6, 6).handle
+	if epoch, err := relays.suggestedEpoch(ctx); err != nil || epoch != 6 {
+		t.Fatalf("suggestedEpoch() = %d, %v, want 6 from relay-0", epoch, err)
+	}
+	if !relays.endpoints[0].isHealthy() || relays.endpoints[1].isHealthy() {
+		t.Fatal("endpoint health was not updated by the calls")
+	}
+
+	// All relays down: the error carries the last relay's code and names every relay.
+	down.handle = codeRelay(codes.ResourceExhausted).handle
+	_, err := relays.suggestedEpoch(ctx)
+	if status.Code(err) != codes.DeadlineExceeded || !strings.Contains(err.Error(), "all 2 relays failed") ||
+		!strings.Contains(err.Error(), "relay-0") || !strings.Contains(err.Error(), "relay-1") {
+		t.Fatalf("suggestedEpoch() = %v, want the failures of both relays", err)
+	}
+}
+
+// An error about the request is the network's answer: it is returned without trying the other relays
+// and leaves the endpoint healthy.
+func TestRelayNoFailover(t *testing.T) {
+	for _, code := range []codes.Code{codes.InvalidArgument, codes.Unauthenticated, codes.PermissionDenied, codes.Unimplemented, codes.Unknown} {
+		first, second := codeRelay(code), epochRelay(5, 5)
+		setupTestRelays(t, first, second)
+		if _, err := relays.suggestedEpoch(t.Context()); status.Code(err) != code {
+			t.Errorf("suggestedEpoch() = %v, want %s", err, code)
+		}
+		if second.callCount() != 0 || !relays.endpoints[0].isHealthy() {
+			t.Errorf("%s failed over to relay-1 or marked relay-0 unhealthy", code)
+		}
+	}
+}
+
+func TestAggregationProof(t *testing.T) {
+	other, signer := proofRelay([]byte("other")), proofRelay([]byte("signer"))
+	setupTestRelays(t, other, signer)
+	ctx := t.Context()
+
+	// The relay that signed the request is asked first.
+	resp, err := relays.aggregationProof(ctx, "relay-1", "0xab")
+	if err != nil || string(resp.AggregationProof.Proof) != "signer" || other.callCount() != 0 {
+		t.Fatalf("aggregationProof() = %v, %v, want the signing relay's proof", resp, err)
+	}
+	// Without a preference the pool order applies.
+	if resp, err := relays.aggregationProof(ctx, "", "0xab"); err != nil || string(resp.AggregationProof.Proof) != "other" {
+		t.Fatalf("aggregationProof() = %v, %v, want relay-0's proof", resp, err)
+	}
+
+	// The signing relay hasn't aggregated the request, another relay has.
+	signer.handle = proofRelay(nil).handle
+	if resp, err := relays.aggregationProof(ctx, "relay-1", "0xab"); err != nil || string(resp.AggregationProof.Proof) != "other" {
+		t.Fatalf("aggregationProof() = %v, %v, want relay-0's proof", resp, err)
+	}
+	if !relays.endpoints[1].isHealthy() {
+		t.Fatal("NotFound marked the signing relay unhealthy")
+	}
+
+	// No relay has the proof yet.
+	other.handle = proofRelay(nil).handle
+	if _, err := relays.aggregationProof(ctx, "relay-1", "0xab"); status.Code(err) != codes.NotFound {
+		t.Fatalf("aggregationProof() = %v, want NotFound", err)
+	}
+	// The signing relay is down, its preference doesn't hold while it is unhealthy.
+	signer.handle = codeRelay(codes.Unavailable).handle
+	other.handle = proofRelay([]byte("other")).handle
+	if resp, err := relays.aggregationProof(ctx, "relay-1", "0xab"); err != nil || string(resp.AggregationProof.Proof) != "other" {
+		t.Fatalf("aggregationProof() = %v, %v, want relay-0's proof", resp, err)
+	}
+	calls := signer.callCount()
+	if _, err := relays.aggregationProof(ctx, "relay-1", "0xab"); err != nil || signer.callCount() != calls {
+		t.Fatalf("aggregationProof() = %v, asked the unhealthy signing relay first", err)
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/go-errors/errors"
+
+	"sum/internal/store"
+)
+
+// blockHashWindow is how many blocks behind the scan checkpoint we keep hashes for.
+// Reorgs deeper than that can't be detected.
+const blockHashWindow = 256
+
+// confirmedHeader returns the newest block of the app chain that is considered safe to ingest,
+// i.e. the block selected by --block-tag minus the chain's confirmation depth.
+// It also returns the number of the tagged block. The header is nil when the chain is not yet deep enough.
+func confirmedHeader(ctx context.Context, chainID int64, cli *ethclient.Client) (*types.Header, uint64, error) {
+	var tag *big.Int
+	switch cfg.blockTag {
+	case "", "latest":
+	case "safe":
+		tag = big.NewInt(int64(rpc.SafeBlockNumber))
+	case "finalized":
+		tag = big.NewInt(int64(rpc.FinalizedBlockNumber))
+	default:
+		return nil, 0, errors.Errorf("unknown block tag %q", cfg.blockTag)
+	}
+	head, err := cli.HeaderByNumber(ctx, tag)
+	if err != nil {
+		return nil, 0, errors.Errorf("failed to get %s header: %w", cfg.blockTag, err)
+	}
+	headNumber := head.Number.Uint64()
+
+	depth := cfg.confirmations
+	if d, ok := chainConfirmations[chainID]; ok {
+		depth = d
+	}
+	if depth == 0 {
+		return head, headNumber, nil
+	}
+	if headNumber < depth {
+		return nil, headNumber, nil
+	}
+	confirmed, err := cli.HeaderByNumber(ctx, new(big.Int).SetUint64(headNumber-depth))
+	return confirmed, headNumber, err
+}
+
+// detectReorg compares the recorded hashes of already scanned blocks with the canonical chain.
+// On mismatch it rewinds the chain checkpoint to the last common block and reconciles tasks
+// that were ingested from the dropped blocks. It reports whether the checkpoint was rewound.
+func (w *chainWorker) detectReorg(ctx context.Context) (bool, error) {
+	chainID, cli := w.chainID, w.cli
+	recorded, err := db.BlockHashes(chainID)
+	if err != nil {
+		return false, errors.Errorf("failed to load block hashes: %w", err)
+	}
+	if len(recorded) == 0 {
+		return false, nil
+	}
+
+	canonical := func(rec store.BlockHash) (bool, error) {
+		h, err := cli.HeaderByNumber(ctx, new(big.Int).SetUint64(rec.Number))
+		if err != nil {
+			return false, errors.Errorf("failed to get header %d: %w", rec.Number, err)
+		}
+		return h.Hash() == rec.Hash, nil
+	}
+	if ok, err := canonical(recorded[0]); err != nil || ok {
+		return false, err
+	}
+	// Every block below the common ancestor is canonical as well, so bisect for the newest
+	// recorded block that still is. lo ends at len(recorded) if none is.
+	lo, hi := 1, len(recorded)
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		ok, err := canonical(recorded[mid])
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	// from is the first block to scan again. If the whole recorded window was reorged out,
+	// restart from its oldest block.
+	from := recorded[len(recorded)-1].Number
+	if lo < len(recorded) {
+		from = recorded[lo].Number + 1
+	}
+
+	slog.WarnContext(ctx, "Detected reorg", "chainID", chainID, "recordedHead", recorded[0].Number, "rescanFrom", from)
+
+	if err := db.RewindBlockHashes(chainID, from); err != nil {
+		return false, errors.Errorf("failed to rewind block hashes: %w", err)
+	}
+	w.nextBlock = from
+	if err := db.PutCheckpoint(chainID, from); err != nil {
+		return true, errors.Errorf("failed to rewind checkpoint: %w", err)
+	}
+
+	return true, reconcileReorgedTasks(ctx, chainID, from)
+}
+
+// reconcileReorgedTasks drops tasks created in or after block from that no longer exist on chain.
+// Tasks that were re-included in another block are kept as is since the task ID commits to the request.
+func reconcileReorgedTasks(ctx context.Context, chainID int64, from uint64) error {
+	for _, st := range taskSnapshot() {
+		if st.ChainID != chainID || st.CreatedBlock < from {
+			continue
+		}
+		if err := dropIfReorged(ctx, st.Key()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dropIfReorged forgets a task if it no longer exists on its chain.
+func dropIfReorged(ctx context.Context, key store.TaskKey) error {
+	st, ok := getTask(key)
+	if !ok {
+		return nil
+	}
+	h, ok := handlers[key.ChainID][key.Contract]
+	if !ok {
+		return nil
+	}
+	status, err := h.GetTaskStatus(ctx, key.TaskID)
+	if err != nil {
+		return errors.Errorf("failed to get status of reorged task %s: %w", key, err)
+	}
+	if status != TaskNotFound {
+		slog.InfoContext(ctx, "Task survived reorg", "task", key, "status", status)
+		return nil
+	}
+	slog.WarnContext(ctx, "Dropping task removed by reorg", "task", key, "createdBlock", st.CreatedBlock)
+	removeTask(key)
+	return nil
+}
+
+// recordScannedBlock remembers the hash of the last scanned block and forgets hashes
+// that fell out of the reorg detection window.
+func recordScannedBlock(chainID int64, header *types.Header) error {
+	number := header.Number.Uint64()
+	if err := db.PutBlockHash(chainID, number, header.Hash()); err != nil {
+		return err
+	}
+	if number > blockHashWindow {
+		return db.PruneBlockHashes(chainID, number-blockHashWindow)
+	}
+	return nil
+}
@@ -0,0 +1,463 @@
+package main
+
+import (
+	"context"
+	"maps"
+	"math/big"
+	"math/bits"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/go-errors/errors"
+
+	"sum/internal/store"
+)
+
+// fakeChain serves a chain of empty blocks as the "eth" JSON-RPC namespace, see newFakeChain.
+type fakeChain struct {
+	mu      sync.Mutex
+	headers []*types.Header
+	// safe and finalized are the blocks the safe and finalized tags select.
+	safe, finalized uint64
+	headerCalls     int
+	// views holds the eth_call results of deployed contracts by method selector, at any block.
+	// A contract without an entry for the called method reverts without data.
+	views map[common.Address]map[[4]byte]fakeCall
+	// codeErr and callErr fail eth_getCode and eth_call, as an unavailable node would.
+	codeErr, callErr error
+	// eth_getLogs fails with logsErr, or if the range spans more than maxLogRange blocks.
+	// The chain has no logs, the ranges of successful queries are recorded in logRanges.
+	logsErr     error
+	maxLogRange uint64
+	logRanges   [][2]uint64
+	// balance is the eth_getBalance of every account.
+	balance *big.Int
+}
+
+type fakeCall struct {
+	out []byte
+	err error
+}
+
+// revertError is how nodes report a reverted eth_call: code 3 with the revert data.
+type revertError struct{ data []byte }
+
+func (e revertError) Error() string          { return "execution reverted" }
+func (e revertError) ErrorCode() int         { return 3 }
+func (e revertError) ErrorData() interface{} { return hexutil.Encode(e.data) }
+
+// newFakeChain serves blocks 0 to length-1 to the returned client over an in-process connection.
+func newFakeChain(t *testing.T, length int) (*fakeChain, *ethclient.Client) {
+	t.Helper()
+	c := &fakeChain{balance: new(big.Int)}
+	c.fork(0, length, 0)
+	srv := rpc.NewServer()
+	if err := srv.RegisterName("eth", c); err != nil {
+		t.Fatal(err)
+	}
+	cli := ethclient.NewClient(rpc.DialInProc(srv))
+	t.Cleanup(func() {
+		cli.Close()
+		srv.Stop()
+	})
+	return c, cli
+}
+
+// fork replaces the blocks from the given one on by those of another branch, up to length-1.
+func (c *fakeChain) fork(from uint64, length int, branch byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.headers = c.headers[:from]
+	for n := from; n < uint64(length); n++ {
+		h := &types.Header{Number: new(big.Int).SetUint64(n), Difficulty: big.NewInt(1), Time: n, Extra: []byte{branch}}
+		if n > 0 {
+			h.ParentHash = c.headers[n-1].Hash()
+		}
+		c.headers = append(c.headers, h)
+	}
+}
+
+func (c *fakeChain) header(n uint64) *types.Header {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.headers[n]
+}
+
+func (c *fakeChain) GetBlockByNumber(number rpc.BlockNumber, _ bool) (*types.Header, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.headerCalls++
+	n := uint64(len(c.headers) - 1)
+	switch number {
+	case rpc.LatestBlockNumber:
+	case rpc.SafeBlockNumber:
+		n = c.safe
+	case rpc.FinalizedBlockNumber:
+		n = c.finalized
+	default:
+		if number < 0 {
+			return nil, errors.Errorf("unsupported block tag %s", number)
+		}
+		n = uint64(number)
+	}
+	if n >= uint64(len(c.headers)) {
+		return nil, nil
+	}
+	return c.headers[n], nil
+}
+
+func (c *fakeChain) BlockNumber() hexutil.Uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return hexutil.Uint64(len(c.headers) - 1)
+}
+
+func (c *fakeChain) GetBalance(common.Address, rpc.BlockNumberOrHash) *hexutil.Big {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return (*hexutil.Big)(new(big.Int).Set(c.balance))
+}
+
+// deploy gives the contract code. Its view method, e.g. "ownerOf(uint256)", returns out or fails with err.
+func (c *fakeChain) deploy(contract common.Address, method string, out []byte, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.views == nil {
+		c.views = make(map[common.Address]map[[4]byte]fakeCall)
+	}
+	if c.views[contract] == nil {
+		c.views[contract] = make(map[[4]byte]fakeCall)
+	}
+	if method != "" {
+		c.views[contract][[4]byte(crypto.Keccak256([]byte(method)))] = fakeCall{out, err}
+	}
+}
+
+func (c *fakeChain) GetCode(contract common.Address, _ rpc.BlockNumberOrHash) (hexutil.Bytes, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.codeErr != nil {
+		return nil, c.codeErr
+	}
+	if _, ok := c.views[contract]; !ok {
+		return nil, nil
+	}
+	return hexutil.Bytes{0x60, 0x80}, nil
+}
+
+type fakeCallArgs struct {
+	To    *common.Address `json:"to"`
+	Input hexutil.Bytes   `json:"input"`
+	Data  hexutil.Bytes   `json:"data"`
+}
+
+func (c *fakeChain) Call(args fakeCallArgs, _ rpc.BlockNumberOrHash) (hexutil.Bytes, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.callErr != nil {
+		return nil, c.callErr
+	}
+	input := args.Input
+	if input == nil {
+		input = args.Data
+	}
+	if args.To == nil || len(input) < 4 {
+		return nil, revertError{}
+	}
+	call, ok := c.views[*args.To][[4]byte(input[:4])]
+	if !ok {
+		return nil, revertError{}
+	}
+	return call.out, call.err
+}
+
+type fakeFilterArgs struct {
+	FromBlock hexutil.Uint64 `json:"fromBlock"`
+	ToBlock   hexutil.Uint64 `json:"toBlock"`
+}
+
+func (c *fakeChain) GetLogs(args fakeFilterArgs) ([]types.Log, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	from, to := uint64(args.FromBlock), uint64(args.ToBlock)
+	if c.logsErr != nil {
+		return nil, c.logsErr
+	}
+	if c.maxLogRange != 0 && to-from+1 > c.maxLogRange {
+		return nil, errors.Errorf("query returned more than 10000 results. Try with this block range [0x%x, 0x%x].", from, from+c.maxLogRange-1)
+	}
+	c.logRanges = append(c.logRanges, [2]uint64{from, to})
+	return []types.Log{}, nil
+}
+
+func TestConfirmedHeader(t *testing.T) {
+	chain, cli := newFakeChain(t, 11)
+	chain.safe, chain.finalized = 8, 6
+	t.Cleanup(func() {
+		cfg = config{}
+		chainConfirmations = nil
+	})
+
+	tests := []struct {
+		name          string
+		tag           string
+		confirmations uint64
+		chainDepth    map[int64]uint64
+		want          int64 // -1 if the chain is not deep enough
+		wantErr       bool
+	}{
+		{name: "default tag", want: 10},
+		{name: "latest", tag: "latest", confirmations: 3, want: 7},
+		{name: "safe", tag: "safe", confirmations: 2, want: 6},
+		{name: "finalized", tag: "finalized", want: 6},
+		{name: "chain depth overrides the default", confirmations: 3, chainDepth: map[int64]uint64{31337: 5}, want: 5},
+		{name: "zero chain depth", confirmations: 3, chainDepth: map[int64]uint64{31337: 0}, want: 10},
+		{name: "other chain's depth", confirmations: 3, chainDepth: map[int64]uint64{1: 5}, want: 7},
+		{name: "depth reaches genesis", confirmations: 10, want: 0},
+		{name: "chain not deep enough", confirmations: 11, want: -1},
+		{name: "unknown tag", tag: "pending", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg = config{blockTag: tt.tag, confirmations: tt.confirmations}
+			chainConfirmations = tt.chainDepth
+			header, head, err := confirmedHeader(context.Background(), 31337, cli)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("confirmedHeader() succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantHead := uint64(10)
+			switch tt.tag {
+			case "safe":
+				wantHead = chain.safe
+			case "finalized":
+				wantHead = chain.finalized
+			}
+			if head != wantHead {
+				t.Errorf("tagged head = %d, want %d", head, wantHead)
+			}
+			switch {
+			case tt.want < 0:
+				if header != nil {
+					t.Errorf("confirmed header = %d, want none", header.Number)
+				}
+			case header == nil:
+				t.Errorf("no confirmed header, want %d", tt.want)
+			case header.Hash() != chain.header(uint64(tt.want)).Hash():
+				t.Errorf("confirmed header = %d, want %d", header.Number, tt.want)
+			}
+		})
+	}
+}
+
+// statusHandler is a task contract that only answers GetTaskStatus, with TaskNotFound for unknown tasks.
+type statusHandler struct {
+	TaskHandler
+	statuses map[common.Hash]uint8
+	err      error
+}
+
+func (h statusHandler) GetTaskStatus(_ context.Context, taskID common.Hash) (uint8, error) {
+	if h.err != nil {
+		return 0, h.err
+	}
+	if status, ok := h.statuses[taskID]; ok {
+		return status, nil
+	}
+	return TaskNotFound, nil
+}
+
+var reorgTestContract = common.HexToAddress("0x5FbDB2315678afecb367f032d93F642f64180aa3")
+
+// setupReorgTest tracks the given tasks of chain 31337 in a fresh store, bound to a contract answering with h.
+func setupReorgTest(t *testing.T, h TaskHandler, tracked ...TaskState) {
+	t.Helper()
+	t.Cleanup(func() { handlers, tasks, db = nil, nil, nil })
+	openTestDB(t)
+	handlers = map[int64]map[common.Address]TaskHandler{31337: {reorgTestContract: h}}
+	tasks = make(map[store.TaskKey]TaskState)
+	for _, st := range tracked {
+		if err := addTask(st); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func reorgTestTask(id int64, createdBlock uint64) TaskState {
+	return TaskState{ChainID: 31337, Contract: reorgTestContract, TaskID: common.BigToHash(big.NewInt(id)), CreatedBlock: createdBlock}
+}
+
+func TestDetectReorg(t *testing.T) {
+	tests := []struct {
+		name     string
+		recorded []uint64
+		fork     bool
+		forkAt   uint64 // the first block replaced by another branch
+		want     uint64 // the block scanning restarts from
+	}{
+		{name: "no reorg", recorded: []uint64{4, 8, 12}, want: 13},
+		{name: "reorg between recorded blocks", recorded: []uint64{4, 8, 12}, fork: true, forkAt: 10, want: 9},
+		{name: "sparse hashes", recorded: []uint64{2, 5, 9, 12}, fork: true, forkAt: 7, want: 6},
+		{name: "whole window", recorded: []uint64{6, 9, 12}, fork: true, forkAt: 3, want: 6},
+		{name: "whole window from genesis", recorded: []uint64{0, 5, 12}, fork: true, forkAt: 0, want: 0},
+		{name: "reorg above genesis", recorded: []uint64{0, 5, 12}, fork: true, forkAt: 1, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain, cli := newFakeChain(t, 13)
+			setupReorgTest(t, statusHandler{})
+			for _, n := range tt.recorded {
+				if err := recordScannedBlock(31337, chain.header(n)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := db.PutCheckpoint(31337, 13); err != nil {
+				t.Fatal(err)
+			}
+			if tt.fork {
+				chain.fork(tt.forkAt, 13, 1)
+			}
+
+			w := newChainWorker(31337, cli, 13, false)
+			rewound, err := w.detectReorg(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rewound != tt.fork {
+				t.Errorf("rewound = %t, want %t", rewound, tt.fork)
+			}
+			if w.nextBlock != tt.want {
+				t.Errorf("next block = %d, want %d", w.nextBlock, tt.want)
+			}
+			checkpoints, err := db.Checkpoints()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if checkpoints[31337] != tt.want {
+				t.Errorf("checkpoint = %d, want %d", checkpoints[31337], tt.want)
+			}
+			hashes, err := db.BlockHashes(31337)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var left []uint64
+			for _, h := range hashes {
+				left = append(left, h.Number)
+			}
+			var want []uint64
+			for _, n := range slices.Backward(tt.recorded) {
+				if n < tt.want {
+					want = append(want, n)
+				}
+			}
+			if !slices.Equal(left, want) {
+				t.Errorf("block hashes after the check = %v, want %v", left, want)
+			}
+		})
+	}
+}
+
+// A deep reorg is located by bisecting the recorded hashes rather than checking each of them.
+func TestDetectReorgBisects(t *testing.T) {
+	chain, cli := newFakeChain(t, blockHashWindow)
+	setupReorgTest(t, statusHandler{})
+	for n := range uint64(blockHashWindow) {
+		if err := recordScannedBlock(31337, chain.header(n)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	chain.fork(40, blockHashWindow, 1)
+	chain.headerCalls = 0
+
+	w := newChainWorker(31337, cli, blockHashWindow, false)
+	if _, err := w.detectReorg(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if w.nextBlock != 40 {
+		t.Errorf("next block = %d, want 40", w.nextBlock)
+	}
+	if limit := 1 + bits.Len(blockHashWindow); chain.headerCalls > limit {
+		t.Errorf("fetched %d headers, want at most %d", chain.headerCalls, limit)
+	}
+}
+
+func TestDetectReorgReconcilesTasks(t *testing.T) {
+	chain, cli := newFakeChain(t, 13)
+	kept, survived, dropped := reorgTestTask(1, 8), reorgTestTask(2, 9), reorgTestTask(3, 11)
+	// Only tasks at or above the rescan start are checked, so kept would be dropped if it was.
+	setupReorgTest(t, statusHandler{statuses: map[common.Hash]uint8{survived.TaskID: TaskCreated}}, kept, survived, dropped)
+	for _, n := range []uint64{4, 8, 12} {
+		if err := recordScannedBlock(31337, chain.header(n)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	chain.fork(9, 13, 1)
+
+	w := newChainWorker(31337, cli, 13, false)
+	if _, err := w.detectReorg(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if w.nextBlock != 9 {
+		t.Fatalf("next block = %d, want 9", w.nextBlock)
+	}
+	for _, st := range []TaskState{kept, survived} {
+		if _, ok := getTask(st.Key()); !ok {
+			t.Errorf("task created in block %d was dropped", st.CreatedBlock)
+		}
+	}
+	if _, ok := getTask(dropped.Key()); ok {
+		t.Errorf("task created in block %d is still tracked", dropped.CreatedBlock)
+	}
+}
+
+func TestDropIfReorged(t *testing.T) {
+	gone, alive := reorgTestTask(1, 5), reorgTestTask(2, 5)
+	unbound := reorgTestTask(3, 5)
+	unbound.Contract = common.HexToAddress("0x01")
+
+	t.Run("status", func(t *testing.T) {
+		setupReorgTest(t, statusHandler{statuses: map[common.Hash]uint8{alive.TaskID: TaskResponded}}, gone, alive, unbound)
+		for _, st := range []TaskState{gone, alive, unbound, reorgTestTask(4, 5)} {
+			if err := dropIfReorged(context.Background(), st.Key()); err != nil {
+				t.Fatalf("task %s: %v", st.Key(), err)
+			}
+		}
+		if _, ok := getTask(gone.Key()); ok {
+			t.Error("task no longer on chain is still tracked")
+		}
+		for _, st := range []TaskState{alive, unbound} {
+			if _, ok := getTask(st.Key()); !ok {
+				t.Errorf("task %s was dropped", st.Key())
+			}
+		}
+		loaded, _, err := loadTasks()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := loaded[gone.Key()]; ok || len(loaded) != 2 {
+			t.Errorf("stored tasks = %v, want the two remaining ones", slices.Collect(maps.Keys(loaded)))
+		}
+	})
+
+	t.Run("status unavailable", func(t *testing.T) {
+		setupReorgTest(t, statusHandler{err: errors.New("connection refused")}, gone)
+		if err := dropIfReorged(context.Background(), gone.Key()); err == nil {
+			t.Fatal("dropIfReorged() succeeded without the task status")
+		}
+		if _, ok := getTask(gone.Key()); !ok {
+			t.Error("task was dropped without its status")
+		}
+	})
+}
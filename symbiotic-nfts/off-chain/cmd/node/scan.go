@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/go-errors/errors"
+)
+
+// scanChunksPerTick bounds the eth_getLogs requests of a single scan so catching up on a long chain
+// doesn't hold back the rest of the loop. The next tick continues from the checkpoint.
+const scanChunksPerTick = 20
+
+// rangeLimitErrors are lowercase fragments of the eth_getLogs errors hosted providers and node
+// implementations return when the block range or the result set is too large. They are specific
+// enough not to match rate limit errors, which share code -32005 and phrases like "limit exceeded".
+var rangeLimitErrors = []string{
+	"query returned more than",
+	"range is too large",
+	"range too large",
+	"block range is too wide",
+	"exceed maximum block range",
+	"exceeds maximum range limit",
+	"response size exceeded",
+	"response size is larger",
+	"too many results",
+	"results exceed",
+	"query timeout exceeded",
+}
+
+// scan ingests task events of the chain from its checkpoint towards the confirmed head, in chunks
+// of at most the chain's block range. It reports whether the confirmed head was reached.
+func (w *chainWorker) scan(ctx context.Context) (bool, error) {
+	if _, err := w.detectReorg(ctx); err != nil {
+		return false, errors.Errorf("failed to check for reorg: %w", err)
+	}
+
+	endHeader, head, err := confirmedHeader(ctx, w.chainID, w.cli)
+	if err != nil {
+		return false, errors.Errorf("failed to get confirmed block: %w", err)
+	}
+	defer w.recordProgress(head)
+	if endHeader == nil || endHeader.Number.Uint64() < w.nextBlock {
+		return true, nil
+	}
+	end := endHeader.Number.Uint64()
+
+	// A chunk is counted once its logs are fetched, splitting a rejected range doesn't use one up.
+	for chunks := 0; chunks < scanChunksPerTick; {
+		start := w.nextBlock
+		if start > end {
+			return true, nil
+		}
+		to := end
+		if r := w.scanRange(); r > 0 && to-start >= r {
+			to = start + r - 1
+		}
+
+		slog.DebugContext(ctx, "Fetching task events", "chainID", w.chainID, "fromBlock", start, "toBlock", to)
+
+		query := createdEventsQuery(w.chainID)
+		query.FromBlock = new(big.Int).SetUint64(start)
+		query.ToBlock = new(big.Int).SetUint64(to)
+		logs, err := w.cli.FilterLogs(ctx, query)
+		if err != nil {
+			if isRangeLimitError(err) && to > start {
+				w.logRange = max((to-start+1)/2, 1)
+				slog.WarnContext(ctx, "Provider rejected log range, splitting", "chainID", w.chainID, "fromBlock", start, "toBlock", to, "newRange", w.logRange, "err", err)
+				continue
+			}
+			return false, errors.Errorf("failed to filter task events in blocks %d-%d: %w", start, to, err)
+		}
+		chunks++
+		w.growScanRange()
+
+		// The checkpoint stays before the range until all of its tasks are ingested.
+		if err := processNewTasks(ctx, w.chainID, logs); err != nil {
+			return false, errors.Errorf("failed to process task events in blocks %d-%d: %w", start, to, err)
+		}
+
+		header := endHeader
+		if to != end {
+			header, err = w.cli.HeaderByNumber(ctx, new(big.Int).SetUint64(to))
+			if err != nil {
+				return false, errors.Errorf("failed to get header %d: %w", to, err)
+			}
+		}
+		w.advanceCheckpoint(header)
+	}
+	return w.nextBlock > end, nil
+}
+
+// advanceCheckpoint marks everything up to header as scanned.
+func (w *chainWorker) advanceCheckpoint(header *types.Header) {
+	end := header.Number.Uint64()
+	w.nextBlock = end + 1
+	if err := recordScannedBlock(w.chainID, header); err != nil {
+		slog.Error("Failed to record scanned block hash", "chainID", w.chainID, "err", err)
+	}
+	if err := db.PutCheckpoint(w.chainID, end+1); err != nil {
+		slog.Error("Failed to persist block checkpoint", "chainID", w.chainID, "err", err)
+	}
+}
+
+// maxBlockRange is the configured eth_getLogs block range of the chain, 0 meaning unlimited.
+func maxBlockRange(chainID int64) uint64 {
+	if r, ok := chainBlockRanges[chainID]; ok {
+		return r
+	}
+	return cfg.maxBlockRange
+}
+
+func (w *chainWorker) scanRange() uint64 {
+	if w.logRange != 0 {
+		return w.logRange
+	}
+	return maxBlockRange(w.chainID)
+}
+
+// growScanRange doubles a reduced range after a successful request until it's back at the configured limit.
+func (w *chainWorker) growScanRange() {
+	if w.logRange == 0 {
+		return
+	}
+	w.logRange *= 2
+	if limit := maxBlockRange(w.chainID); limit != 0 && w.logRange >= limit {
+		w.logRange = 0
+	}
+}
+
+// isRangeLimitError reports whether an eth_getLogs failure is caused by the size of the request
+// rather than the provider being unavailable, so a smaller range may succeed.
+func isRangeLimitError(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, frag := range rangeLimitErrors {
+		if strings.Contains(msg, frag) {
+			return true
+		}
+	}
+	return false
+}
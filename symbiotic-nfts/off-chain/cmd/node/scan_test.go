@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-errors/errors"
+)
+
+// rpcError is an error response of a JSON-RPC provider.
+type rpcError struct {
+	code int
+	msg  string
+}
+
+func (e rpcError) Error() string  { return e.msg }
+func (e rpcError) ErrorCode() int { return e.code }
+
+func TestIsRangeLimitError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{rpcError{-32005, "query returned more than 10000 results. Try with this block range [0x1, 0x2]."}, true},
+		{errors.New("Log response size exceeded. You can make eth_getLogs requests with up to a 2K block range"), true},
+		{errors.New("block range is too wide"), true},
+		{errors.New("exceed maximum block range: 5000"), true},
+		{errors.New("Requested range exceeds maximum range limit"), true},
+		{errors.New("eth_getLogs range is too large, max is 1k blocks"), true},
+		{errors.Errorf("failed to filter: %w", rpcError{-32000, "query timeout exceeded"}), true},
+		// Rate limits, which a smaller range doesn't help with.
+		{rpcError{-32005, "limit exceeded"}, false},
+		{rpcError{-32005, "project ID request rate exceeded"}, false},
+		{errors.New("rate limit exceeded, retry in 1s"), false},
+		{errors.New("daily request count exceeded, request rate limited"), false},
+		{errors.New("429 Too Many Requests"), false},
+		// Other failures.
+		{errors.New("header not found for block range 100-200"), false},
+		{errors.New("connection refused"), false},
+	}
+	for _, tt := range tests {
+		if got := isRangeLimitError(tt.err); got != tt.want {
+			t.Errorf("isRangeLimitError(%q) = %t, want %t", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestGrowScanRange(t *testing.T) {
+	t.Cleanup(func() { chainBlockRanges = nil })
+	chainBlockRanges = map[int64]uint64{31337: 1000}
+	w := newChainWorker(31337, nil, 0, false)
+	w.growScanRange()
+	if w.logRange != 0 {
+		t.Fatalf("unreduced range grew to %d", w.logRange)
+	}
+	w.logRange = 100
+	for _, want := range []uint64{200, 400, 800, 0} {
+		w.growScanRange()
+		if w.logRange != want {
+			t.Fatalf("range grew to %d, want %d", w.logRange, want)
+		}
+	}
+
+	// Without a configured limit the range keeps growing.
+	chainBlockRanges = nil
+	w.logRange = 1 << 20
+	w.growScanRange()
+	if w.logRange != 1<<21 {
+		t.Fatalf("range grew to %d, want %d", w.logRange, 1<<21)
+	}
+}
+
+// setupScanTest returns a polling worker for a chain of the given length whose checkpoint is at block 0.
+func setupScanTest(t *testing.T, length int) (*fakeChain, *chainWorker) {
+	t.Helper()
+	chain, cli := newFakeChain(t, length)
+	h, err := newHandler(nftOwnershipType, reorgTestContract, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setupReorgTest(t, h)
+	return chain, newChainWorker(31337, cli, 0, false)
+}
+
+// Splitting a rejected range doesn't count towards the chunks of a tick.
+func TestScanSplitsRange(t *testing.T) {
+	chain, w := setupScanTest(t, 100)
+	chain.maxLogRange = 8
+	caughtUp, err := w.scan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !caughtUp || w.nextBlock != 100 {
+		t.Fatalf("scan() caught up %t at block %d, want true at 100", caughtUp, w.nextBlock)
+	}
+	next := uint64(0)
+	for _, r := range chain.logRanges {
+		if r[0] != next || r[1]-r[0]+1 > chain.maxLogRange {
+			t.Fatalf("queried ranges %v", chain.logRanges)
+		}
+		next = r[1] + 1
+	}
+	if len(chain.logRanges) > scanChunksPerTick {
+		t.Fatalf("queried %d ranges in one tick, want at most %d", len(chain.logRanges), scanChunksPerTick)
+	}
+}
+
+// The checkpoint only moves past ranges whose logs were fetched.
+func TestScanCheckpointOnFailure(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(c *fakeChain)
+	}{
+		{"provider error", func(c *fakeChain) { c.logsErr = errors.New("rate limit exceeded") }},
+		{"single block over the limit", func(c *fakeChain) { c.logsErr = errors.New("query returned more than 10000 results") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain, w := setupScanTest(t, 20)
+			if err := db.PutCheckpoint(31337, 0); err != nil {
+				t.Fatal(err)
+			}
+			tt.setup(chain)
+			if _, err := w.scan(context.Background()); err == nil {
+				t.Fatal("scan() succeeded")
+			}
+			checkpoints, err := db.Checkpoints()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if w.nextBlock != 0 || checkpoints[31337] != 0 {
+				t.Fatalf("next block %d, checkpoint %d after a failed scan, want 0", w.nextBlock, checkpoints[31337])
+			}
+			hashes, err := db.BlockHashes(31337)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(hashes) != 0 {
+				t.Fatalf("recorded %d block hashes after a failed scan", len(hashes))
+			}
+		})
+	}
+}
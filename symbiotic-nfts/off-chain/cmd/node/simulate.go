@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/go-errors/errors"
+
+	"sum/internal/store"
+)
+
+// simOutcome is the result of a simulated respondTask call.
+type simOutcome uint8
+
+const (
+	simOK simOutcome = iota
+	// simAlreadyResponded: another operator's response landed first.
+	simAlreadyResponded
+	// simInvalidEpoch: the signing epoch is too old to be verified, the task has to be signed again.
+	simInvalidEpoch
+	// simRejected: the contract rejects the response, e.g. InvalidQuorumSignature. The verdict depends on
+	// the RPC's view of the settlement, so it's retried; the task is only given up once it's responded or expired.
+	simRejected
+	// simUnknown: the call reverted without a recognized error, possibly a transient settlement state.
+	simUnknown
+)
+
+// simRejection records the reverted simulations of a task's response on a chain since the last successful one.
+type simRejection struct {
+	Count int       `json:"count"`
+	Error string    `json:"error"`
+	At    time.Time `json:"at"`
+}
+
+type simResult struct {
+	Outcome simOutcome
+	// Error is the decoded revert, e.g. "InvalidQuorumSignature()".
+	Error string
+}
+
+// simulateResponse runs the task's respondTask on the chain with eth_call from the submitting account
+// and decodes a revert against the contract's custom errors.
+func simulateResponse(ctx context.Context, chainID int64, st TaskState) (simResult, error) {
+	h, ok := targetHandler(st, chainID)
+	if !ok {
+		return simResult{}, errors.Errorf("no contract bound for task %s on chain %d", st.Key(), chainID)
+	}
+	data, err := h.PackRespond(st.TaskID, st.Payload, big.NewInt(st.SigEpoch), st.AggProof)
+	if err != nil {
+		return simResult{}, errors.Errorf("failed to pack respondTask: %w", err)
+	}
+	to := h.Address()
+	_, err = appClients[chainID].CallContract(ctx, ethereum.CallMsg{
+		From: txManagers[chainID].From(),
+		To:   &to,
+		Data: data,
+	}, nil)
+	if err == nil {
+		return simResult{Outcome: simOK}, nil
+	}
+	revert, ok := revertData(err)
+	if !ok {
+		return simResult{}, errors.Errorf("failed to simulate respondTask: %w", err)
+	}
+
+	name, desc := decodeRevert(h.ABI(), revert)
+	switch name {
+	case "AlreadyResponded":
+		return simResult{Outcome: simAlreadyResponded, Error: desc}, nil
+	case "InvalidVerifyingEpoch":
+		return simResult{Outcome: simInvalidEpoch, Error: desc}, nil
+	case "InvalidQuorumSignature", "UnsupportedPayloadVersion":
+		return simResult{Outcome: simRejected, Error: desc}, nil
+	default:
+		return simResult{Outcome: simUnknown, Error: desc}, nil
+	}
+}
+
+// revertData extracts the revert data of a failed eth_call, false if the call didn't revert.
+func revertData(err error) ([]byte, bool) {
+	var dataErr rpc.DataError
+	if errors.As(err, &dataErr) {
+		if s, ok := dataErr.ErrorData().(string); ok {
+			if data, err := hexutil.Decode(s); err == nil {
+				return data, true
+			}
+		}
+	}
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == 3 {
+		return nil, true
+	}
+	if strings.Contains(err.Error(), "execution reverted") {
+		return nil, true
+	}
+	return nil, false
+}
+
+// panicSelector is the selector of the Panic(uint256) reverts of failed assertions and arithmetic.
+var panicSelector = crypto.Keccak256([]byte("Panic(uint256)"))[:4]
+
+// decodeRevert matches revert data against the contract's custom errors. It returns the error name,
+// empty if none matched, and a description with the decoded arguments, an Error(string) message,
+// a Panic(uint256) reason or the raw data.
+func decodeRevert(contractABI *abi.ABI, data []byte) (string, string) {
+	if len(data) < 4 {
+		return "", "execution reverted"
+	}
+	for _, e := range contractABI.Errors {
+		if !bytes.Equal(e.ID[:4], data[:4]) {
+			continue
+		}
+		args, err := e.Inputs.Unpack(data[4:])
+		if err != nil {
+			return e.Name, e.Name + "(?)"
+		}
+		var sb strings.Builder
+		sb.WriteString(e.Name + "(")
+		for i, a := range args {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(formatArg(a))
+		}
+		sb.WriteString(")")
+		return e.Name, sb.String()
+	}
+	if reason, err := abi.UnpackRevert(data); err == nil {
+		if bytes.Equal(data[:4], panicSelector) {
+			return "", "Panic(" + reason + ")"
+		}
+		return "", "Error(" + reason + ")"
+	}
+	return "", hexutil.Encode(data)
+}
+
+func formatArg(a any) string {
+	switch v := a.(type) {
+	case common.Address:
+		return v.Hex()
+	case []byte:
+		return hexutil.Encode(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// preflightResponse simulates the task's response on the chain and applies the outcome.
+// It reports whether the response should be broadcast now.
+func preflightResponse(ctx context.Context, chainID int64, st TaskState) (bool, error) {
+	res, err := simulateResponse(ctx, chainID, st)
+	if err != nil {
+		return false, err
+	}
+	switch res.Outcome {
+	case simOK:
+		if _, ok := st.Rejections[chainID]; ok {
+			updateTask(st.Key(), func(s *TaskState) { delete(s.Rejections, chainID) })
+		}
+		return true, nil
+	case simAlreadyResponded:
+		slog.InfoContext(ctx, "Task already responded, not submitting", "taskID", st.TaskID, "chainID", chainID)
+		updateTask(st.Key(), func(s *TaskState) { s.Statuses[chainID] = TaskResponded })
+	case simInvalidEpoch:
+		slog.WarnContext(ctx, "Signing epoch no longer verifiable, re-signing", "taskID", st.TaskID, "chainID", chainID, "epoch", st.SigEpoch)
+		updateTask(st.Key(), func(s *TaskState) { retireSignature(s, st.SigRequestHash, retiredInvalidEpoch) })
+	case simRejected:
+		n := recordRejection(st.Key(), chainID, res.Error)
+		slog.ErrorContext(ctx, "Response rejected by simulation, retrying later", "taskID", st.TaskID, "chainID", chainID, "err", res.Error, "rejections", n)
+	default:
+		n := recordRejection(st.Key(), chainID, res.Error)
+		slog.WarnContext(ctx, "Response simulation reverted, retrying later", "taskID", st.TaskID, "chainID", chainID, "err", res.Error, "rejections", n)
+	}
+	return false, nil
+}
+
+// recordRejection counts a reverted simulation of the task's response on the chain and returns the count.
+func recordRejection(key store.TaskKey, chainID int64, reason string) int {
+	var n int
+	updateTask(key, func(s *TaskState) {
+		if s.Rejections == nil {
+			s.Rejections = map[int64]simRejection{}
+		}
+		n = s.Rejections[chainID].Count + 1
+		s.Rejections[chainID] = simRejection{Count: n, Error: reason, At: time.Now()}
+	})
+	return n
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"sum/internal/contracts"
+)
+
+func packRevert(t *testing.T, signature, typ string, arg any) []byte {
+	t.Helper()
+	data := crypto.Keccak256([]byte(signature))[:4]
+	if typ == "" {
+		return data
+	}
+	ty, err := abi.NewType(typ, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	packed, err := abi.Arguments{{Type: ty}}.Pack(arg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append(data, packed...)
+}
+
+func TestDecodeRevert(t *testing.T) {
+	nftABI, err := contracts.NftOwnershipTaskMetaData.GetAbi()
+	if err != nil {
+		t.Fatal(err)
+	}
+	unsupported := packRevert(t, "UnsupportedPayloadVersion(uint8)", "uint8", uint8(7))
+	errorString := packRevert(t, "Error(string)", "string", "not the owner")
+
+	tests := []struct {
+		name     string
+		data     []byte
+		wantName string
+		wantDesc string
+	}{
+		{"no data", nil, "", "execution reverted"},
+		{"shorter than a selector", []byte{0x01, 0x02, 0x03}, "", "execution reverted"},
+		{"custom error without arguments", packRevert(t, "AlreadyResponded()", "", nil), "AlreadyResponded", "AlreadyResponded()"},
+		{"custom error with arguments", unsupported, "UnsupportedPayloadVersion", "UnsupportedPayloadVersion(7)"},
+		{"custom error with truncated arguments", unsupported[:20], "UnsupportedPayloadVersion", "UnsupportedPayloadVersion(?)"},
+		{"Error(string)", errorString, "", "Error(not the owner)"},
+		{"Panic(uint256)", packRevert(t, "Panic(uint256)", "uint256", big.NewInt(0x11)), "", "Panic(arithmetic underflow or overflow)"},
+		{"Panic with unknown code", packRevert(t, "Panic(uint256)", "uint256", big.NewInt(0x99)), "", "Panic(unknown panic code: 0x99)"},
+		{"malformed Error(string)", errorString[:10], "", "0x08c379a0000000000000"},
+		{"unknown selector", []byte{0xde, 0xad, 0xbe, 0xef, 0x01}, "", "0xdeadbeef01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, desc := decodeRevert(nftABI, tt.data)
+			if name != tt.wantName || desc != tt.wantDesc {
+				t.Fatalf("decodeRevert() = %q, %q, want %q, %q", name, desc, tt.wantName, tt.wantDesc)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/go-errors/errors"
+
+	"sum/internal/store"
+)
+
+// subscriptionState tracks how TaskCreated events of an app chain are ingested.
+type subscriptionState uint8
+
+const (
+	// subNone: no live subscription, the chain is polled with FilterLogs.
+	subNone subscriptionState = iota
+	// subBackfill: subscribed, but the next poll still has to cover the gap since the last checkpoint.
+	subBackfill
+	// subLive: subscribed and backfilled, events come from the subscription only.
+	subLive
+)
+
+const (
+	subRetryMinBackoff = time.Second
+	subRetryMaxBackoff = time.Minute
+)
+
+type subEventKind uint8
+
+const (
+	subConnected subEventKind = iota
+	subDisconnected
+	subUnsupported
+	subLog
+)
+
+type subEvent struct {
+	kind subEventKind
+	log  types.Log
+}
+
+func isWebsocketURL(url string) bool {
+	return strings.HasPrefix(url, "ws://") || strings.HasPrefix(url, "wss://")
+}
+
+// watch keeps a task event log subscription open on the chain and forwards its events to the worker.
+// It resubscribes with backoff after failures and gives up if the endpoint doesn't support subscriptions.
+func (w *chainWorker) watch(ctx context.Context) {
+	chainID := w.chainID
+	query := createdEventsQuery(chainID)
+	backoff := subRetryMinBackoff
+	for {
+		logs := make(chan types.Log, 64)
+		sub, err := w.cli.SubscribeFilterLogs(ctx, query, logs)
+		if err != nil {
+			if errors.Is(err, rpc.ErrNotificationsUnsupported) {
+				slog.WarnContext(ctx, "Subscriptions unsupported, falling back to polling", "chainID", chainID, "err", err)
+				w.send(ctx, subEvent{kind: subUnsupported})
+				return
+			}
+			slog.WarnContext(ctx, "Failed to subscribe to TaskCreated events", "chainID", chainID, "retryIn", backoff, "err", err)
+			if !sleepCtx(ctx, backoff) {
+				return
+			}
+			backoff = min(backoff*2, subRetryMaxBackoff)
+			continue
+		}
+		backoff = subRetryMinBackoff
+		slog.InfoContext(ctx, "Subscribed to TaskCreated events", "chainID", chainID, "contracts", len(query.Addresses))
+		w.send(ctx, subEvent{kind: subConnected})
+
+	recv:
+		for {
+			select {
+			case lg := <-logs:
+				w.send(ctx, subEvent{kind: subLog, log: lg})
+			case err := <-sub.Err():
+				slog.WarnContext(ctx, "TaskCreated subscription dropped", "chainID", chainID, "err", err)
+				break recv
+			case <-ctx.Done():
+				sub.Unsubscribe()
+				return
+			}
+		}
+		sub.Unsubscribe()
+		w.send(ctx, subEvent{kind: subDisconnected})
+		if !sleepCtx(ctx, backoff) {
+			return
+		}
+	}
+}
+
+func (w *chainWorker) send(ctx context.Context, ev subEvent) {
+	select {
+	case w.events <- ev:
+	case <-ctx.Done():
+	}
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// handleSubEvent applies a subscription event in the worker goroutine.
+// While a chain is not live, polling covers its events and subscription logs are only buffered
+// so nothing between the backfill range and the subscription start is lost.
+func (w *chainWorker) handleSubEvent(ctx context.Context, ev subEvent) {
+	switch ev.kind {
+	case subConnected:
+		// The poll following a (re)connect backfills everything since the checkpoint.
+		w.sub = subBackfill
+	case subDisconnected, subUnsupported:
+		w.sub = subNone
+		w.pending = nil
+	case subLog:
+		if w.sub == subNone {
+			return
+		}
+		if ev.log.Removed {
+			w.handleRemovedLog(ctx, ev.log)
+			return
+		}
+		w.pending = append(w.pending, ev.log)
+	}
+}
+
+// handleRemovedLog reconciles a task whose creation log was dropped by a reorg.
+func (w *chainWorker) handleRemovedLog(ctx context.Context, lg types.Log) {
+	chainID := w.chainID
+	pending := w.pending[:0]
+	for _, p := range w.pending {
+		if p.BlockHash != lg.BlockHash || p.Index != lg.Index {
+			pending = append(pending, p)
+		}
+	}
+	w.pending = pending
+
+	h, ok := handlers[chainID][lg.Address]
+	if !ok || len(lg.Topics) == 0 || lg.Topics[0] != h.CreatedEventID() {
+		return
+	}
+	taskID, _, err := h.DecodeCreated(lg)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to decode removed task event", "chainID", chainID, "contract", lg.Address, "tx", lg.TxHash, "err", err)
+		return
+	}
+	key := store.TaskKey{ChainID: chainID, Contract: lg.Address, TaskID: taskID}
+	if err := dropIfReorged(ctx, key); err != nil {
+		slog.ErrorContext(ctx, "Failed to reconcile removed task", "task", key, "err", err)
+	}
+}
+
+// processSubscribedLogs ingests buffered subscription logs that reached the confirmed head
+// and moves the checkpoint forward without querying logs. If a reorg rewinds the checkpoint or
+// ingesting fails, the chain falls back to the backfill scan from the checkpoint.
+func (w *chainWorker) processSubscribedLogs(ctx context.Context) error {
+	rewound, err := w.detectReorg(ctx)
+	if err != nil {
+		return errors.Errorf("failed to check for reorg: %w", err)
+	}
+	if rewound {
+		// The subscription doesn't deliver the logs of the new branch below its head again.
+		w.sub = subBackfill
+		return nil
+	}
+	endHeader, head, err := confirmedHeader(ctx, w.chainID, w.cli)
+	if err != nil {
+		return errors.Errorf("failed to get confirmed block: %w", err)
+	}
+	defer w.recordProgress(head)
+	if endHeader == nil || endHeader.Number.Uint64() < w.nextBlock {
+		return nil
+	}
+	end := endHeader.Number.Uint64()
+
+	var ready, rest []types.Log
+	for _, lg := range w.pending {
+		switch {
+		case lg.BlockNumber < w.nextBlock:
+			// Already covered by a scan.
+		case lg.BlockNumber <= end:
+			ready = append(ready, lg)
+		default:
+			rest = append(rest, lg)
+		}
+	}
+	w.pending = rest
+
+	if err := processNewTasks(ctx, w.chainID, ready); err != nil {
+		// Leave the checkpoint and go back to scanning, which ingests the range again with FilterLogs.
+		w.sub = subBackfill
+		return errors.Errorf("failed to process subscribed task events up to block %d: %w", end, err)
+	}
+	w.advanceCheckpoint(endHeader)
+	return nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+// A reorg seen while ingesting from the subscription sends the chain back to scanning the rewound blocks.
+func TestProcessSubscribedLogsReorg(t *testing.T) {
+	for _, reorg := range []bool{false, true} {
+		name := "no reorg"
+		if reorg {
+			name = "reorg"
+		}
+		t.Run(name, func(t *testing.T) {
+			chain, cli := newFakeChain(t, 13)
+			setupReorgTest(t, statusHandler{})
+			for _, n := range []uint64{4, 8} {
+				if err := recordScannedBlock(31337, chain.header(n)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if reorg {
+				chain.fork(6, 13, 1)
+			}
+
+			w := newChainWorker(31337, cli, 9, false)
+			w.sub = subLive
+			if err := w.ingest(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			wantSub, wantNext := subLive, uint64(13)
+			if reorg {
+				wantSub, wantNext = subBackfill, 5
+			}
+			if w.sub != wantSub || w.nextBlock != wantNext {
+				t.Fatalf("subscription state %d, next block %d, want %d, %d", w.sub, w.nextBlock, wantSub, wantNext)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/go-errors/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"sum/internal/store"
+)
+
+// A task is traced across ticks and restarts as one trace whose IDs are derived from the task key:
+// the root span is written when the task is ingested and every later step is a child of it.
+// The trace ID only depends on the task, so the spans of all operators handling it end up in one trace,
+// while the root span ID also depends on the node so that the operators' root spans don't collide.
+
+const taskRootSpan = "task"
+
+// traceNode identifies this node in the root span IDs: its operator address, or its signer's address without one.
+var traceNode common.Address
+
+var tracer = otel.Tracer("sum/cmd/node")
+
+// setupTracing exports spans to --otlp-endpoint. Without an endpoint the global no-op provider stays in place.
+// The returned function flushes and stops the exporter.
+func setupTracing(ctx context.Context) (func(context.Context) error, error) {
+	if cfg.otlpEndpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+	opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(cfg.otlpEndpoint)}
+	if cfg.otlpInsecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+	exp, err := otlptracegrpc.New(ctx, opts...)
+	if err != nil {
+		return nil, errors.Errorf("failed to create OTLP exporter for %s: %w", cfg.otlpEndpoint, err)
+	}
+	attrs := []attribute.KeyValue{attribute.String("service.name", "sum-node")}
+	if cfg.operatorAddress != "" {
+		attrs = append(attrs, attribute.String("service.instance.id", cfg.operatorAddress))
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(resource.NewSchemaless(attrs...)),
+		sdktrace.WithIDGenerator(taskIDGenerator{}),
+		// Spans outside of a task, e.g. relay calls of the election or the probes, are not exported.
+		sdktrace.WithSampler(sdktrace.ParentBased(taskRootSampler{})),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return tp.Shutdown, nil
+}
+
+// taskTraceIDs derives the trace ID of the task and the ID of the root span node writes for it.
+func taskTraceIDs(key store.TaskKey, node common.Address) (trace.TraceID, trace.SpanID) {
+	var (
+		traceID trace.TraceID
+		spanID  trace.SpanID
+	)
+	copy(traceID[:], crypto.Keccak256(key.Bytes()))
+	copy(spanID[:], crypto.Keccak256(key.Bytes(), node.Bytes()))
+	return traceID, spanID
+}
+
+func taskAttributes(st TaskState) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("task.key", st.Key().String()),
+		attribute.String("task.id", st.TaskID.Hex()),
+		attribute.Int64("task.chain_id", st.ChainID),
+		attribute.String("task.contract", st.Contract.Hex()),
+		attribute.String("task.type", st.Type),
+	}
+}
+
+type taskRootKey struct{}
+
+// startTaskRoot starts the root span of a newly ingested task, backdated to the task's creation block.
+func startTaskRoot(ctx context.Context, st TaskState) (context.Context, trace.Span) {
+	ctx = context.WithValue(ctx, taskRootKey{}, st.Key())
+	opts := []trace.SpanStartOption{
+		trace.WithNewRoot(),
+		trace.WithAttributes(taskAttributes(st)...),
+		trace.WithAttributes(attribute.Int64("task.created_block", int64(st.CreatedBlock))),
+	}
+	if !st.CreatedAt.IsZero() {
+		opts = append(opts, trace.WithTimestamp(st.CreatedAt))
+	}
+	return tracer.Start(ctx, taskRootSpan, opts...)
+}
+
+// startTaskSpan starts a span of one step of the task under the task's root span.
+func startTaskSpan(ctx context.Context, st TaskState, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	traceID, spanID := taskTraceIDs(st.Key(), traceNode)
+	parent := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+	ctx = trace.ContextWithRemoteSpanContext(ctx, parent)
+	return tracer.Start(ctx, name, trace.WithAttributes(append(taskAttributes(st), attrs...)...))
+}
+
+// endSpan records err, if any, as the span's status and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// taskIDGenerator assigns the derived IDs to task root spans and random IDs to everything else.
+type taskIDGenerator struct{}
+
+func (taskIDGenerator) NewIDs(ctx context.Context) (trace.TraceID, trace.SpanID) {
+	if key, ok := ctx.Value(taskRootKey{}).(store.TaskKey); ok {
+		return taskTraceIDs(key, traceNode)
+	}
+	var (
+		traceID trace.TraceID
+		spanID  trace.SpanID
+	)
+	_, _ = rand.Read(traceID[:])
+	_, _ = rand.Read(spanID[:])
+	return traceID, spanID
+}
+
+func (taskIDGenerator) NewSpanID(context.Context, trace.TraceID) trace.SpanID {
+	var spanID trace.SpanID
+	_, _ = rand.Read(spanID[:])
+	return spanID
+}
+
+// taskRootSampler samples the task root spans only, so that traces are always about one task.
+type taskRootSampler struct{}
+
+func (taskRootSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	decision := sdktrace.Drop
+	if p.Name == taskRootSpan {
+		decision = sdktrace.RecordAndSample
+	}
+	return sdktrace.SamplingResult{Decision: decision, Tracestate: trace.SpanContextFromContext(p.ParentContext).TraceState()}
+}
+
+func (taskRootSampler) Description() string { return "TaskRootSampler" }
+
+// tracingShutdownTimeout bounds the flush of buffered spans on exit.
+const tracingShutdownTimeout = 5 * time.Second
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"sum/internal/store"
+)
+
+func TestTaskTraceIDs(t *testing.T) {
+	key := store.TaskKey{ChainID: 31337, Contract: common.HexToAddress("0x5FbDB2315678afecb367f032d93F642f64180aa3"), TaskID: common.HexToHash("0x01")}
+	other := key
+	other.TaskID = common.HexToHash("0x02")
+
+	traceA, spanA := taskTraceIDs(key, opA)
+	traceB, spanB := taskTraceIDs(key, opB)
+	if traceA != traceB {
+		t.Errorf("operators derive different trace IDs %s and %s for one task", traceA, traceB)
+	}
+	if spanA == spanB {
+		t.Errorf("operators derive the same root span ID %s", spanA)
+	}
+	if trace, span := taskTraceIDs(key, opA); trace != traceA || span != spanA {
+		t.Errorf("IDs are not deterministic: %s/%s, then %s/%s", traceA, spanA, trace, span)
+	}
+	if trace, _ := taskTraceIDs(other, opA); trace == traceA {
+		t.Errorf("tasks %s and %s share trace ID %s", key, other, trace)
+	}
+}
+
+// The steps of a task are children of the root span the node wrote when it ingested the task.
+func TestTaskSpansParentedToRoot(t *testing.T) {
+	exp := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSyncer(exp),
+		sdktrace.WithIDGenerator(taskIDGenerator{}),
+		sdktrace.WithSampler(sdktrace.ParentBased(taskRootSampler{})),
+	)
+	otel.SetTracerProvider(tp)
+	t.Cleanup(func() {
+		_ = tp.Shutdown(context.Background())
+		traceNode = common.Address{}
+	})
+	traceNode = opA
+
+	st := TaskState{ChainID: 31337, Contract: common.HexToAddress("0x5FbDB2315678afecb367f032d93F642f64180aa3"), TaskID: common.HexToHash("0x01")}
+	_, root := startTaskRoot(context.Background(), st)
+	root.End()
+	_, step := startTaskSpan(context.Background(), st, "sign")
+	step.End()
+	// Spans outside of a task are dropped.
+	_, other := tracer.Start(context.Background(), "probe")
+	other.End()
+
+	spans := exp.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("exported %d spans, want the root and its step", len(spans))
+	}
+	traceID, spanID := taskTraceIDs(st.Key(), opA)
+	if got := spans[0].SpanContext; got.TraceID() != traceID || got.SpanID() != spanID {
+		t.Errorf("root span IDs = %s/%s, want %s/%s", got.TraceID(), got.SpanID(), traceID, spanID)
+	}
+	if got := spans[1]; got.SpanContext.TraceID() != traceID || got.Parent.SpanID() != spanID {
+		t.Errorf("step span is in trace %s under %s, want trace %s under %s", got.SpanContext.TraceID(), got.Parent.SpanID(), traceID, spanID)
+	}
+}
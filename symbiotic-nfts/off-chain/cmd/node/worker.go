@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/go-errors/errors"
+)
+
+// Chain worker health states
+const (
+	chainHealthy = "healthy"
+	// chainDegraded: recent ticks failed, but fewer than the error budget in a row.
+	chainDegraded = "degraded"
+	// chainUnhealthy: the error budget is exhausted, the worker keeps retrying with backoff.
+	chainUnhealthy = "unhealthy"
+)
+
+const (
+	workerTickInterval = time.Second
+	workerMinBackoff   = time.Second
+)
+
+// workers runs one chainWorker per app chain. The map itself is not modified after startup.
+var workers map[int64]*chainWorker
+
+type chainHealth struct {
+	Status              string    `json:"status"`
+	ConsecutiveFailures int       `json:"consecutiveFailures"`
+	LastError           string    `json:"lastError,omitempty"`
+	LastSuccess         time.Time `json:"lastSuccess"`
+	Restarts            int       `json:"restarts"`
+}
+
+// chainProgress is how far the worker ingested the chain as of its last tick.
+type chainProgress struct {
+	Head      uint64    `json:"head"`
+	Scanned   uint64    `json:"scanned"`
+	Lag       uint64    `json:"lag"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// chainWorker ingests task events of all contracts of one app chain and drives every task's status and response on it.
+// Failures of a chain only slow down its own worker.
+type chainWorker struct {
+	chainID int64
+	cli     *ethclient.Client
+	ws      bool
+	events  chan subEvent
+
+	// Ingestion state, only touched by the worker goroutine.
+	nextBlock uint64
+	sub       subscriptionState
+	pending   []types.Log
+	// logRange is the eth_getLogs block range in use after a provider rejected a larger one, 0 if not reduced.
+	logRange uint64
+	// balanceAt is when the responder balance gauge was last refreshed.
+	balanceAt time.Time
+
+	mu       sync.Mutex
+	health   chainHealth
+	progress chainProgress
+}
+
+func newChainWorker(chainID int64, cli *ethclient.Client, nextBlock uint64, ws bool) *chainWorker {
+	return &chainWorker{
+		chainID:   chainID,
+		cli:       cli,
+		ws:        ws,
+		events:    make(chan subEvent, 256),
+		nextBlock: nextBlock,
+		health:    chainHealth{Status: chainHealthy},
+	}
+}
+
+// supervise runs the worker until ctx is done and restarts it with backoff if it panics.
+func (w *chainWorker) supervise(ctx context.Context) {
+	if w.ws {
+		go w.watch(ctx)
+	}
+	for {
+		err := w.runSafe(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		slog.ErrorContext(ctx, "Chain worker crashed, restarting", "chainID", w.chainID, "err", err)
+		w.mu.Lock()
+		w.health.Restarts++
+		w.mu.Unlock()
+		w.recordFailure(ctx, err)
+		if !sleepCtx(ctx, w.backoff()) {
+			return
+		}
+	}
+}
+
+func (w *chainWorker) runSafe(ctx context.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Errorf("panic: %v", r)
+		}
+	}()
+	w.run(ctx)
+	return nil
+}
+
+func (w *chainWorker) run(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+			if err := w.tick(ctx); err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				w.recordFailure(ctx, err)
+				timer.Reset(w.backoff())
+				continue
+			}
+			w.recordSuccess(ctx)
+			timer.Reset(workerTickInterval)
+		case ev := <-w.events:
+			w.handleSubEvent(ctx, ev)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (w *chainWorker) tick(ctx context.Context) error {
+	if err := w.ingest(ctx); err != nil {
+		return err
+	}
+	if err := processTasks(ctx, w.chainID); err != nil {
+		return err
+	}
+	w.refreshBalance(ctx)
+	return nil
+}
+
+// ingest reads new task events either from the live subscription or by scanning logs.
+func (w *chainWorker) ingest(ctx context.Context) error {
+	if w.sub == subLive {
+		return w.processSubscribedLogs(ctx)
+	}
+	caughtUp, err := w.scan(ctx)
+	if err != nil {
+		return err
+	}
+	if caughtUp && w.sub == subBackfill {
+		w.sub = subLive
+		slog.InfoContext(ctx, "Backfill done, ingesting from subscription", "chainID", w.chainID, "nextBlock", w.nextBlock)
+	}
+	return nil
+}
+
+func (w *chainWorker) recordFailure(ctx context.Context, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.health.ConsecutiveFailures++
+	w.health.LastError = err.Error()
+	chainTickFailures.WithLabelValues(chainLabel(w.chainID)).Inc()
+	if w.health.ConsecutiveFailures < cfg.chainErrorBudget {
+		w.health.Status = chainDegraded
+		slog.WarnContext(ctx, "Chain worker tick failed", "chainID", w.chainID, "failures", w.health.ConsecutiveFailures, "err", err)
+		return
+	}
+	if w.health.Status != chainUnhealthy {
+		slog.ErrorContext(ctx, "Chain marked unhealthy", "chainID", w.chainID, "failures", w.health.ConsecutiveFailures, "err", err)
+	}
+	w.health.Status = chainUnhealthy
+}
+
+func (w *chainWorker) recordSuccess(ctx context.Context) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.health.Status != chainHealthy {
+		slog.InfoContext(ctx, "Chain recovered", "chainID", w.chainID, "failures", w.health.ConsecutiveFailures)
+	}
+	w.health = chainHealth{Status: chainHealthy, LastSuccess: time.Now(), Restarts: w.health.Restarts}
+}
+
+// backoff is the delay before the next tick, growing exponentially with consecutive failures.
+func (w *chainWorker) backoff() time.Duration {
+	w.mu.Lock()
+	failures := w.health.ConsecutiveFailures
+	w.mu.Unlock()
+	if failures == 0 {
+		return workerTickInterval
+	}
+	d := workerMinBackoff
+	for i := 1; i < failures && d < cfg.chainMaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, cfg.chainMaxBackoff)
+}
+
+// recordProgress publishes the tagged head and the last ingested block of the chain.
+func (w *chainWorker) recordProgress(head uint64) {
+	if w.nextBlock == 0 {
+		return
+	}
+	scanned := w.nextBlock - 1
+	recordScanProgress(w.chainID, head, scanned)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.progress = chainProgress{Head: head, Scanned: scanned, Lag: head - min(head, scanned), UpdatedAt: time.Now()}
+}
+
+// Progress returns the scan progress recorded by the last tick.
+func (w *chainWorker) Progress() chainProgress {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.progress
+}
+
+// Health returns a snapshot of the worker's health state.
+func (w *chainWorker) Health() chainHealth {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.health
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-errors/errors"
+)
+
+func setWorkerConfig(t *testing.T, budget int, maxBackoff time.Duration) {
+	t.Helper()
+	cfg.chainErrorBudget, cfg.chainMaxBackoff = budget, maxBackoff
+	t.Cleanup(func() { cfg = config{} })
+}
+
+// panickingWorker returns a worker whose first tick panics: it has no chain client.
+func panickingWorker(t *testing.T) *chainWorker {
+	t.Helper()
+	setupReorgTest(t, statusHandler{})
+	return newChainWorker(31337, nil, 0, false)
+}
+
+func TestRunSafeRecoversPanic(t *testing.T) {
+	w := panickingWorker(t)
+	err := w.runSafe(context.Background())
+	if err == nil || !strings.HasPrefix(err.Error(), "panic: ") {
+		t.Fatalf("runSafe() = %v, want the recovered panic", err)
+	}
+}
+
+func TestSuperviseRestartsAfterPanic(t *testing.T) {
+	setWorkerConfig(t, 3, time.Minute)
+	w := panickingWorker(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		w.supervise(ctx)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for w.Health().Restarts == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("worker was not restarted")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("supervise() did not return after cancellation")
+	}
+	h := w.Health()
+	if h.Status != chainDegraded || h.ConsecutiveFailures == 0 || !strings.HasPrefix(h.LastError, "panic: ") {
+		t.Fatalf("health after a crash = %+v", h)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	setWorkerConfig(t, 3, 30*time.Second)
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, workerTickInterval},
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{5, 16 * time.Second},
+		{6, 30 * time.Second},
+		{100, 30 * time.Second},
+	}
+	w := newChainWorker(31337, nil, 0, false)
+	for _, tt := range tests {
+		w.health.ConsecutiveFailures = tt.failures
+		if got := w.backoff(); got != tt.want {
+			t.Errorf("backoff() after %d failures = %s, want %s", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestRecordFailure(t *testing.T) {
+	setWorkerConfig(t, 3, time.Minute)
+	w := newChainWorker(31337, nil, 0, false)
+	w.health.Restarts = 2
+	for i, want := range []string{chainDegraded, chainDegraded, chainUnhealthy, chainUnhealthy} {
+		msg := fmt.Sprintf("failure %d", i+1)
+		w.recordFailure(context.Background(), errors.New(msg))
+		h := w.Health()
+		if h.Status != want || h.ConsecutiveFailures != i+1 || h.LastError != msg {
+			t.Fatalf("health after %d failures = %+v, want %s", i+1, h, want)
+		}
+	}
+	w.recordSuccess(context.Background())
+	if h := w.Health(); h.Status != chainHealthy || h.ConsecutiveFailures != 0 || h.LastError != "" || h.Restarts != 2 || h.LastSuccess.IsZero() {
+		t.Fatalf("health after a success = %+v", h)
+	}
+}
@@ -37,21 +37,25 @@ type NftOwnershipTaskRequest struct {
 	Owner        common.Address
 	CheckedBlock uint64
 	Standard     uint8
+	MinAmount    *big.Int
 	Nonce        *big.Int
 	CreatedAt    *big.Int
 }
 
 // NftOwnershipTaskResponse is an auto generated low-level Go binding around an user-defined struct.
 type NftOwnershipTaskResponse struct {
-	AnsweredAt    *big.Int
-	IsOwner       bool
-	OwnerAtBlock  common.Address
-	ObservedBlock uint64
+	AnsweredAt     *big.Int
+	IsOwner        bool
+	OwnerAtBlock   common.Address
+	ObservedBlock  uint64
+	Reason         uint8
+	ObservedAmount *big.Int
+	Decimals       uint8
 }
 
 // NftOwnershipTaskMetaData contains all meta data concerning the NftOwnershipTask contract.
 var NftOwnershipTaskMetaData = &bind.MetaData{
-	ABI: "[{\"type\":\"constructor\",\"inputs\":[{\"name\":\"_settlement\",\"type\":\"address\",\"internalType\":\"address\"}],\"stateMutability\":\"nonpayable\"},{\"type\":\"function\",\"name\":\"TASK_EXPIRY\",\"inputs\":[],\"outputs\":[{\"name\":\"\",\"type\":\"uint32\",\"internalType\":\"uint32\"}],\"stateMutability\":\"view\"},{\"type\":\"function\",\"name\":\"createTask\",\"inputs\":[{\"name\":\"chainId\",\"type\":\"uint256\",\"internalType\":\"uint256\"},{\"name\":\"collection\",\"type\":\"address\",\"internalType\":\"address\"},{\"name\":\"tokenId\",\"type\":\"uint256\",\"internalType\":\"uint256\"},{\"name\":\"owner\",\"type\":\"address\",\"internalType\":\"address\"},{\"name\":\"checkedBlock\",\"type\":\"uint64\",\"internalType\":\"uint64\"},{\"name\":\"standard\",\"type\":\"uint8\",\"internalType\":\"enumNftOwnershipTask.Standard\"}],\"outputs\":[{\"name\":\"taskId\",\"type\":\"bytes32\",\"internalType\":\"bytes32\"}],\"stateMutability\":\"nonpayable\"},{\"type\":\"function\",\"name\":\"getTaskStatus\",\"inputs\":[{\"name\":\"taskId\",\"type\":\"bytes32\",\"internalType\":\"bytes32\"}],\"outputs\":[{\"name\":\"\",\"type\":\"uint8\",\"internalType\":\"enumNftOwnershipTask.TaskStatus\"}],\"stateMutability\":\"view\"},{\"type\":\"function\",\"name\":\"nonce\",\"inputs\":[],\"outputs\":[{\"name\":\"\",\"type\":\"uint256\",\"internalType\":\"uint256\"}],\"stateMutability\":\"view\"},{\"type\":\"function\",\"name\":\"respondTask\",\"inputs\":[{\"name\":\"taskId\",\"type\":\"bytes32\",\"internalType\":\"bytes32\"},{\"name\":\"payload\",\"type\":\"bytes\",\"internalType\":\"bytes\"},{\"name\":\"epoch\",\"type\":\"uint48\",\"internalType\":\"uint48\"},{\"name\":\"proof\",\"type\":\"bytes\",\"internalType\":\"bytes\"}],\"outputs\":[],\"stateMutability\":\"nonpayable\"},{\"type\":\"function\",\"name\":\"responses\",\"inputs\":[{\"name\":\"\",\"type\":\"bytes32\",\"internalType\":\"bytes32\"}],\"outputs\":[{\"name\":\"answeredAt\",\"type\":\"uint48\",\"internalType\":\"uint48\"},{\"name\":\"isOwner\",\"type\":\"bool\",\"internalType\":\"bool\"},{\"name\":\"ownerAtBlock\",\"type\":\"address\",\"internalType\":\"address\"},{\"name\":\"observedBlock\",\"type\":\"uint64\",\"internalType\":\"uint64\"}],\"stateMutability\":\"view\"},{\"type\":\"function\",\"name\":\"settlement\",\"inputs\":[],\"outputs\":[{\"name\":\"\",\"type\":\"address\",\"internalType\":\"contractISettlement\"}],\"stateMutability\":\"view\"},{\"type\":\"function\",\"name\":\"tasks\",\"inputs\":[{\"name\":\"\",\"type\":\"bytes32\",\"internalType\":\"bytes32\"}],\"outputs\":[{\"name\":\"chainId\",\"type\":\"uint256\",\"internalType\":\"uint256\"},{\"name\":\"collection\",\"type\":\"address\",\"internalType\":\"address\"},{\"name\":\"tokenId\",\"type\":\"uint256\",\"internalType\":\"uint256\"},{\"name\":\"owner\",\"type\":\"address\",\"internalType\":\"address\"},{\"name\":\"checkedBlock\",\"type\":\"uint64\",\"internalType\":\"uint64\"},{\"name\":\"standard\",\"type\":\"uint8\",\"internalType\":\"enumNftOwnershipTask.Standard\"},{\"name\":\"nonce\",\"type\":\"uint256\",\"internalType\":\"uint256\"},{\"name\":\"createdAt\",\"type\":\"uint48\",\"internalType\":\"uint48\"}],\"stateMutability\":\"view\"},{\"type\":\"event\",\"name\":\"CreateTask\",\"inputs\":[{\"name\":\"taskId\",\"type\":\"bytes32\",\"indexed\":true,\"internalType\":\"bytes32\"},{\"name\":\"req\",\"type\":\"tuple\",\"indexed\":false,\"internalType\":\"structNftOwnershipTask.Request\",\"components\":[{\"name\":\"chainId\",\"type\":\"uint256\",\"internalType\":\"uint256\"},{\"name\":\"collection\",\"type\":\"address\",\"internalType\":\"address\"},{\"name\":\"tokenId\",\"type\":\"uint256\",\"internalType\":\"uint256\"},{\"name\":\"owner\",\"type\":\"address\",\"internalType\":\"address\"},{\"name\":\"checkedBlock\",\"type\":\"uint64\",\"internalType\":\"uint64\"},{\"name\":\"standard\",\"type\":\"uint8\",\"internalType\":\"enumNftOwnershipTask.Standard\"},{\"name\":\"nonce\",\"type\":\"uint256\",\"internalType\":\"uint256\"},{\"name\":\"createdAt\",\"type\":\"uint48\",\"internalType\":\"uint48\"}]}],\"anonymous\":false},{\"type\":\"event\",\"name\":\"RespondTask\",\"inputs\":[{\"name\":\"taskId\",\"type\":\"bytes32\",\"indexed\":true,\"internalType\":\"bytes32\"},{\"name\":\"response\",\"type\":\"tuple\",\"indexed\":false,\"internalType\":\"structNftOwnershipTask.Response\",\"components\":[{\"name\":\"answeredAt\",\"type\":\"uint48\",\"internalType\":\"uint48\"},{\"name\":\"isOwner\",\"type\":\"bool\",\"internalType\":\"bool\"},{\"name\":\"ownerAtBlock\",\"type\":\"address\",\"internalType\":\"address\"},{\"name\":\"observedBlock\",\"type\":\"uint64\",\"internalType\":\"uint64\"}]}],\"anonymous\":false},{\"type\":\"event\",\"name\":\"TaskCreated\",\"inputs\":[{\"name\":\"taskId\",\"type\":\"bytes32\",\"indexed\":true,\"internalType\":\"bytes32\"},{\"name\":\"req\",\"type\":\"tuple\",\"indexed\":false,\"internalType\":\"structNftOwnershipTask.Request\",\"components\":[{\"name\":\"chainId\",\"type\":\"uint256\",\"internalType\":\"uint256\"},{\"name\":\"collection\",\"type\":\"address\",\"internalType\":\"address\"},{\"name\":\"tokenId\",\"type\":\"uint256\",\"internalType\":\"uint256\"},{\"name\":\"owner\",\"type\":\"address\",\"internalType\":\"address\"},{\"name\":\"checkedBlock\",\"type\":\"uint64\",\"internalType\":\"uint64\"},{\"name\":\"standard\",\"type\":\"uint8\",\"internalType\":\"enumNftOwnershipTask.Standard\"},{\"name\":\"nonce\",\"type\":\"uint256\",\"internalType\":\"uint256\"},{\"name\":\"createdAt\",\"type\":\"uint48\",\"internalType\":\"uint48\"}]}],\"anonymous\":false},{\"type\":\"error\",\"name\":\"AlreadyResponded\",\"inputs\":[]},{\"type\":\"error\",\"name\":\"InvalidQuorumSignature\",\"inputs\":[]},{\"type\":\"error\",\"name\":\"InvalidVerifyingEpoch\",\"inputs\":[]}]",
+	ABI: "[{\"type\":\"constructor\",\"inputs\":[{\"name\":\"_settlement\",\"type\":\"address\",\"internalType\":\"address\"}],\"stateMutability\":\"nonpayable\"},{\"type\":\"function\",\"name\":\"PAYLOAD_VERSION\",\"inputs\":[],\"outputs\":[{\"name\":\"\",\"type\":\"uint8\",\"internalType\":\"uint8\"}],\"stateMutability\":\"view\"},{\"type\":\"function\",\"name\":\"TASK_EXPIRY\",\"inputs\":[],\"outputs\":[{\"name\":\"\",\"type\":\"uint32\",\"internalType\":\"uint32\"}],\"stateMutability\":\"view\"},{\"type\":\"function\",\"name\":\"createTask\",\"inputs\":[{\"name\":\"chainId\",\"type\":\"uint256\",\"internalType\":\"uint256\"},{\"name\":\"collection\",\"type\":\"address\",\"internalType\":\"address\"},{\"name\":\"tokenId\",\"type\":\"uint256\",\"internalType\":\"uint256\"},{\"name\":\"owner\",\"type\":\"address\",\"internalType\":\"address\"},{\"name\":\"checkedBlock\",\"type\":\"uint64\",\"internalType\":\"uint64\"},{\"name\":\"standard\",\"type\":\"uint8\",\"internalType\":\"enumNftOwnershipTask.Standard\"},{\"name\":\"minAmount\",\"type\":\"uint256\",\"internalType\":\"uint256\"}],\"outputs\":[{\"name\":\"taskId\",\"type\":\"bytes32\",\"internalType\":\"bytes32\"}],\"stateMutability\":\"nonpayable\"},{\"type\":\"function\",\"name\":\"getTaskStatus\",\"inputs\":[{\"name\":\"taskId\",\"type\":\"bytes32\",\"internalType\":\"bytes32\"}],\"outputs\":[{\"name\":\"\",\"type\":\"uint8\",\"internalType\":\"enumNftOwnershipTask.TaskStatus\"}],\"stateMutability\":\"view\"},{\"type\":\"function\",\"name\":\"nonce\",\"inputs\":[],\"outputs\":[{\"name\":\"\",\"type\":\"uint256\",\"internalType\":\"uint256\"}],\"stateMutability\":\"view\"},{\"type\":\"function\",\"name\":\"respondTask\",\"inputs\":[{\"name\":\"taskId\",\"type\":\"bytes32\",\"internalType\":\"bytes32\"},{\"name\":\"payload\",\"type\":\"bytes\",\"internalType\":\"bytes\"},{\"name\":\"epoch\",\"type\":\"uint48\",\"internalType\":\"uint48\"},{\"name\":\"proof\",\"type\":\"bytes\",\"internalType\":\"bytes\"}],\"outputs\":[],\"stateMutability\":\"nonpayable\"},{\"type\":\"function\",\"name\":\"responses\",\"inputs\":[{\"name\":\"\",\"type\":\"bytes32\",\"internalType\":\"bytes32\"}],\"outputs\":[{\"name\":\"answeredAt\",\"type\":\"uint48\",\"internalType\":\"uint48\"},{\"name\":\"isOwner\",\"type\":\"bool\",\"internalType\":\"bool\"},{\"name\":\"ownerAtBlock\",\"type\":\"address\",\"internalType\":\"address\"},{\"name\":\"observedBlock\",\"type\":\"uint64\",\"internalType\":\"uint64\"},{\"name\":\"reason\",\"type\":\"uint8\",\"internalType\":\"enumNftOwnershipTask.Reason\"},{\"name\":\"observedAmount\",\"type\":\"uint256\",\"internalType\":\"uint256\"},{\"name\":\"decimals\",\"type\":\"uint8\",\"internalType\":\"uint8\"}],\"stateMutability\":\"view\"},{\"type\":\"function\",\"name\":\"settlement\",\"inputs\":[],\"outputs\":[{\"name\":\"\",\"type\":\"address\",\"internalType\":\"contractISettlement\"}],\"stateMutability\":\"view\"},{\"type\":\"function\",\"name\":\"tasks\",\"inputs\":[{\"name\":\"\",\"type\":\"bytes32\",\"internalType\":\"bytes32\"}],\"outputs\":[{\"name\":\"chainId\",\"type\":\"uint256\",\"internalType\":\"uint256\"},{\"name\":\"collection\",\"type\":\"address\",\"internalType\":\"address\"},{\"name\":\"tokenId\",\"type\":\"uint256\",\"internalType\":\"uint256\"},{\"name\":\"owner\",\"type\":\"address\",\"internalType\":\"address\"},{\"name\":\"checkedBlock\",\"type\":\"uint64\",\"internalType\":\"uint64\"},{\"name\":\"standard\",\"type\":\"uint8\",\"internalType\":\"enumNftOwnershipTask.Standard\"},{\"name\":\"minAmount\",\"type\":\"uint256\",\"internalType\":\"uint256\"},{\"name\":\"nonce\",\"type\":\"uint256\",\"internalType\":\"uint256\"},{\"name\":\"createdAt\",\"type\":\"uint48\",\"internalType\":\"uint48\"}],\"stateMutability\":\"view\"},{\"type\":\"event\",\"name\":\"CreateTask\",\"inputs\":[{\"name\":\"taskId\",\"type\":\"bytes32\",\"indexed\":true,\"internalType\":\"bytes32\"},{\"name\":\"req\",\"type\":\"tuple\",\"indexed\":false,\"internalType\":\"structNftOwnershipTask.Request\",\"components\":[{\"name\":\"chainId\",\"type\":\"uint256\",\"internalType\":\"uint256\"},{\"name\":\"collection\",\"type\":\"address\",\"internalType\":\"address\"},{\"name\":\"tokenId\",\"type\":\"uint256\",\"internalType\":\"uint256\"},{\"name\":\"owner\",\"type\":\"address\",\"internalType\":\"address\"},{\"name\":\"checkedBlock\",\"type\":\"uint64\",\"internalType\":\"uint64\"},{\"name\":\"standard\",\"type\":\"uint8\",\"internalType\":\"enumNftOwnershipTask.Standard\"},{\"name\":\"minAmount\",\"type\":\"uint256\",\"internalType\":\"uint256\"},{\"name\":\"nonce\",\"type\":\"uint256\",\"internalType\":\"uint256\"},{\"name\":\"createdAt\",\"type\":\"uint48\",\"internalType\":\"uint48\"}]}],\"anonymous\":false},{\"type\":\"event\",\"name\":\"RespondTask\",\"inputs\":[{\"name\":\"taskId\",\"type\":\"bytes32\",\"indexed\":true,\"internalType\":\"bytes32\"},{\"name\":\"response\",\"type\":\"tuple\",\"indexed\":false,\"internalType\":\"structNftOwnershipTask.Response\",\"components\":[{\"name\":\"answeredAt\",\"type\":\"uint48\",\"internalType\":\"uint48\"},{\"name\":\"isOwner\",\"type\":\"bool\",\"internalType\":\"bool\"},{\"name\":\"ownerAtBlock\",\"type\":\"address\",\"internalType\":\"address\"},{\"name\":\"observedBlock\",\"type\":\"uint64\",\"internalType\":\"uint64\"},{\"name\":\"reason\",\"type\":\"uint8\",\"internalType\":\"enumNftOwnershipTask.Reason\"},{\"name\":\"observedAmount\",\"type\":\"uint256\",\"internalType\":\"uint256\"},{\"name\":\"decimals\",\"type\":\"uint8\",\"internalType\":\"uint8\"}]}],\"anonymous\":false},{\"type\":\"event\",\"name\":\"TaskCreated\",\"inputs\":[{\"name\":\"taskId\",\"type\":\"bytes32\",\"indexed\":true,\"internalType\":\"bytes32\"},{\"name\":\"req\",\"type\":\"tuple\",\"indexed\":false,\"internalType\":\"structNftOwnershipTask.Request\",\"components\":[{\"name\":\"chainId\",\"type\":\"uint256\",\"internalType\":\"uint256\"},{\"name\":\"collection\",\"type\":\"address\",\"internalType\":\"address\"},{\"name\":\"tokenId\",\"type\":\"uint256\",\"internalType\":\"uint256\"},{\"name\":\"owner\",\"type\":\"address\",\"internalType\":\"address\"},{\"name\":\"checkedBlock\",\"type\":\"uint64\",\"internalType\":\"uint64\"},{\"name\":\"standard\",\"type\":\"uint8\",\"internalType\":\"enumNftOwnershipTask.Standard\"},{\"name\":\"minAmount\",\"type\":\"uint256\",\"internalType\":\"uint256\"},{\"name\":\"nonce\",\"type\":\"uint256\",\"internalType\":\"uint256\"},{\"name\":\"createdAt\",\"type\":\"uint48\",\"internalType\":\"uint48\"}]}],\"anonymous\":false},{\"type\":\"error\",\"name\":\"AlreadyResponded\",\"inputs\":[]},{\"type\":\"error\",\"name\":\"InvalidQuorumSignature\",\"inputs\":[]},{\"type\":\"error\",\"name\":\"InvalidVerifyingEpoch\",\"inputs\":[]},{\"type\":\"error\",\"name\":\"UnsupportedPayloadVersion\",\"inputs\":[{\"name\":\"version\",\"type\":\"uint8\",\"internalType\":\"uint8\"}]}]",
 }
 
 // NftOwnershipTaskABI is the input ABI used to generate the binding from.
@@ -200,6 +204,37 @@ func (_NftOwnershipTask *NftOwnershipTaskTransactorRaw) Transact(opts *bind.Tran
 	return _NftOwnershipTask.Contract.contract.Transact(opts, method, params...)
 }
 
+// PAYLOADVERSION is a free data retrieval call binding the contract method 0x5265ece6.
+//
+// Solidity: function PAYLOAD_VERSION() view returns(uint8)
+func (_NftOwnershipTask *NftOwnershipTaskCaller) PAYLOADVERSION(opts *bind.CallOpts) (uint8, error) {
+	var out []interface{}
+	err := _NftOwnershipTask.contract.Call(opts, &out, "PAYLOAD_VERSION")
+
+	if err != nil {
+		return *new(uint8), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new(uint8)).(*uint8)
+
+	return out0, err
+
+}
+
+// PAYLOADVERSION is a free data retrieval call binding the contract method 0x5265ece6.
+//
+// Solidity: function PAYLOAD_VERSION() view returns(uint8)
+func (_NftOwnershipTask *NftOwnershipTaskSession) PAYLOADVERSION() (uint8, error) {
+	return _NftOwnershipTask.Contract.PAYLOADVERSION(&_NftOwnershipTask.CallOpts)
+}
+
+// PAYLOADVERSION is a free data retrieval call binding the contract method 0x5265ece6.
+//
+// Solidity: function PAYLOAD_VERSION() view returns(uint8)
+func (_NftOwnershipTask *NftOwnershipTaskCallerSession) PAYLOADVERSION() (uint8, error) {
+	return _NftOwnershipTask.Contract.PAYLOADVERSION(&_NftOwnershipTask.CallOpts)
+}
+
 // TASKEXPIRY is a free data retrieval call binding the contract method 0x240697b6.
 //
 // Solidity: function TASK_EXPIRY() view returns(uint32)
@@ -295,21 +330,27 @@ func (_NftOwnershipTask *NftOwnershipTaskCallerSession) Nonce() (*big.Int, error
 
 // Responses is a free data retrieval call binding the contract method 0x72164a6c.
 //
-// Solidity: function responses(bytes32 ) view returns(uint48 answeredAt, bool isOwner, address ownerAtBlock, uint64 observedBlock)
+// Solidity: function responses(bytes32 ) view returns(uint48 answeredAt, bool isOwner, address ownerAtBlock, uint64 observedBlock, uint8 reason, uint256 observedAmount, uint8 decimals)
 func (_NftOwnershipTask *NftOwnershipTaskCaller) Responses(opts *bind.CallOpts, arg0 [32]byte) (struct {
-	AnsweredAt    *big.Int
-	IsOwner       bool
-	OwnerAtBlock  common.Address
-	ObservedBlock uint64
+	AnsweredAt     *big.Int
+	IsOwner        bool
+	OwnerAtBlock   common.Address
+	ObservedBlock  uint64
+	Reason         uint8
+	ObservedAmount *big.Int
+	Decimals       uint8
 }, error) {
 	var out []interface{}
 	err := _NftOwnershipTask.contract.Call(opts, &out, "responses", arg0)
 
 	outstruct := new(struct {
-		AnsweredAt    *big.Int
-		IsOwner       bool
-		OwnerAtBlock  common.Address
-		ObservedBlock uint64
+		AnsweredAt     *big.Int
+		IsOwner        bool
+		OwnerAtBlock   common.Address
+		ObservedBlock  uint64
+		Reason         uint8
+		ObservedAmount *big.Int
+		Decimals       uint8
 	})
 	if err != nil {
 		return *outstruct, err
@@ -319,6 +360,9 @@ func (_NftOwnershipTask *NftOwnershipTaskCaller) Responses(opts *bind.CallOpts,
 	outstruct.IsOwner = *abi.ConvertType(out[1], new(bool)).(*bool)
 	outstruct.OwnerAtBlock = *abi.ConvertType(out[2], new(common.Address)).(*common.Address)
 	outstruct.ObservedBlock = *abi.ConvertType(out[3], new(uint64)).(*uint64)
+	outstruct.Reason = *abi.ConvertType(out[4], new(uint8)).(*uint8)
+	outstruct.ObservedAmount = *abi.ConvertType(out[5], new(*big.Int)).(**big.Int)
+	outstruct.Decimals = *abi.ConvertType(out[6], new(uint8)).(*uint8)
 
 	return *outstruct, err
 
@@ -326,24 +370,30 @@ func (_NftOwnershipTask *NftOwnershipTaskCaller) Responses(opts *bind.CallOpts,
 
 // Responses is a free data retrieval call binding the contract method 0x72164a6c.
 //
-// Solidity: function responses(bytes32 ) view returns(uint48 answeredAt, bool isOwner, address ownerAtBlock, uint64 observedBlock)
+// Solidity: function responses(bytes32 ) view returns(uint48 answeredAt, bool isOwner, address ownerAtBlock, uint64 observedBlock, uint8 reason, uint256 observedAmount, uint8 decimals)
 func (_NftOwnershipTask *NftOwnershipTaskSession) Responses(arg0 [32]byte) (struct {
-	AnsweredAt    *big.Int
-	IsOwner       bool
-	OwnerAtBlock  common.Address
-	ObservedBlock uint64
+	AnsweredAt     *big.Int
+	IsOwner        bool
+	OwnerAtBlock   common.Address
+	ObservedBlock  uint64
+	Reason         uint8
+	ObservedAmount *big.Int
+	Decimals       uint8
 }, error) {
 	return _NftOwnershipTask.Contract.Responses(&_NftOwnershipTask.CallOpts, arg0)
 }
 
 // Responses is a free data retrieval call binding the contract method 0x72164a6c.
 //
-// Solidity: function responses(bytes32 ) view returns(uint48 answeredAt, bool isOwner, address ownerAtBlock, uint64 observedBlock)
+// Solidity: function responses(bytes32 ) view returns(uint48 answeredAt, bool isOwner, address ownerAtBlock, uint64 observedBlock, uint8 reason, uint256 observedAmount, uint8 decimals)
 func (_NftOwnershipTask *NftOwnershipTaskCallerSession) Responses(arg0 [32]byte) (struct {
-	AnsweredAt    *big.Int
-	IsOwner       bool
-	OwnerAtBlock  common.Address
-	ObservedBlock uint64
+	AnsweredAt     *big.Int
+	IsOwner        bool
+	OwnerAtBlock   common.Address
+	ObservedBlock  uint64
+	Reason         uint8
+	ObservedAmount *big.Int
+	Decimals       uint8
 }, error) {
 	return _NftOwnershipTask.Contract.Responses(&_NftOwnershipTask.CallOpts, arg0)
 }
@@ -381,7 +431,7 @@ func (_NftOwnershipTask *NftOwnershipTaskCallerSession) Settlement() (common.Add
 
 // Tasks is a free data retrieval call binding the contract method 0xe579f500.
 //
-// Solidity: function tasks(bytes32 ) view returns(uint256 chainId, address collection, uint256 tokenId, address owner, uint64 checkedBlock, uint8 standard, uint256 nonce, uint48 createdAt)
+// Solidity: function tasks(bytes32 ) view returns(uint256 chainId, address collection, uint256 tokenId, address owner, uint64 checkedBlock, uint8 standard, uint256 minAmount, uint256 nonce, uint48 createdAt)
 func (_NftOwnershipTask *NftOwnershipTaskCaller) Tasks(opts *bind.CallOpts, arg0 [32]byte) (struct {
 	ChainId      *big.Int
 	Collection   common.Address
@@ -389,6 +439,7 @@ func (_NftOwnershipTask *NftOwnershipTaskCaller) Tasks(opts *bind.CallOpts, arg0
 	Owner        common.Address
 	CheckedBlock uint64
 	Standard     uint8
+	MinAmount    *big.Int
 	Nonce        *big.Int
 	CreatedAt    *big.Int
 }, error) {
@@ -402,6 +453,7 @@ func (_NftOwnershipTask *NftOwnershipTaskCaller) Tasks(opts *bind.CallOpts, arg0
 		Owner        common.Address
 		CheckedBlock uint64
 		Standard     uint8
+		MinAmount    *big.Int
 		Nonce        *big.Int
 		CreatedAt    *big.Int
 	})
@@ -415,8 +467,9 @@ func (_NftOwnershipTask *NftOwnershipTaskCaller) Tasks(opts *bind.CallOpts, arg0
 	outstruct.Owner = *abi.ConvertType(out[3], new(common.Address)).(*common.Address)
 	outstruct.CheckedBlock = *abi.ConvertType(out[4], new(uint64)).(*uint64)
 	outstruct.Standard = *abi.ConvertType(out[5], new(uint8)).(*uint8)
-	outstruct.Nonce = *abi.ConvertType(out[6], new(*big.Int)).(**big.Int)
-	outstruct.CreatedAt = *abi.ConvertType(out[7], new(*big.Int)).(**big.Int)
+	outstruct.MinAmount = *abi.ConvertType(out[6], new(*big.Int)).(**big.Int)
+	outstruct.Nonce = *abi.ConvertType(out[7], new(*big.Int)).(**big.Int)
+	outstruct.CreatedAt = *abi.ConvertType(out[8], new(*big.Int)).(**big.Int)
 
 	return *outstruct, err
 
@@ -424,7 +477,7 @@ func (_NftOwnershipTask *NftOwnershipTaskCaller) Tasks(opts *bind.CallOpts, arg0
 
 // Tasks is a free data retrieval call binding the contract method 0xe579f500.
 //
-// Solidity: function tasks(bytes32 ) view returns(uint256 chainId, address collection, uint256 tokenId, address owner, uint64 checkedBlock, uint8 standard, uint256 nonce, uint48 createdAt)
+// Solidity: function tasks(bytes32 ) view returns(uint256 chainId, address collection, uint256 tokenId, address owner, uint64 checkedBlock, uint8 standard, uint256 minAmount, uint256 nonce, uint48 createdAt)
 func (_NftOwnershipTask *NftOwnershipTaskSession) Tasks(arg0 [32]byte) (struct {
 	ChainId      *big.Int
 	Collection   common.Address
@@ -432,6 +485,7 @@ func (_NftOwnershipTask *NftOwnershipTaskSession) Tasks(arg0 [32]byte) (struct {
 	Owner        common.Address
 	CheckedBlock uint64
 	Standard     uint8
+	MinAmount    *big.Int
 	Nonce        *big.Int
 	CreatedAt    *big.Int
 }, error) {
@@ -440,7 +494,7 @@ func (_NftOwnershipTask *NftOwnershipTaskSession) Tasks(arg0 [32]byte) (struct {
 
 // Tasks is a free data retrieval call binding the contract method 0xe579f500.
 //
-// Solidity: function tasks(bytes32 ) view returns(uint256 chainId, address collection, uint256 tokenId, address owner, uint64 checkedBlock, uint8 standard, uint256 nonce, uint48 createdAt)
+// Solidity: function tasks(bytes32 ) view returns(uint256 chainId, address collection, uint256 tokenId, address owner, uint64 checkedBlock, uint8 standard, uint256 minAmount, uint256 nonce, uint48 createdAt)
 func (_NftOwnershipTask *NftOwnershipTaskCallerSession) Tasks(arg0 [32]byte) (struct {
 	ChainId      *big.Int
 	Collection   common.Address
@@ -448,31 +502,32 @@ func (_NftOwnershipTask *NftOwnershipTaskCallerSession) Tasks(arg0 [32]byte) (st
 	Owner        common.Address
 	CheckedBlock uint64
 	Standard     uint8
+	MinAmount    *big.Int
 	Nonce        *big.Int
 	CreatedAt    *big.Int
 }, error) {
 	return _NftOwnershipTask.Contract.Tasks(&_NftOwnershipTask.CallOpts, arg0)
 }
 
-// CreateTask is a paid mutator transaction binding the contract method 0x4017c17f.
+// CreateTask is a paid mutator transaction binding the contract method 0x44ab8bf9.
 //
-// Solidity: function createTask(uint256 chainId, address collection, uint256 tokenId, address owner, uint64 checkedBlock, uint8 standard) returns(bytes32 taskId)
-func (_NftOwnershipTask *NftOwnershipTaskTransactor) CreateTask(opts *bind.TransactOpts, chainId *big.Int, collection common.Address, tokenId *big.Int, owner common.Address, checkedBlock uint64, standard uint8) (*types.Transaction, error) {
-	return _NftOwnershipTask.contract.Transact(opts, "createTask", chainId, collection, tokenId, owner, checkedBlock, standard)
+// Solidity: function createTask(uint256 chainId, address collection, uint256 tokenId, address owner, uint64 checkedBlock, uint8 standard, uint256 minAmount) returns(bytes32 taskId)
+func (_NftOwnershipTask *NftOwnershipTaskTransactor) CreateTask(opts *bind.TransactOpts, chainId *big.Int, collection common.Address, tokenId *big.Int, owner common.Address, checkedBlock uint64, standard uint8, minAmount *big.Int) (*types.Transaction, error) {
+	return _NftOwnershipTask.contract.Transact(opts, "createTask", chainId, collection, tokenId, owner, checkedBlock, standard, minAmount)
 }
 
-// CreateTask is a paid mutator transaction binding the contract method 0x4017c17f.
+// CreateTask is a paid mutator transaction binding the contract method 0x44ab8bf9.
 //
-// Solidity: function createTask(uint256 chainId, address collection, uint256 tokenId, address owner, uint64 checkedBlock, uint8 standard) returns(bytes32 taskId)
-func (_NftOwnershipTask *NftOwnershipTaskSession) CreateTask(chainId *big.Int, collection common.Address, tokenId *big.Int, owner common.Address, checkedBlock uint64, standard uint8) (*types.Transaction, error) {
-	return _NftOwnershipTask.Contract.CreateTask(&_NftOwnershipTask.TransactOpts, chainId, collection, tokenId, owner, checkedBlock, standard)
+// Solidity: function createTask(uint256 chainId, address collection, uint256 tokenId, address owner, uint64 checkedBlock, uint8 standard, uint256 minAmount) returns(bytes32 taskId)
+func (_NftOwnershipTask *NftOwnershipTaskSession) CreateTask(chainId *big.Int, collection common.Address, tokenId *big.Int, owner common.Address, checkedBlock uint64, standard uint8, minAmount *big.Int) (*types.Transaction, error) {
+	return _NftOwnershipTask.Contract.CreateTask(&_NftOwnershipTask.TransactOpts, chainId, collection, tokenId, owner, checkedBlock, standard, minAmount)
 }
 
-// CreateTask is a paid mutator transaction binding the contract method 0x4017c17f.
+// CreateTask is a paid mutator transaction binding the contract method 0x44ab8bf9.
 //
-// Solidity: function createTask(uint256 chainId, address collection, uint256 tokenId, address owner, uint64 checkedBlock, uint8 standard) returns(bytes32 taskId)
-func (_NftOwnershipTask *NftOwnershipTaskTransactorSession) CreateTask(chainId *big.Int, collection common.Address, tokenId *big.Int, owner common.Address, checkedBlock uint64, standard uint8) (*types.Transaction, error) {
-	return _NftOwnershipTask.Contract.CreateTask(&_NftOwnershipTask.TransactOpts, chainId, collection, tokenId, owner, checkedBlock, standard)
+// Solidity: function createTask(uint256 chainId, address collection, uint256 tokenId, address owner, uint64 checkedBlock, uint8 standard, uint256 minAmount) returns(bytes32 taskId)
+func (_NftOwnershipTask *NftOwnershipTaskTransactorSession) CreateTask(chainId *big.Int, collection common.Address, tokenId *big.Int, owner common.Address, checkedBlock uint64, standard uint8, minAmount *big.Int) (*types.Transaction, error) {
+	return _NftOwnershipTask.Contract.CreateTask(&_NftOwnershipTask.TransactOpts, chainId, collection, tokenId, owner, checkedBlock, standard, minAmount)
 }
 
 // RespondTask is a paid mutator transaction binding the contract method 0xc2ea2bf3.
@@ -570,9 +625,9 @@ type NftOwnershipTaskCreateTask struct {
 	Raw    types.Log // Blockchain specific contextual infos
 }
 
-// FilterCreateTask is a free log retrieval operation binding the contract event 0x313874bd5b1cda73bb443bd62cf625061ba13ebe53f553359ff50ce05cacc5a8.
+// FilterCreateTask is a free log retrieval operation binding the contract event 0x9e4a5c5eb8948f27263850260af19aa02bf9c45aa9d48a4b4fb057cb62fd35bb.
 //
-// Solidity: event CreateTask(bytes32 indexed taskId, (uint256,address,uint256,address,uint64,uint8,uint256,uint48) req)
+// Solidity: event CreateTask(bytes32 indexed taskId, (uint256,address,uint256,address,uint64,uint8,uint256,uint256,uint48) req)
 func (_NftOwnershipTask *NftOwnershipTaskFilterer) FilterCreateTask(opts *bind.FilterOpts, taskId [][32]byte) (*NftOwnershipTaskCreateTaskIterator, error) {
 
 	var taskIdRule []interface{}
@@ -587,9 +642,9 @@ func (_NftOwnershipTask *NftOwnershipTaskFilterer) FilterCreateTask(opts *bind.F
 	return &NftOwnershipTaskCreateTaskIterator{contract: _NftOwnershipTask.contract, event: "CreateTask", logs: logs, sub: sub}, nil
 }
 
-// WatchCreateTask is a free log subscription operation binding the contract event 0x313874bd5b1cda73bb443bd62cf625061ba13ebe53f553359ff50ce05cacc5a8.
+// WatchCreateTask is a free log subscription operation binding the contract event 0x9e4a5c5eb8948f27263850260af19aa02bf9c45aa9d48a4b4fb057cb62fd35bb.
 //
-// Solidity: event CreateTask(bytes32 indexed taskId, (uint256,address,uint256,address,uint64,uint8,uint256,uint48) req)
+// Solidity: event CreateTask(bytes32 indexed taskId, (uint256,address,uint256,address,uint64,uint8,uint256,uint256,uint48) req)
 func (_NftOwnershipTask *NftOwnershipTaskFilterer) WatchCreateTask(opts *bind.WatchOpts, sink chan<- *NftOwnershipTaskCreateTask, taskId [][32]byte) (event.Subscription, error) {
 
 	var taskIdRule []interface{}
@@ -629,9 +684,9 @@ func (_NftOwnershipTask *NftOwnershipTaskFilterer) WatchCreateTask(opts *bind.Wa
 	}), nil
 }
 
-// ParseCreateTask is a log parse operation binding the contract event 0x313874bd5b1cda73bb443bd62cf625061ba13ebe53f553359ff50ce05cacc5a8.
+// ParseCreateTask is a log parse operation binding the contract event 0x9e4a5c5eb8948f27263850260af19aa02bf9c45aa9d48a4b4fb057cb62fd35bb.
 //
-// Solidity: event CreateTask(bytes32 indexed taskId, (uint256,address,uint256,address,uint64,uint8,uint256,uint48) req)
+// Solidity: event CreateTask(bytes32 indexed taskId, (uint256,address,uint256,address,uint64,uint8,uint256,uint256,uint48) req)
 func (_NftOwnershipTask *NftOwnershipTaskFilterer) ParseCreateTask(log types.Log) (*NftOwnershipTaskCreateTask, error) {
 	event := new(NftOwnershipTaskCreateTask)
 	if err := _NftOwnershipTask.contract.UnpackLog(event, "CreateTask", log); err != nil {
@@ -715,9 +770,9 @@ type NftOwnershipTaskRespondTask struct {
 	Raw      types.Log // Blockchain specific contextual infos
 }
 
-// FilterRespondTask is a free log retrieval operation binding the contract event 0x0bf426223476d58f384d98805bf2570c769b82ec6e2ccda8886bbf5e3c09f98e.
+// FilterRespondTask is a free log retrieval operation binding the contract event 0x30f7c2ef7b673028ea6839b90453ff386c34aa9cffbbc3c36b41f2e3555756ae.
 //
-// Solidity: event RespondTask(bytes32 indexed taskId, (uint48,bool,address,uint64) response)
+// Solidity: event RespondTask(bytes32 indexed taskId, (uint48,bool,address,uint64,uint8,uint256,uint8) response)
 func (_NftOwnershipTask *NftOwnershipTaskFilterer) FilterRespondTask(opts *bind.FilterOpts, taskId [][32]byte) (*NftOwnershipTaskRespondTaskIterator, error) {
 
 	var taskIdRule []interface{}
@@ -732,9 +787,9 @@ func (_NftOwnershipTask *NftOwnershipTaskFilterer) FilterRespondTask(opts *bind.
 	return &NftOwnershipTaskRespondTaskIterator{contract: _NftOwnershipTask.contract, event: "RespondTask", logs: logs, sub: sub}, nil
 }
 
-// WatchRespondTask is a free log subscription operation binding the contract event 0x0bf426223476d58f384d98805bf2570c769b82ec6e2ccda8886bbf5e3c09f98e.
+// WatchRespondTask is a free log subscription operation binding the contract event 0x30f7c2ef7b673028ea6839b90453ff386c34aa9cffbbc3c36b41f2e3555756ae.
 //
-// Solidity: event RespondTask(bytes32 indexed taskId, (uint48,bool,address,uint64) response)
+// Solidity: event RespondTask(bytes32 indexed taskId, (uint48,bool,address,uint64,uint8,uint256,uint8) response)
 func (_NftOwnershipTask *NftOwnershipTaskFilterer) WatchRespondTask(opts *bind.WatchOpts, sink chan<- *NftOwnershipTaskRespondTask, taskId [][32]byte) (event.Subscription, error) {
 
 	var taskIdRule []interface{}
@@ -774,9 +829,9 @@ func (_NftOwnershipTask *NftOwnershipTaskFilterer) WatchRespondTask(opts *bind.W
 	}), nil
 }
 
-// ParseRespondTask is a log parse operation binding the contract event 0x0bf426223476d58f384d98805bf2570c769b82ec6e2ccda8886bbf5e3c09f98e.
+// ParseRespondTask is a log parse operation binding the contract event 0x30f7c2ef7b673028ea6839b90453ff386c34aa9cffbbc3c36b41f2e3555756ae.
 //
-// Solidity: event RespondTask(bytes32 indexed taskId, (uint48,bool,address,uint64) response)
+// Solidity: event RespondTask(bytes32 indexed taskId, (uint48,bool,address,uint64,uint8,uint256,uint8) response)
 func (_NftOwnershipTask *NftOwnershipTaskFilterer) ParseRespondTask(log types.Log) (*NftOwnershipTaskRespondTask, error) {
 	event := new(NftOwnershipTaskRespondTask)
 	if err := _NftOwnershipTask.contract.UnpackLog(event, "RespondTask", log); err != nil {
@@ -860,9 +915,9 @@ type NftOwnershipTaskTaskCreated struct {
 	Raw    types.Log // Blockchain specific contextual infos
 }
 
-// FilterTaskCreated is a free log retrieval operation binding the contract event 0x7a37329df301808b8ac3daca67b67243924444f5a64e02bb10a901c69579d63f.
+// FilterTaskCreated is a free log retrieval operation binding the contract event 0xc056bc4f2f57c2d277bc36e76066e7c26a06b41ac9e98b906cbae2edf12c292e.
 //
-// Solidity: event TaskCreated(bytes32 indexed taskId, (uint256,address,uint256,address,uint64,uint8,uint256,uint48) req)
+// Solidity: event TaskCreated(bytes32 indexed taskId, (uint256,address,uint256,address,uint64,uint8,uint256,uint256,uint48) req)
 func (_NftOwnershipTask *NftOwnershipTaskFilterer) FilterTaskCreated(opts *bind.FilterOpts, taskId [][32]byte) (*NftOwnershipTaskTaskCreatedIterator, error) {
 
 	var taskIdRule []interface{}
@@ -877,9 +932,9 @@ func (_NftOwnershipTask *NftOwnershipTaskFilterer) FilterTaskCreated(opts *bind.
 	return &NftOwnershipTaskTaskCreatedIterator{contract: _NftOwnershipTask.contract, event: "TaskCreated", logs: logs, sub: sub}, nil
 }
 
-// WatchTaskCreated is a free log subscription operation binding the contract event 0x7a37329df301808b8ac3daca67b67243924444f5a64e02bb10a901c69579d63f.
+// WatchTaskCreated is a free log subscription operation binding the contract event 0xc056bc4f2f57c2d277bc36e76066e7c26a06b41ac9e98b906cbae2edf12c292e.
 //
-// Solidity: event TaskCreated(bytes32 indexed taskId, (uint256,address,uint256,address,uint64,uint8,uint256,uint48) req)
+// Solidity: event TaskCreated(bytes32 indexed taskId, (uint256,address,uint256,address,uint64,uint8,uint256,uint256,uint48) req)
 func (_NftOwnershipTask *NftOwnershipTaskFilterer) WatchTaskCreated(opts *bind.WatchOpts, sink chan<- *NftOwnershipTaskTaskCreated, taskId [][32]byte) (event.Subscription, error) {
 
 	var taskIdRule []interface{}
@@ -919,9 +974,9 @@ func (_NftOwnershipTask *NftOwnershipTaskFilterer) WatchTaskCreated(opts *bind.W
 	}), nil
 }
 
-// ParseTaskCreated is a log parse operation binding the contract event 0x7a37329df301808b8ac3daca67b67243924444f5a64e02bb10a901c69579d63f.
+// ParseTaskCreated is a log parse operation binding the contract event 0xc056bc4f2f57c2d277bc36e76066e7c26a06b41ac9e98b906cbae2edf12c292e.
 //
-// Solidity: event TaskCreated(bytes32 indexed taskId, (uint256,address,uint256,address,uint64,uint8,uint256,uint48) req)
+// Solidity: event TaskCreated(bytes32 indexed taskId, (uint256,address,uint256,address,uint64,uint8,uint256,uint256,uint48) req)
 func (_NftOwnershipTask *NftOwnershipTaskFilterer) ParseTaskCreated(log types.Log) (*NftOwnershipTaskTaskCreated, error) {
 	event := new(NftOwnershipTaskTaskCreated)
 	if err := _NftOwnershipTask.contract.UnpackLog(event, "TaskCreated", log); err != nil {
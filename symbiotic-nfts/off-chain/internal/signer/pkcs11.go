@@ -0,0 +1,294 @@
+//go:build cgo
+
+package signer
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/asn1"
+	"log/slog"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/go-errors/errors"
+	"github.com/miekg/pkcs11"
+)
+
+// secp256k1OID is the DER encoded CKA_EC_PARAMS of secp256k1 keys (OID 1.3.132.0.10).
+var secp256k1OID = []byte{0x06, 0x05, 0x2b, 0x81, 0x04, 0x00, 0x0a}
+
+var secp256k1HalfN = new(big.Int).Rsh(crypto.S256().Params().N, 1)
+
+// pkcs11Signer signs with a secp256k1 key that never leaves the token. A PKCS#11 session
+// must not be used concurrently, so signing is serialized. A session the token drops, e.g. when
+// a network HSM reconnects, is opened and logged into again, see sign.
+type pkcs11Signer struct {
+	mu      sync.Mutex
+	p       *pkcs11.Ctx
+	cfg     PKCS11Config
+	pin     string
+	session pkcs11.SessionHandle
+	key     pkcs11.ObjectHandle
+	address common.Address
+}
+
+// NewPKCS11 logs into the token, looks up the key pair by label and runs a signing self-test.
+func NewPKCS11(cfg PKCS11Config) (Signer, error) {
+	pin, err := readSecret(cfg.PinFile, "PKCS#11 PIN file")
+	if err != nil {
+		return nil, err
+	}
+	p := pkcs11.New(cfg.Module)
+	if p == nil {
+		return nil, errors.Errorf("failed to load PKCS#11 module %s", cfg.Module)
+	}
+	if err := p.Initialize(); err != nil {
+		p.Destroy()
+		return nil, errors.Errorf("failed to initialize PKCS#11 module: %w", err)
+	}
+	s := &pkcs11Signer{p: p, cfg: cfg, pin: pin}
+	if err := s.open(); err != nil {
+		s.Close()
+		return nil, err
+	}
+	if err := s.selfTest(); err != nil {
+		s.Close()
+		return nil, errors.Errorf("PKCS#11 signer self-test failed: %w", err)
+	}
+	return s, nil
+}
+
+// open opens a session, logs in and looks up the key pair. When reopening, the key must still be the signer's.
+func (s *pkcs11Signer) open() error {
+	slot, err := s.findSlot(s.cfg.TokenLabel)
+	if err != nil {
+		return err
+	}
+	s.session, err = s.p.OpenSession(slot, pkcs11.CKF_SERIAL_SESSION)
+	if err != nil {
+		return errors.Errorf("failed to open PKCS#11 session: %w", err)
+	}
+	// The login state is shared by the sessions of the application, it may have outlived the session dropped.
+	if err := s.p.Login(s.session, pkcs11.CKU_USER, s.pin); err != nil && !isPKCS11Error(err, pkcs11.CKR_USER_ALREADY_LOGGED_IN) {
+		return errors.Errorf("failed to log into token: %w", err)
+	}
+	if s.key, err = s.findKey(pkcs11.CKO_PRIVATE_KEY, s.cfg.KeyLabel); err != nil {
+		return err
+	}
+	pubObj, err := s.findKey(pkcs11.CKO_PUBLIC_KEY, s.cfg.KeyLabel)
+	if err != nil {
+		return err
+	}
+	pub, err := s.publicKey(pubObj)
+	if err != nil {
+		return err
+	}
+	address := crypto.PubkeyToAddress(*pub)
+	if s.address != (common.Address{}) && address != s.address {
+		return errors.Errorf("key labeled %q is now %s, was %s", s.cfg.KeyLabel, address.Hex(), s.address.Hex())
+	}
+	s.address = address
+	return nil
+}
+
+// reopen replaces a session the token dropped. The session is left unset if that fails, so the next
+// signing fails with CKR_SESSION_HANDLE_INVALID and tries again.
+func (s *pkcs11Signer) reopen() error {
+	_ = s.p.CloseSession(s.session)
+	if err := s.open(); err != nil {
+		if s.session != 0 {
+			_ = s.p.CloseSession(s.session)
+			s.session = 0
+		}
+		return errors.Errorf("failed to reopen PKCS#11 session: %w", err)
+	}
+	return nil
+}
+
+// sessionLost reports whether err means the session is gone and a new one may succeed.
+func sessionLost(err error) bool {
+	return isPKCS11Error(err, pkcs11.CKR_SESSION_HANDLE_INVALID) ||
+		isPKCS11Error(err, pkcs11.CKR_SESSION_CLOSED) ||
+		isPKCS11Error(err, pkcs11.CKR_DEVICE_REMOVED)
+}
+
+func isPKCS11Error(err error, code uint) bool {
+	var perr pkcs11.Error
+	return errors.As(err, &perr) && uint(perr) == code
+}
+
+// findSlot returns the slot of the token with the given label, or the only token if label is empty.
+func (s *pkcs11Signer) findSlot(label string) (uint, error) {
+	slots, err := s.p.GetSlotList(true)
+	if err != nil {
+		return 0, errors.Errorf("failed to list PKCS#11 slots: %w", err)
+	}
+	if label == "" {
+		if len(slots) != 1 {
+			return 0, errors.Errorf("found %d PKCS#11 tokens, select one with --pkcs11-token-label", len(slots))
+		}
+		return slots[0], nil
+	}
+	for _, slot := range slots {
+		info, err := s.p.GetTokenInfo(slot)
+		if err != nil {
+			return 0, errors.Errorf("failed to get token info of slot %d: %w", slot, err)
+		}
+		if strings.TrimSpace(info.Label) == label {
+			return slot, nil
+		}
+	}
+	return 0, errors.Errorf("no PKCS#11 token labeled %q", label)
+}
+
+// findKey returns the single EC key object of the class with the given label.
+func (s *pkcs11Signer) findKey(class uint, label string) (pkcs11.ObjectHandle, error) {
+	template := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, class),
+		pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_EC),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+	}
+	if err := s.p.FindObjectsInit(s.session, template); err != nil {
+		return 0, errors.Errorf("failed to search keys: %w", err)
+	}
+	objs, _, err := s.p.FindObjects(s.session, 2)
+	if finalErr := s.p.FindObjectsFinal(s.session); err == nil {
+		err = finalErr
+	}
+	if err != nil {
+		return 0, errors.Errorf("failed to search keys: %w", err)
+	}
+	kind := "private"
+	if class == pkcs11.CKO_PUBLIC_KEY {
+		kind = "public"
+	}
+	switch len(objs) {
+	case 0:
+		return 0, errors.Errorf("no EC %s key labeled %q on the token", kind, label)
+	case 1:
+		return objs[0], nil
+	default:
+		return 0, errors.Errorf("several EC %s keys labeled %q on the token", kind, label)
+	}
+}
+
+// publicKey reads the secp256k1 public key of a public key object.
+func (s *pkcs11Signer) publicKey(obj pkcs11.ObjectHandle) (*ecdsa.PublicKey, error) {
+	attrs, err := s.p.GetAttributeValue(s.session, obj, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_EC_PARAMS, nil),
+		pkcs11.NewAttribute(pkcs11.CKA_EC_POINT, nil),
+	})
+	if err != nil {
+		return nil, errors.Errorf("failed to read public key: %w", err)
+	}
+	if !bytes.Equal(attrs[0].Value, secp256k1OID) {
+		return nil, errors.New("key is not a secp256k1 key")
+	}
+	// CKA_EC_POINT is a DER octet string holding the uncompressed point; some modules return the raw point.
+	point := attrs[1].Value
+	var inner []byte
+	if rest, err := asn1.Unmarshal(point, &inner); err == nil && len(rest) == 0 {
+		point = inner
+	}
+	pub, err := crypto.UnmarshalPubkey(point)
+	if err != nil {
+		return nil, errors.Errorf("invalid EC point: %w", err)
+	}
+	return pub, nil
+}
+
+func (s *pkcs11Signer) Address() common.Address { return s.address }
+
+func (s *pkcs11Signer) SignTx(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	signer := types.LatestSignerForChainID(chainID)
+	h := signer.Hash(tx)
+	sig, err := s.sign(h[:])
+	if err != nil {
+		return nil, err
+	}
+	return tx.WithSignature(signer, sig)
+}
+
+// sign signs a 32 byte digest and returns the signature in the [R || S || V] format Ethereum expects.
+// The token returns plain ECDSA, so S is normalized to the lower half and V found by recovery.
+// If the session was lost, signing is retried once on a new one.
+func (s *pkcs11Signer) sign(digest []byte) ([]byte, error) {
+	s.mu.Lock()
+	raw, err := s.signRaw(digest)
+	if sessionLost(err) {
+		slog.Warn("PKCS#11 session lost, logging in again", "err", err)
+		if err = s.reopen(); err == nil {
+			raw, err = s.signRaw(digest)
+		}
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) != 64 {
+		return nil, errors.Errorf("unexpected PKCS#11 signature length %d", len(raw))
+	}
+	r := new(big.Int).SetBytes(raw[:32])
+	sv := new(big.Int).SetBytes(raw[32:])
+	if sv.Cmp(secp256k1HalfN) > 0 {
+		sv.Sub(crypto.S256().Params().N, sv)
+	}
+	sig := make([]byte, 65)
+	r.FillBytes(sig[:32])
+	sv.FillBytes(sig[32:64])
+	for v := byte(0); v < 2; v++ {
+		sig[64] = v
+		pub, err := crypto.SigToPub(digest, sig)
+		if err == nil && crypto.PubkeyToAddress(*pub) == s.address {
+			return sig, nil
+		}
+	}
+	return nil, errors.New("signature doesn't recover to the key's address")
+}
+
+func (s *pkcs11Signer) signRaw(digest []byte) ([]byte, error) {
+	if err := s.p.SignInit(s.session, []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_ECDSA, nil)}, s.key); err != nil {
+		return nil, errors.Errorf("failed to start PKCS#11 signing: %w", err)
+	}
+	raw, err := s.p.Sign(s.session, digest)
+	if err != nil {
+		return nil, errors.Errorf("PKCS#11 signing failed: %w", err)
+	}
+	return raw, nil
+}
+
+// selfTest signs a random digest and checks it verifies against the key's address.
+func (s *pkcs11Signer) selfTest() error {
+	nonce := make([]byte, 32)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	digest := crypto.Keccak256([]byte("pkcs11 signer self-test"), nonce)
+	sig, err := s.sign(digest)
+	if err != nil {
+		return err
+	}
+	pub, err := crypto.SigToPub(digest, sig)
+	if err != nil {
+		return err
+	}
+	if !crypto.VerifySignature(crypto.FromECDSAPub(pub), digest, sig[:64]) {
+		return errors.New("signature doesn't verify")
+	}
+	return nil
+}
+
+func (s *pkcs11Signer) Close() error {
+	if s.session != 0 {
+		_ = s.p.Logout(s.session)
+		_ = s.p.CloseSession(s.session)
+	}
+	err := s.p.Finalize()
+	s.p.Destroy()
+	return err
+}
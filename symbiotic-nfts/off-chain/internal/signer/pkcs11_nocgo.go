@@ -0,0 +1,10 @@
+//go:build !cgo
+
+package signer
+
+import "github.com/go-errors/errors"
+
+// NewPKCS11 is unavailable without cgo, the PKCS#11 module is loaded through it.
+func NewPKCS11(cfg PKCS11Config) (Signer, error) {
+	return nil, errors.New("PKCS#11 signing requires a build with CGO_ENABLED=1")
+}
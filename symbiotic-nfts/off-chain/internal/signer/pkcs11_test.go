@@ -0,0 +1,250 @@
+//go:build cgo
+
+package signer
+
+import (
+	"encoding/asn1"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/miekg/pkcs11"
+)
+
+// p256OID is the DER encoded CKA_EC_PARAMS of NIST P-256 keys (OID 1.2.840.10045.3.1.7).
+var p256OID = []byte{0x06, 0x08, 0x2a, 0x86, 0x48, 0xce, 0x3d, 0x03, 0x01, 0x07}
+
+const (
+	testTokenLabel = "signer-test"
+	testUserPIN    = "1234"
+)
+
+type softHSMToken struct {
+	module  string
+	pinFile string
+	address common.Address
+}
+
+// setupSoftHSM initializes a SoftHSM token in a temporary directory holding a secp256k1 key pair labeled
+// "node", a P-256 key pair labeled "p256" and two key pairs labeled "dup". The test is skipped unless
+// SOFTHSM2_MODULE points to the SoftHSM library, e.g. /usr/lib/softhsm/libsofthsm2.so.
+func setupSoftHSM(t *testing.T) softHSMToken {
+	t.Helper()
+	module := os.Getenv("SOFTHSM2_MODULE")
+	if module == "" {
+		t.Skip("SOFTHSM2_MODULE is not set")
+	}
+	if _, err := os.Stat(module); err != nil {
+		t.Skipf("SoftHSM module: %v", err)
+	}
+	dir := t.TempDir()
+	tokens := filepath.Join(dir, "tokens")
+	if err := os.Mkdir(tokens, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	conf := filepath.Join(dir, "softhsm2.conf")
+	if err := os.WriteFile(conf, []byte("directories.tokendir = "+tokens+"\nobjectstore.backend = file\nlog.level = ERROR\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SOFTHSM2_CONF", conf)
+
+	p := pkcs11.New(module)
+	if p == nil {
+		t.Fatalf("failed to load %s", module)
+	}
+	if err := p.Initialize(); err != nil {
+		p.Destroy()
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = p.Finalize()
+		p.Destroy()
+	}()
+
+	slots, err := p.GetSlotList(false)
+	if err != nil || len(slots) == 0 {
+		t.Fatalf("GetSlotList() = %v, %v", slots, err)
+	}
+	if err := p.InitToken(slots[len(slots)-1], "so-pin", testTokenLabel); err != nil {
+		t.Fatal(err)
+	}
+	// SoftHSM moves an initialized token to a new slot.
+	var slot uint
+	if slots, err = p.GetSlotList(true); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range slots {
+		if info, err := p.GetTokenInfo(id); err == nil && strings.TrimSpace(info.Label) == testTokenLabel {
+			slot = id
+		}
+	}
+	session, err := p.OpenSession(slot, pkcs11.CKF_SERIAL_SESSION|pkcs11.CKF_RW_SESSION)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = p.CloseSession(session) }()
+	if err := p.Login(session, pkcs11.CKU_SO, "so-pin"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.InitPIN(session, testUserPIN); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Logout(session); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Login(session, pkcs11.CKU_USER, testUserPIN); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = p.Logout(session) }()
+
+	generate := func(label string, params []byte) pkcs11.ObjectHandle {
+		pub, _, err := p.GenerateKeyPair(session,
+			[]*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_EC_KEY_PAIR_GEN, nil)},
+			[]*pkcs11.Attribute{
+				pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+				pkcs11.NewAttribute(pkcs11.CKA_VERIFY, true),
+				pkcs11.NewAttribute(pkcs11.CKA_EC_PARAMS, params),
+				pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+			},
+			[]*pkcs11.Attribute{
+				pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+				pkcs11.NewAttribute(pkcs11.CKA_PRIVATE, true),
+				pkcs11.NewAttribute(pkcs11.CKA_SENSITIVE, true),
+				pkcs11.NewAttribute(pkcs11.CKA_SIGN, true),
+				pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+			})
+		if err != nil {
+			t.Fatalf("generating key %q: %v", label, err)
+		}
+		return pub
+	}
+	pub := generate("node", secp256k1OID)
+	generate("p256", p256OID)
+	generate("dup", secp256k1OID)
+	generate("dup", secp256k1OID)
+
+	attrs, err := p.GetAttributeValue(session, pub, []*pkcs11.Attribute{pkcs11.NewAttribute(pkcs11.CKA_EC_POINT, nil)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var point []byte
+	if _, err := asn1.Unmarshal(attrs[0].Value, &point); err != nil {
+		t.Fatal(err)
+	}
+	key, err := crypto.UnmarshalPubkey(point)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return softHSMToken{
+		module:  module,
+		pinFile: writeFile(t, "pin", testUserPIN+"\n"),
+		address: crypto.PubkeyToAddress(*key),
+	}
+}
+
+func TestNewPKCS11(t *testing.T) {
+	token := setupSoftHSM(t)
+	cfg := PKCS11Config{Module: token.module, TokenLabel: testTokenLabel, KeyLabel: "node", PinFile: token.pinFile}
+
+	tests := []struct {
+		name string
+		edit func(*PKCS11Config)
+		want string
+	}{
+		{"unknown token", func(c *PKCS11Config) { c.TokenLabel = "other" }, `no PKCS#11 token labeled "other"`},
+		{"unknown key", func(c *PKCS11Config) { c.KeyLabel = "other" }, `no EC private key labeled "other"`},
+		{"ambiguous key", func(c *PKCS11Config) { c.KeyLabel = "dup" }, `several EC private keys labeled "dup"`},
+		{"P-256 key", func(c *PKCS11Config) { c.KeyLabel = "p256" }, "key is not a secp256k1 key"},
+		{"wrong PIN", func(c *PKCS11Config) { c.PinFile = writeFile(t, "pin", "4321") }, "failed to log into token"},
+	}
+	for _, tt := range tests {
+		c := cfg
+		tt.edit(&c)
+		// A failed start finalizes the module, so the next one can initialize it again.
+		if s, err := NewPKCS11(c); err == nil || !strings.Contains(err.Error(), tt.want) {
+			if s != nil {
+				_ = s.(*pkcs11Signer).Close()
+			}
+			t.Fatalf("%s: NewPKCS11() = %v, want an error containing %q", tt.name, err, tt.want)
+		}
+	}
+
+	// NewPKCS11 only returns after the self-test signature recovered to the key's address.
+	s, err := NewPKCS11(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ps := s.(*pkcs11Signer)
+	t.Cleanup(func() { _ = ps.Close() })
+	if s.Address() != token.address {
+		t.Fatalf("signer address = %s, want %s", s.Address(), token.address)
+	}
+}
+
+func TestPKCS11Sign(t *testing.T) {
+	token := setupSoftHSM(t)
+	s, err := NewPKCS11(PKCS11Config{Module: token.module, TokenLabel: testTokenLabel, KeyLabel: "node", PinFile: token.pinFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ps := s.(*pkcs11Signer)
+	t.Cleanup(func() { _ = ps.Close() })
+
+	// The token returns high S values about half of the time; each must come back normalized with a
+	// V that recovers the signer.
+	var high int
+	for i := range 64 {
+		digest := crypto.Keccak256([]byte{byte(i)})
+		raw, err := ps.signRaw(digest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if new(big.Int).SetBytes(raw[32:]).Cmp(secp256k1HalfN) > 0 {
+			high++
+		}
+		sig, err := ps.sign(digest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if new(big.Int).SetBytes(sig[32:64]).Cmp(secp256k1HalfN) > 0 {
+			t.Fatalf("signature %x has a high S", sig)
+		}
+		if sig[64] > 1 {
+			t.Fatalf("signature %x has V %d", sig, sig[64])
+		}
+		pub, err := crypto.SigToPub(digest, sig)
+		if err != nil || crypto.PubkeyToAddress(*pub) != token.address {
+			t.Fatalf("signature %x doesn't recover to %s: %v", sig, token.address, err)
+		}
+	}
+	if high == 0 {
+		t.Fatal("the token returned no high S signature to normalize")
+	}
+
+	chainID := big.NewInt(31337)
+	tx := types.NewTx(&types.DynamicFeeTx{ChainID: chainID, Nonce: 1, Gas: 21000, GasFeeCap: big.NewInt(2e9), GasTipCap: big.NewInt(1e9), To: &common.Address{}})
+	signTx := func() {
+		t.Helper()
+		signed, err := s.SignTx(tx, chainID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if from, err := types.Sender(types.LatestSignerForChainID(chainID), signed); err != nil || from != token.address {
+			t.Fatalf("signed tx sender = %s, %v, want %s", from, err, token.address)
+		}
+	}
+	signTx()
+
+	// Closing the only session logs the application out, as when a network HSM drops the connection.
+	// Signing logs in on a new session and retries.
+	if err := ps.p.CloseSession(ps.session); err != nil {
+		t.Fatal(err)
+	}
+	signTx()
+	signTx()
+}
@@ -0,0 +1,143 @@
+package signer
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/external"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/go-errors/errors"
+)
+
+// Signer signs the node's transactions. Implementations must be safe for concurrent use.
+type Signer interface {
+	Address() common.Address
+	SignTx(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
+	Close() error
+}
+
+// SignerFn adapts s to the signer callback of bind.TransactOpts for the given chain.
+func SignerFn(s Signer, chainID *big.Int) bind.SignerFn {
+	return func(from common.Address, tx *types.Transaction) (*types.Transaction, error) {
+		if from != s.Address() {
+			return nil, bind.ErrNotAuthorized
+		}
+		return s.SignTx(tx, chainID)
+	}
+}
+
+// keySigner signs with an in-memory private key.
+type keySigner struct {
+	key     *ecdsa.PrivateKey
+	address common.Address
+}
+
+func newKeySigner(key *ecdsa.PrivateKey) *keySigner {
+	return &keySigner{key: key, address: crypto.PubkeyToAddress(key.PublicKey)}
+}
+
+func (s *keySigner) Address() common.Address { return s.address }
+
+func (s *keySigner) SignTx(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return types.SignTx(tx, types.LatestSignerForChainID(chainID), s.key)
+}
+
+func (s *keySigner) Close() error { return nil }
+
+// NewRawKey returns a signer for a hex encoded private key. Meant for development only:
+// the key ends up in process listings and shell history.
+func NewRawKey(hexKey string) (Signer, error) {
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(strings.TrimSpace(hexKey), "0x"))
+	if err != nil {
+		return nil, errors.Errorf("failed to parse private key: %w", err)
+	}
+	return newKeySigner(key), nil
+}
+
+// NewKeystore decrypts a go-ethereum keystore file with the passphrase stored in passwordFile.
+func NewKeystore(keyFile, passwordFile string) (Signer, error) {
+	keyJSON, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, errors.Errorf("failed to read keystore file: %w", err)
+	}
+	passphrase, err := readSecret(passwordFile, "keystore password file")
+	if err != nil {
+		return nil, err
+	}
+	key, err := keystore.DecryptKey(keyJSON, passphrase)
+	if err != nil {
+		return nil, errors.Errorf("failed to decrypt keystore file %s: %w", keyFile, err)
+	}
+	return newKeySigner(key.PrivateKey), nil
+}
+
+// readSecret reads a passphrase or PIN from a file, what names the file in errors.
+func readSecret(path, what string) (string, error) {
+	if path == "" {
+		return "", errors.Errorf("%s is required", what)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", errors.Errorf("failed to read %s: %w", what, err)
+	}
+	// Only the first line counts, like geth's --password.
+	return strings.TrimRight(strings.SplitN(string(data), "\n", 2)[0], "\r"), nil
+}
+
+// PKCS11Config selects a secp256k1 key pair on a PKCS#11 token, e.g. SoftHSM or a network HSM.
+type PKCS11Config struct {
+	// Module is the path of the vendor's PKCS#11 library.
+	Module string
+	// TokenLabel selects the token, may be empty if the module exposes a single one.
+	TokenLabel string
+	// KeyLabel is the CKA_LABEL shared by the private and public key objects.
+	KeyLabel string
+	// PinFile holds the user PIN.
+	PinFile string
+}
+
+// externalSigner delegates signing to a Clef compatible signer, which keeps the key and may ask for approval.
+type externalSigner struct {
+	api     *external.ExternalSigner
+	account accounts.Account
+}
+
+// NewExternal connects to a Clef compatible signer at endpoint, an IPC path or an HTTP URL.
+// If address is the zero address the signer must manage exactly one account.
+func NewExternal(endpoint string, address common.Address) (Signer, error) {
+	api, err := external.NewExternalSigner(endpoint)
+	if err != nil {
+		return nil, errors.Errorf("failed to connect to external signer %s: %w", endpoint, err)
+	}
+	accs := api.Accounts()
+	var account accounts.Account
+	switch {
+	case address != (common.Address{}):
+		account = accounts.Account{Address: address}
+		if !api.Contains(account) {
+			api.Close()
+			return nil, errors.Errorf("external signer %s doesn't manage account %s", endpoint, address.Hex())
+		}
+	case len(accs) == 1:
+		account = accs[0]
+	default:
+		api.Close()
+		return nil, errors.Errorf("external signer %s manages %d accounts, select one with --signer-address", endpoint, len(accs))
+	}
+	return &externalSigner{api: api, account: account}, nil
+}
+
+func (s *externalSigner) Address() common.Address { return s.account.Address }
+
+func (s *externalSigner) SignTx(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return s.api.SignTx(s.account, tx, chainID)
+}
+
+func (s *externalSigner) Close() error { return s.api.Close() }
@@ -0,0 +1,127 @@
+package signer
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/go-errors/errors"
+)
+
+// The first anvil/hardhat development account.
+const (
+	testKey     = "ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80"
+	testAddress = "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestNewRawKey(t *testing.T) {
+	for _, hexKey := range []string{testKey, "0x" + testKey, " 0x" + testKey + "\n"} {
+		s, err := NewRawKey(hexKey)
+		if err != nil {
+			t.Fatalf("NewRawKey(%q): %v", hexKey, err)
+		}
+		if s.Address() != common.HexToAddress(testAddress) {
+			t.Fatalf("NewRawKey(%q) address = %s, want %s", hexKey, s.Address(), testAddress)
+		}
+	}
+	for _, hexKey := range []string{"", "0x1234", testKey[:62] + "zz"} {
+		if _, err := NewRawKey(hexKey); err == nil {
+			t.Errorf("NewRawKey(%q) succeeded", hexKey)
+		}
+	}
+}
+
+func TestNewKeystore(t *testing.T) {
+	priv, err := crypto.HexToECDSA(testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks := keystore.NewKeyStore(t.TempDir(), keystore.LightScryptN, keystore.LightScryptP)
+	acc, err := ks.ImportECDSA(priv, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := acc.URL.Path
+
+	// Only the first line of the password file is the passphrase.
+	s, err := NewKeystore(keyFile, writeFile(t, "password", "correct horse\r\nsecond line\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Address() != common.HexToAddress(testAddress) {
+		t.Fatalf("keystore address = %s, want %s", s.Address(), testAddress)
+	}
+
+	if _, err := NewKeystore(keyFile, writeFile(t, "password", "wrong horse\n")); !errors.Is(err, keystore.ErrDecrypt) {
+		t.Fatalf("NewKeystore() with a wrong password = %v, want %v", err, keystore.ErrDecrypt)
+	}
+	if _, err := NewKeystore(keyFile, ""); err == nil {
+		t.Fatal("NewKeystore() without a password file succeeded")
+	}
+	if _, err := NewKeystore(filepath.Join(t.TempDir(), "missing.json"), writeFile(t, "password", "correct horse")); err == nil {
+		t.Fatal("NewKeystore() of a missing keystore file succeeded")
+	}
+}
+
+func TestReadSecret(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{"1234", "1234"},
+		{"1234\n", "1234"},
+		{"1234\r\n", "1234"},
+		{"1234\nsecond line\n", "1234"},
+		{" pass phrase \n", " pass phrase "},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		got, err := readSecret(writeFile(t, "secret", tt.content), "PIN file")
+		if err != nil || got != tt.want {
+			t.Errorf("readSecret(%q) = %q, %v, want %q", tt.content, got, err, tt.want)
+		}
+	}
+	if _, err := readSecret("", "PIN file"); err == nil || err.Error() != "PIN file is required" {
+		t.Errorf("readSecret() without a path = %v", err)
+	}
+	if _, err := readSecret(filepath.Join(t.TempDir(), "missing"), "PIN file"); err == nil {
+		t.Error("readSecret() of a missing file succeeded")
+	}
+}
+
+func TestSignerFn(t *testing.T) {
+	s, err := NewRawKey(testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chainID := big.NewInt(31337)
+	fn := SignerFn(s, chainID)
+	tx := types.NewTx(&types.DynamicFeeTx{ChainID: chainID, Nonce: 1, Gas: 21000, GasFeeCap: big.NewInt(2e9), GasTipCap: big.NewInt(1e9), To: &common.Address{}})
+
+	if _, err := fn(common.HexToAddress("0x01"), tx); !errors.Is(err, bind.ErrNotAuthorized) {
+		t.Fatalf("signing for another account = %v, want %v", err, bind.ErrNotAuthorized)
+	}
+	signed, err := fn(s.Address(), tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	from, err := types.Sender(types.LatestSignerForChainID(chainID), signed)
+	if err != nil || from != s.Address() {
+		t.Fatalf("signed tx sender = %s, %v, want %s", from, err, s.Address())
+	}
+}
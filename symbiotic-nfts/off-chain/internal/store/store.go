@@ -0,0 +1,219 @@
+package store
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/go-errors/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	tasksBucket       = []byte("tasks")
+	checkpointsBucket = []byte("checkpoints")
+	blockHashesBucket = []byte("blockhashes")
+)
+
+// Store is an embedded on-disk store for node state that has to survive restarts:
+// task states (including sign request hashes, proofs and submission tx hashes),
+// per-chain block checkpoints and the hashes of recently scanned blocks.
+type Store struct {
+	db *bolt.DB
+}
+
+func Open(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, errors.Errorf("failed to open store at %s: %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{tasksBucket, checkpointsBucket, blockHashesBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, errors.Errorf("failed to init store buckets: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// TaskKey identifies a task by the chain and contract it was created on, since task IDs are only
+// unique per contract.
+type TaskKey struct {
+	ChainID  int64
+	Contract common.Address
+	TaskID   common.Hash
+}
+
+func (k TaskKey) String() string {
+	return fmt.Sprintf("%d/%s/%s", k.ChainID, k.Contract.Hex(), k.TaskID.Hex())
+}
+
+// Bytes is the store key, chain ID, contract and task ID concatenated.
+func (k TaskKey) Bytes() []byte {
+	b := append(chainKey(k.ChainID), k.Contract.Bytes()...)
+	return append(b, k.TaskID.Bytes()...)
+}
+
+// taskKeyLen is the length of TaskKey.Bytes. Stores written before contracts were part of the key
+// hold bare 32 byte task IDs.
+const taskKeyLen = 8 + common.AddressLength + common.HashLength
+
+// PutTask stores v as the JSON-encoded state of the task.
+func (s *Store) PutTask(key TaskKey, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return errors.Errorf("failed to encode task %s: %w", key, err)
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(tasksBucket).Put(key.Bytes(), data)
+	})
+}
+
+func (s *Store) DeleteTask(key TaskKey) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(tasksBucket).Delete(key.Bytes())
+	})
+}
+
+// ForEachTask calls fn with the raw JSON state of every stored task. Tasks stored under a bare task ID
+// by older versions are passed with legacy set and only TaskID filled in the key.
+func (s *Store) ForEachTask(fn func(key TaskKey, legacy bool, data []byte) error) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(tasksBucket).ForEach(func(k, v []byte) error {
+			switch len(k) {
+			case common.HashLength:
+				return fn(TaskKey{TaskID: common.BytesToHash(k)}, true, v)
+			case taskKeyLen:
+				return fn(TaskKey{
+					ChainID:  int64(binary.BigEndian.Uint64(k[:8])),
+					Contract: common.BytesToAddress(k[8 : 8+common.AddressLength]),
+					TaskID:   common.BytesToHash(k[8+common.AddressLength:]),
+				}, false, v)
+			default:
+				return errors.Errorf("malformed task key %x", k)
+			}
+		})
+	})
+}
+
+// MigrateTask moves a task stored under its bare task ID to key.
+func (s *Store) MigrateTask(taskID common.Hash, key TaskKey, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return errors.Errorf("failed to encode task %s: %w", key, err)
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(tasksBucket)
+		if err := b.Put(key.Bytes(), data); err != nil {
+			return err
+		}
+		return b.Delete(taskID.Bytes())
+	})
+}
+
+// PutCheckpoint records the next block to scan on the given chain.
+func (s *Store) PutCheckpoint(chainID int64, block uint64) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(checkpointsBucket).Put(chainKey(chainID), binary.BigEndian.AppendUint64(nil, block))
+	})
+}
+
+func (s *Store) Checkpoints() (map[int64]uint64, error) {
+	m := make(map[int64]uint64)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(checkpointsBucket).ForEach(func(k, v []byte) error {
+			if len(k) != 8 || len(v) != 8 {
+				return errors.Errorf("malformed checkpoint entry %x", k)
+			}
+			m[int64(binary.BigEndian.Uint64(k))] = binary.BigEndian.Uint64(v)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// PutBlockHash records the hash of a scanned block so later scans can detect reorgs.
+func (s *Store) PutBlockHash(chainID int64, number uint64, hash common.Hash) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(blockHashesBucket).Put(blockKey(chainID, number), hash.Bytes())
+	})
+}
+
+// BlockHash is a recorded (number, hash) pair of a scanned block.
+type BlockHash struct {
+	Number uint64
+	Hash   common.Hash
+}
+
+// BlockHashes returns the recorded block hashes of the chain, newest first.
+func (s *Store) BlockHashes(chainID int64) ([]BlockHash, error) {
+	var out []BlockHash
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(blockHashesBucket).Cursor()
+		prefix := chainKey(chainID)
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			out = append(out, BlockHash{Number: binary.BigEndian.Uint64(k[8:]), Hash: common.BytesToHash(v)})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.Reverse(out)
+	return out, nil
+}
+
+func (s *Store) deleteBlockHashes(chainID int64, match func(number uint64) bool) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(blockHashesBucket)
+		prefix := chainKey(chainID)
+		var keys [][]byte
+		c := b.Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			if match(binary.BigEndian.Uint64(k[8:])) {
+				keys = append(keys, bytes.Clone(k))
+			}
+		}
+		for _, k := range keys {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// RewindBlockHashes drops recorded hashes at or above the given block, e.g. after a reorg.
+func (s *Store) RewindBlockHashes(chainID int64, from uint64) error {
+	return s.deleteBlockHashes(chainID, func(number uint64) bool { return number >= from })
+}
+
+// PruneBlockHashes drops recorded hashes below the given block.
+func (s *Store) PruneBlockHashes(chainID int64, below uint64) error {
+	return s.deleteBlockHashes(chainID, func(number uint64) bool { return number < below })
+}
+
+func blockKey(chainID int64, number uint64) []byte {
+	return binary.BigEndian.AppendUint64(chainKey(chainID), number)
+}
+
+func chainKey(chainID int64) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(chainID))
+}
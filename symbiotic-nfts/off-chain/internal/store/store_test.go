@@ -0,0 +1,190 @@
+package store
+
+import (
+	"math/big"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	bolt "go.etcd.io/bbolt"
+)
+
+func openTestStore(t *testing.T, path string) *Store {
+	t.Helper()
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+// reopen closes the store and opens its file again, as a restarted node would.
+func reopen(t *testing.T, s *Store, path string) *Store {
+	t.Helper()
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return openTestStore(t, path)
+}
+
+type storedTask struct {
+	key    TaskKey
+	legacy bool
+	data   string
+}
+
+func storedTasks(t *testing.T, s *Store) []storedTask {
+	t.Helper()
+	var out []storedTask
+	err := s.ForEachTask(func(key TaskKey, legacy bool, data []byte) error {
+		out = append(out, storedTask{key, legacy, string(data)})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestTaskRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "node.db")
+	s := openTestStore(t, path)
+
+	contract := common.HexToAddress("0x5FbDB2315678afecb367f032d93F642f64180aa3")
+	a := TaskKey{ChainID: 1, Contract: contract, TaskID: common.HexToHash("0x01")}
+	b := TaskKey{ChainID: 31337, Contract: contract, TaskID: common.HexToHash("0x02")}
+	if err := s.PutTask(a, map[string]int{"v": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.PutTask(b, map[string]int{"v": 2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.PutTask(a, map[string]int{"v": 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	s = reopen(t, s, path)
+	want := []storedTask{{a, false, `{"v":3}`}, {b, false, `{"v":2}`}}
+	if got := storedTasks(t, s); !slices.Equal(got, want) {
+		t.Fatalf("tasks = %v, want %v", got, want)
+	}
+
+	if err := s.DeleteTask(a); err != nil {
+		t.Fatal(err)
+	}
+	s = reopen(t, s, path)
+	if got := storedTasks(t, s); !slices.Equal(got, want[1:]) {
+		t.Fatalf("tasks after delete = %v, want %v", got, want[1:])
+	}
+}
+
+func TestMigrateTask(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "node.db")
+	s := openTestStore(t, path)
+
+	taskID := common.HexToHash("0x01")
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(tasksBucket).Put(taskID.Bytes(), []byte(`{"v":1}`))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s = reopen(t, s, path)
+	if got, want := storedTasks(t, s), []storedTask{{TaskKey{TaskID: taskID}, true, `{"v":1}`}}; !slices.Equal(got, want) {
+		t.Fatalf("tasks = %v, want %v", got, want)
+	}
+
+	key := TaskKey{ChainID: 1, Contract: common.HexToAddress("0x5FbDB2315678afecb367f032d93F642f64180aa3"), TaskID: taskID}
+	if err := s.MigrateTask(taskID, key, map[string]int{"v": 2}); err != nil {
+		t.Fatal(err)
+	}
+	s = reopen(t, s, path)
+	if got, want := storedTasks(t, s), []storedTask{{key, false, `{"v":2}`}}; !slices.Equal(got, want) {
+		t.Fatalf("tasks after migration = %v, want %v", got, want)
+	}
+}
+
+func TestMalformedTaskKey(t *testing.T) {
+	s := openTestStore(t, filepath.Join(t.TempDir(), "node.db"))
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(tasksBucket).Put([]byte{1, 2, 3}, []byte(`{}`))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ForEachTask(func(TaskKey, bool, []byte) error { return nil }); err == nil {
+		t.Fatal("ForEachTask() succeeded on a malformed key")
+	}
+}
+
+func TestCheckpointsRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "node.db")
+	s := openTestStore(t, path)
+	for chainID, block := range map[int64]uint64{1: 100, 31337: 7} {
+		if err := s.PutCheckpoint(chainID, block); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.PutCheckpoint(1, 150); err != nil {
+		t.Fatal(err)
+	}
+
+	s = reopen(t, s, path)
+	got, err := s.Checkpoints()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[1] != 150 || got[31337] != 7 {
+		t.Fatalf("checkpoints = %v, want map[1:150 31337:7]", got)
+	}
+}
+
+func TestBlockHashes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "node.db")
+	s := openTestStore(t, path)
+	for n := uint64(10); n < 15; n++ {
+		if err := s.PutBlockHash(1, n, common.BigToHash(new(big.Int).SetUint64(n))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Another chain's hashes must not show up or be dropped.
+	if err := s.PutBlockHash(2, 12, common.HexToHash("0xff")); err != nil {
+		t.Fatal(err)
+	}
+
+	numbers := func(chainID int64) []uint64 {
+		t.Helper()
+		hashes, err := s.BlockHashes(chainID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out []uint64
+		for _, h := range hashes {
+			if chainID == 1 && h.Hash != common.BigToHash(new(big.Int).SetUint64(h.Number)) {
+				t.Fatalf("block %d has hash %s", h.Number, h.Hash.Hex())
+			}
+			out = append(out, h.Number)
+		}
+		return out
+	}
+
+	s = reopen(t, s, path)
+	if got, want := numbers(1), []uint64{14, 13, 12, 11, 10}; !slices.Equal(got, want) {
+		t.Fatalf("block hashes = %v, want %v", got, want)
+	}
+	if err := s.RewindBlockHashes(1, 13); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.PruneBlockHashes(1, 11); err != nil {
+		t.Fatal(err)
+	}
+	s = reopen(t, s, path)
+	if got, want := numbers(1), []uint64{12, 11}; !slices.Equal(got, want) {
+		t.Fatalf("block hashes after rewind and prune = %v, want %v", got, want)
+	}
+	if got, want := numbers(2), []uint64{12}; !slices.Equal(got, want) {
+		t.Fatalf("block hashes of chain 2 = %v, want %v", got, want)
+	}
+}
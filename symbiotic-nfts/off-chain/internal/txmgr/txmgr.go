@@ -0,0 +1,357 @@
+package txmgr
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/go-errors/errors"
+)
+
+// State is the lifecycle state of a managed transaction.
+type State string
+
+const (
+	StateSubmitted State = "submitted"
+	StateConfirmed State = "confirmed"
+	StateFailed    State = "failed"
+)
+
+// minBumpPercent is the fee increase nodes require to accept a replacement transaction.
+const minBumpPercent = 10
+
+// Config is the fee and replacement policy of a Manager.
+type Config struct {
+	// MaxFeeCap and MaxTipCap cap the per gas fees in wei, nil means uncapped.
+	MaxFeeCap *big.Int
+	MaxTipCap *big.Int
+	// BaseFeeMultiplier sets the fee cap to baseFee*BaseFeeMultiplier + tip, so the tx stays
+	// includable while the base fee rises.
+	BaseFeeMultiplier uint64
+	// BumpPercent is how much both fees grow when a stuck tx is replaced, at least 10.
+	BumpPercent uint64
+	// StuckAfter is how long a tx may stay unmined before it is replaced.
+	StuckAfter time.Duration
+	// MaxReplacements bounds the replacements of a tx; after that it's only waited for.
+	MaxReplacements int
+	// Confirmations is how many blocks deep the receipt must be before the tx is confirmed.
+	Confirmations uint64
+}
+
+// Tx tracks a transaction through replacements until it's confirmed or failed.
+// It is a plain value so callers can persist it.
+type Tx struct {
+	State        State          `json:"state"`
+	Nonce        uint64         `json:"nonce"`
+	To           common.Address `json:"to"`
+	Data         hexutil.Bytes  `json:"data"`
+	Gas          uint64         `json:"gas"`
+	GasFeeCap    *big.Int       `json:"gasFeeCap"`
+	GasTipCap    *big.Int       `json:"gasTipCap"`
+	Hashes       []common.Hash  `json:"hashes"` // all broadcast versions, latest last
+	SentAt       time.Time      `json:"sentAt"`
+	Replacements int            `json:"replacements"`
+	MinedBlock   uint64         `json:"minedBlock,omitempty"`
+	Error        string         `json:"error,omitempty"`
+	// GasUsed and EffectiveGasPrice are taken from the receipt once the tx is final.
+	GasUsed           uint64   `json:"gasUsed,omitempty"`
+	EffectiveGasPrice *big.Int `json:"effectiveGasPrice,omitempty"`
+	// NonceTakenAt is the head at which the nonce was first seen used while no version had a receipt.
+	NonceTakenAt uint64 `json:"nonceTakenAt,omitempty"`
+}
+
+// Hash is the hash of the latest broadcast version.
+func (t Tx) Hash() common.Hash {
+	if len(t.Hashes) == 0 {
+		return common.Hash{}
+	}
+	return t.Hashes[len(t.Hashes)-1]
+}
+
+// Client is the chain access of a Manager, implemented by *ethclient.Client.
+type Client interface {
+	ethereum.BlockNumberReader
+	ethereum.ChainReader
+	ethereum.ChainStateReader
+	ethereum.PendingStateReader
+	ethereum.GasPricer1559
+	ethereum.TransactionReader
+	ethereum.TransactionSender
+}
+
+// Manager sends transactions from one account on one chain. It allocates nonces locally,
+// prices transactions with an EIP-1559 fee policy and replaces transactions that got stuck.
+type Manager struct {
+	cli     Client
+	chainID *big.Int
+	from    common.Address
+	signer  bind.SignerFn
+	cfg     Config
+
+	mu     sync.Mutex
+	nonce  uint64
+	synced bool
+}
+
+func New(cli Client, chainID *big.Int, from common.Address, signer bind.SignerFn, cfg Config) (*Manager, error) {
+	if cfg.BumpPercent < minBumpPercent {
+		return nil, errors.Errorf("fee bump must be at least %d%%, got %d%%", minBumpPercent, cfg.BumpPercent)
+	}
+	if cfg.BaseFeeMultiplier == 0 {
+		return nil, errors.New("base fee multiplier must be positive")
+	}
+	return &Manager{cli: cli, chainID: chainID, from: from, signer: signer, cfg: cfg}, nil
+}
+
+func (m *Manager) From() common.Address {
+	return m.from
+}
+
+// Send prices and signs the transaction produced by build with the next local nonce and broadcasts it.
+// build is typically a contract binding call; it must honour the given options and not send by itself.
+func (m *Manager) Send(ctx context.Context, build func(opts *bind.TransactOpts) (*types.Transaction, error)) (Tx, error) {
+	feeCap, tipCap, err := m.fees(ctx)
+	if err != nil {
+		return Tx{}, err
+	}
+	nonce, err := m.nextNonce(ctx)
+	if err != nil {
+		return Tx{}, err
+	}
+	opts := &bind.TransactOpts{
+		From:      m.from,
+		Signer:    m.signer,
+		Context:   ctx,
+		Nonce:     new(big.Int).SetUint64(nonce),
+		GasFeeCap: feeCap,
+		GasTipCap: tipCap,
+		NoSend:    true,
+	}
+	tx, err := build(opts)
+	if err != nil {
+		m.resync()
+		return Tx{}, err
+	}
+	if err := m.cli.SendTransaction(ctx, tx); err != nil {
+		m.resync()
+		return Tx{}, errors.Errorf("failed to send tx: %w", err)
+	}
+	return Tx{
+		State:     StateSubmitted,
+		Nonce:     nonce,
+		To:        *tx.To(),
+		Data:      tx.Data(),
+		Gas:       tx.Gas(),
+		GasFeeCap: tx.GasFeeCap(),
+		GasTipCap: tx.GasTipCap(),
+		Hashes:    []common.Hash{tx.Hash()},
+		SentAt:    time.Now(),
+	}, nil
+}
+
+// Check advances a submitted tx: it confirms or fails it once a receipt is deep enough,
+// and replaces it with higher fees if it has been pending for longer than StuckAfter.
+func (m *Manager) Check(ctx context.Context, t Tx) (Tx, error) {
+	if t.State != StateSubmitted {
+		return t, nil
+	}
+	head, err := m.cli.BlockNumber(ctx)
+	if err != nil {
+		return t, errors.Errorf("failed to get head: %w", err)
+	}
+	t, found, err := m.checkReceipts(ctx, t, head)
+	if err != nil || found {
+		return t, err
+	}
+
+	// None of our versions is mined; if the nonce is used anyway, some other tx of the account took it.
+	confirmedNonce, err := m.cli.NonceAt(ctx, m.from, nil)
+	if err != nil {
+		return t, errors.Errorf("failed to get account nonce: %w", err)
+	}
+	if confirmedNonce > t.Nonce {
+		// One of our versions may have been mined after its receipt was looked up, so look again.
+		t, found, err = m.checkReceipts(ctx, t, head)
+		if err != nil || found {
+			return t, err
+		}
+		// Receipts of fresh blocks may lag behind the nonce on load balanced RPCs; only give up
+		// once the nonce has stayed taken for as many blocks as a receipt needs confirmations.
+		if t.NonceTakenAt == 0 || head < t.NonceTakenAt {
+			t.NonceTakenAt = head
+		}
+		if head-t.NonceTakenAt < max(m.cfg.Confirmations, 1) {
+			return t, nil
+		}
+		t.State = StateFailed
+		t.Error = "nonce consumed by another transaction"
+		return t, nil
+	}
+	t.NonceTakenAt = 0
+
+	if time.Since(t.SentAt) < m.cfg.StuckAfter || t.Replacements >= m.cfg.MaxReplacements {
+		return t, nil
+	}
+	return m.replace(ctx, t)
+}
+
+// checkReceipts looks for a receipt of any version of the tx, latest first. found reports whether one
+// exists; the tx is then confirmed or failed if the receipt is deep enough, and still submitted otherwise.
+func (m *Manager) checkReceipts(ctx context.Context, t Tx, head uint64) (Tx, bool, error) {
+	for i := len(t.Hashes) - 1; i >= 0; i-- {
+		receipt, err := m.cli.TransactionReceipt(ctx, t.Hashes[i])
+		if errors.Is(err, ethereum.NotFound) {
+			continue
+		}
+		if err != nil {
+			return t, false, errors.Errorf("failed to get receipt of %s: %w", t.Hashes[i].Hex(), err)
+		}
+		mined := receipt.BlockNumber.Uint64()
+		t.MinedBlock = mined
+		t.NonceTakenAt = 0
+		if head < mined || head-mined+1 < max(m.cfg.Confirmations, 1) {
+			return t, true, nil
+		}
+		t.GasUsed, t.EffectiveGasPrice = receipt.GasUsed, receipt.EffectiveGasPrice
+		if receipt.Status == types.ReceiptStatusSuccessful {
+			t.State = StateConfirmed
+		} else {
+			t.State = StateFailed
+			t.Error = "execution reverted"
+		}
+		return t, true, nil
+	}
+	t.MinedBlock = 0
+	return t, false, nil
+}
+
+// replace re-sends the tx with the same nonce and bumped fees.
+func (m *Manager) replace(ctx context.Context, t Tx) (Tx, error) {
+	feeCap, tipCap, err := m.fees(ctx)
+	if err != nil {
+		return t, err
+	}
+	// The replacement must outbid the pending version, and should follow the market if it moved further.
+	feeCap = bigMax(feeCap, bump(t.GasFeeCap, m.cfg.BumpPercent))
+	tipCap = bigMax(tipCap, bump(t.GasTipCap, m.cfg.BumpPercent))
+	if m.cfg.MaxTipCap != nil && tipCap.Cmp(m.cfg.MaxTipCap) > 0 {
+		tipCap = new(big.Int).Set(m.cfg.MaxTipCap)
+	}
+	if m.cfg.MaxFeeCap != nil && feeCap.Cmp(m.cfg.MaxFeeCap) > 0 {
+		feeCap = new(big.Int).Set(m.cfg.MaxFeeCap)
+	}
+	if tipCap.Cmp(feeCap) > 0 {
+		tipCap = new(big.Int).Set(feeCap)
+	}
+	if feeCap.Cmp(bump(t.GasFeeCap, minBumpPercent)) < 0 || tipCap.Cmp(bump(t.GasTipCap, minBumpPercent)) < 0 {
+		// Capped, a replacement would be rejected as underpriced. Keep waiting for the current one.
+		return t, nil
+	}
+
+	to := t.To
+	tx, err := m.signer(m.from, types.NewTx(&types.DynamicFeeTx{
+		ChainID:   m.chainID,
+		Nonce:     t.Nonce,
+		GasTipCap: tipCap,
+		GasFeeCap: feeCap,
+		Gas:       t.Gas,
+		To:        &to,
+		Data:      t.Data,
+	}))
+	if err != nil {
+		return t, errors.Errorf("failed to sign replacement: %w", err)
+	}
+	if err := m.cli.SendTransaction(ctx, tx); err != nil {
+		if isNonceTooLow(err) {
+			// A previous version got mined meanwhile, the next Check will find its receipt.
+			return t, nil
+		}
+		return t, errors.Errorf("failed to send replacement: %w", err)
+	}
+	t.GasFeeCap = feeCap
+	t.GasTipCap = tipCap
+	t.Hashes = append(t.Hashes, tx.Hash())
+	t.SentAt = time.Now()
+	t.Replacements++
+	return t, nil
+}
+
+// fees returns the fee cap and tip of a new transaction under the configured policy.
+func (m *Manager) fees(ctx context.Context) (*big.Int, *big.Int, error) {
+	head, err := m.cli.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, nil, errors.Errorf("failed to get head: %w", err)
+	}
+	tipCap, err := m.cli.SuggestGasTipCap(ctx)
+	if err != nil {
+		return nil, nil, errors.Errorf("failed to suggest gas tip: %w", err)
+	}
+	if m.cfg.MaxTipCap != nil && tipCap.Cmp(m.cfg.MaxTipCap) > 0 {
+		tipCap = new(big.Int).Set(m.cfg.MaxTipCap)
+	}
+	baseFee := head.BaseFee
+	if baseFee == nil {
+		baseFee = new(big.Int)
+	}
+	feeCap := new(big.Int).Mul(baseFee, new(big.Int).SetUint64(m.cfg.BaseFeeMultiplier))
+	feeCap.Add(feeCap, tipCap)
+	if m.cfg.MaxFeeCap != nil && feeCap.Cmp(m.cfg.MaxFeeCap) > 0 {
+		feeCap = new(big.Int).Set(m.cfg.MaxFeeCap)
+	}
+	if feeCap.Cmp(baseFee) < 0 {
+		return nil, nil, errors.Errorf("base fee %s exceeds the fee cap %s", baseFee, feeCap)
+	}
+	if tipCap.Cmp(feeCap) > 0 {
+		tipCap = new(big.Int).Set(feeCap)
+	}
+	return feeCap, tipCap, nil
+}
+
+func (m *Manager) nextNonce(ctx context.Context) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.synced {
+		n, err := m.cli.PendingNonceAt(ctx, m.from)
+		if err != nil {
+			return 0, errors.Errorf("failed to get pending nonce: %w", err)
+		}
+		m.nonce, m.synced = n, true
+	}
+	n := m.nonce
+	m.nonce++
+	return n, nil
+}
+
+// resync makes the next allocation re-read the pending nonce, e.g. after an allocated nonce was not used.
+func (m *Manager) resync() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.synced = false
+}
+
+func bump(v *big.Int, percent uint64) *big.Int {
+	if v == nil {
+		return new(big.Int)
+	}
+	out := new(big.Int).Mul(v, new(big.Int).SetUint64(100+percent))
+	out.Add(out, big.NewInt(99))
+	return out.Div(out, big.NewInt(100))
+}
+
+func bigMax(a, b *big.Int) *big.Int {
+	if a.Cmp(b) >= 0 {
+		return a
+	}
+	return b
+}
+
+func isNonceTooLow(err error) bool {
+	return strings.Contains(strings.ToLower(err.Error()), "nonce too low")
+}
@@ -0,0 +1,415 @@
+package txmgr
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	recipient = common.HexToAddress("0x000000000000000000000000000000000000dEaD")
+	// reverter is a contract that reverts every call: PUSH1 0, DUP1, REVERT.
+	reverter = common.HexToAddress("0x00000000000000000000000000000000000000fd")
+)
+
+type testChain struct {
+	backend *simulated.Backend
+	cli     simulated.Client
+	key     *ecdsa.PrivateKey
+	from    common.Address
+	chainID *big.Int
+}
+
+func newTestChain(t *testing.T) *testChain {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	backend := simulated.NewBackend(types.GenesisAlloc{
+		from:     {Balance: new(big.Int).Mul(big.NewInt(100), big.NewInt(params.Ether))},
+		reverter: {Code: []byte{0x60, 0x00, 0x80, 0xfd}, Balance: new(big.Int)},
+	})
+	t.Cleanup(func() { _ = backend.Close() })
+	cli := backend.Client()
+	chainID, err := cli.ChainID(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Until the tx indexer caught up with a first block, lookups of unknown txs fail instead of returning NotFound.
+	backend.Commit()
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		_, err := cli.TransactionReceipt(context.Background(), common.Hash{})
+		if errors.Is(err, ethereum.NotFound) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("tx indexer not ready: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return &testChain{backend: backend, cli: cli, key: key, from: from, chainID: chainID}
+}
+
+func (c *testChain) signer(t *testing.T) bind.SignerFn {
+	t.Helper()
+	opts, err := bind.NewKeyedTransactorWithChainID(c.key, c.chainID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return opts.Signer
+}
+
+func (c *testChain) manager(t *testing.T, cfg Config) *Manager {
+	t.Helper()
+	return c.managerWith(t, c.cli, cfg)
+}
+
+func (c *testChain) managerWith(t *testing.T, cli Client, cfg Config) *Manager {
+	t.Helper()
+	m, err := New(cli, c.chainID, c.from, c.signer(t), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func testConfig() Config {
+	return Config{
+		BaseFeeMultiplier: 2,
+		BumpPercent:       20,
+		StuckAfter:        time.Hour,
+		MaxReplacements:   3,
+		Confirmations:     1,
+	}
+}
+
+// transfer builds a tx to `to` the way a contract binding does: with the given options and without sending it.
+func transfer(to common.Address, gas uint64) func(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return opts.Signer(opts.From, types.NewTx(&types.DynamicFeeTx{
+			Nonce:     opts.Nonce.Uint64(),
+			GasTipCap: opts.GasTipCap,
+			GasFeeCap: opts.GasFeeCap,
+			Gas:       gas,
+			To:        &to,
+			Value:     big.NewInt(1),
+		}))
+	}
+}
+
+func mustSend(t *testing.T, m *Manager, build func(opts *bind.TransactOpts) (*types.Transaction, error)) Tx {
+	t.Helper()
+	tx, err := m.Send(context.Background(), build)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+func mustCheck(t *testing.T, m *Manager, tx Tx) Tx {
+	t.Helper()
+	tx, err := m.Check(context.Background(), tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     func(*Config)
+		wantErr bool
+	}{
+		{"valid", func(*Config) {}, false},
+		{"bump below minimum", func(c *Config) { c.BumpPercent = minBumpPercent - 1 }, true},
+		{"bump at minimum", func(c *Config) { c.BumpPercent = minBumpPercent }, false},
+		{"zero base fee multiplier", func(c *Config) { c.BaseFeeMultiplier = 0 }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			tt.cfg(&cfg)
+			_, err := New(nil, big.NewInt(1), common.Address{}, nil, cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBump(t *testing.T) {
+	tests := []struct {
+		v       *big.Int
+		percent uint64
+		want    int64
+	}{
+		{nil, 10, 0},
+		{big.NewInt(100), 10, 110},
+		{big.NewInt(101), 10, 112}, // rounded up, so the bump is never below the required percentage
+		{big.NewInt(1), 10, 2},
+		{big.NewInt(1000), 0, 1000},
+	}
+	for _, tt := range tests {
+		if got := bump(tt.v, tt.percent); got.Int64() != tt.want {
+			t.Errorf("bump(%v, %d) = %v, want %d", tt.v, tt.percent, got, tt.want)
+		}
+	}
+}
+
+func TestFees(t *testing.T) {
+	c := newTestChain(t)
+	head, err := c.cli.HeaderByNumber(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tip, err := c.cli.SuggestGasTipCap(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	uncapped := new(big.Int).Add(new(big.Int).Mul(head.BaseFee, big.NewInt(2)), tip)
+
+	tests := []struct {
+		name       string
+		maxFeeCap  *big.Int
+		maxTipCap  *big.Int
+		wantFeeCap *big.Int
+		wantTipCap *big.Int
+		wantErr    bool
+	}{
+		{name: "uncapped", wantFeeCap: uncapped, wantTipCap: tip},
+		{name: "tip capped", maxTipCap: big.NewInt(1), wantFeeCap: new(big.Int).Add(new(big.Int).Mul(head.BaseFee, big.NewInt(2)), big.NewInt(1)), wantTipCap: big.NewInt(1)},
+		{name: "fee capped", maxFeeCap: new(big.Int).Add(head.BaseFee, tip), wantFeeCap: new(big.Int).Add(head.BaseFee, tip), wantTipCap: tip},
+		{name: "fee cap below base fee", maxFeeCap: new(big.Int).Sub(head.BaseFee, big.NewInt(1)), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.MaxFeeCap, cfg.MaxTipCap = tt.maxFeeCap, tt.maxTipCap
+			feeCap, tipCap, err := c.manager(t, cfg).fees(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("fees() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if feeCap.Cmp(tt.wantFeeCap) != 0 || tipCap.Cmp(tt.wantTipCap) != 0 {
+				t.Fatalf("fees() = %v, %v, want %v, %v", feeCap, tipCap, tt.wantFeeCap, tt.wantTipCap)
+			}
+		})
+	}
+}
+
+func TestSendReservesNonces(t *testing.T) {
+	c := newTestChain(t)
+	m := c.manager(t, testConfig())
+
+	for want := range uint64(3) {
+		if tx := mustSend(t, m, transfer(recipient, params.TxGas)); tx.Nonce != want || tx.State != StateSubmitted {
+			t.Fatalf("tx %d: nonce %d, state %s", want, tx.Nonce, tx.State)
+		}
+	}
+
+	// A failed build leaves its nonce unused, the next send re-reads the pending nonce and takes it.
+	if _, err := m.Send(context.Background(), func(*bind.TransactOpts) (*types.Transaction, error) {
+		return nil, ethereum.NotFound
+	}); err == nil {
+		t.Fatal("Send() with a failing build succeeded")
+	}
+	if tx := mustSend(t, m, transfer(recipient, params.TxGas)); tx.Nonce != 3 {
+		t.Fatalf("nonce after failed build = %d, want 3", tx.Nonce)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name          string
+		to            common.Address
+		gas           uint64
+		confirmations uint64
+		// blocks mined after sending, and the states after each Check in between
+		want []State
+	}{
+		{"confirmed after one block", recipient, params.TxGas, 1, []State{StateSubmitted, StateConfirmed}},
+		{"waits for confirmations", recipient, params.TxGas, 3, []State{StateSubmitted, StateSubmitted, StateSubmitted, StateConfirmed}},
+		{"reverted", reverter, 50_000, 1, []State{StateSubmitted, StateFailed}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestChain(t)
+			cfg := testConfig()
+			cfg.Confirmations = tt.confirmations
+			m := c.manager(t, cfg)
+			tx := mustSend(t, m, transfer(tt.to, tt.gas))
+			for i, want := range tt.want {
+				if i > 0 {
+					c.backend.Commit()
+				}
+				tx = mustCheck(t, m, tx)
+				if tx.State != want {
+					t.Fatalf("after %d blocks: state %s, want %s (%s)", i, tx.State, want, tx.Error)
+				}
+			}
+			if tx.MinedBlock == 0 || tx.GasUsed == 0 || tx.EffectiveGasPrice == nil {
+				t.Fatalf("final tx lacks receipt data: %+v", tx)
+			}
+			if tt.to == reverter && tx.Error != "execution reverted" {
+				t.Fatalf("error = %q", tx.Error)
+			}
+		})
+	}
+}
+
+func TestCheckReplacesStuckTx(t *testing.T) {
+	tests := []struct {
+		name             string
+		cfg              func(*Config, *Tx)
+		wantReplacements int
+	}{
+		{"replaced", func(*Config, *Tx) {}, 1},
+		{"not stuck yet", func(c *Config, _ *Tx) { c.StuckAfter = time.Hour }, 0},
+		{"replacements exhausted", func(c *Config, _ *Tx) { c.MaxReplacements = 0 }, 0},
+		{"fee cap reached", func(c *Config, tx *Tx) { c.MaxFeeCap = tx.GasFeeCap }, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestChain(t)
+			cfg := testConfig()
+			tx := mustSend(t, c.manager(t, cfg), transfer(recipient, params.TxGas))
+
+			cfg.StuckAfter = 0
+			tt.cfg(&cfg, &tx)
+			m := c.manager(t, cfg)
+			checked := mustCheck(t, m, tx)
+			if checked.Replacements != tt.wantReplacements || len(checked.Hashes) != 1+tt.wantReplacements {
+				t.Fatalf("replacements = %d, hashes = %d, want %d replacements", checked.Replacements, len(checked.Hashes), tt.wantReplacements)
+			}
+			if tt.wantReplacements != 0 {
+				if checked.GasFeeCap.Cmp(bump(tx.GasFeeCap, minBumpPercent)) < 0 || checked.GasTipCap.Cmp(bump(tx.GasTipCap, minBumpPercent)) < 0 {
+					t.Fatalf("replacement fees %v/%v not bumped from %v/%v", checked.GasFeeCap, checked.GasTipCap, tx.GasFeeCap, tx.GasTipCap)
+				}
+				if checked.Nonce != tx.Nonce {
+					t.Fatalf("replacement nonce %d, want %d", checked.Nonce, tx.Nonce)
+				}
+			}
+
+			c.backend.Commit()
+			checked = mustCheck(t, m, checked)
+			if checked.State != StateConfirmed {
+				t.Fatalf("state %s, want %s", checked.State, StateConfirmed)
+			}
+			receipt, err := c.cli.TransactionReceipt(context.Background(), checked.Hash())
+			if err != nil || receipt.Status != types.ReceiptStatusSuccessful {
+				t.Fatalf("latest version not mined: %v", err)
+			}
+		})
+	}
+}
+
+func TestCheckNonceTakenByOtherTx(t *testing.T) {
+	c := newTestChain(t)
+	cfg := testConfig()
+	cfg.Confirmations = 2
+	m := c.manager(t, cfg)
+	tx := mustSend(t, m, transfer(recipient, params.TxGas))
+
+	// Another tx of the account outbids ours for the same nonce and gets mined.
+	other, err := types.SignNewTx(c.key, types.LatestSignerForChainID(c.chainID), &types.DynamicFeeTx{
+		ChainID:   c.chainID,
+		Nonce:     tx.Nonce,
+		GasTipCap: bump(tx.GasTipCap, 50),
+		GasFeeCap: bump(tx.GasFeeCap, 50),
+		Gas:       params.TxGas,
+		To:        &common.Address{1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.cli.SendTransaction(context.Background(), other); err != nil {
+		t.Fatal(err)
+	}
+	c.backend.Commit()
+
+	// The nonce is taken, but our tx is only failed once that held for the receipt confirmations.
+	for i, want := range []State{StateSubmitted, StateSubmitted, StateFailed} {
+		if i > 0 {
+			c.backend.Commit()
+		}
+		tx = mustCheck(t, m, tx)
+		if tx.State != want {
+			t.Fatalf("check %d: state %s, want %s", i, tx.State, want)
+		}
+		if tx.NonceTakenAt == 0 {
+			t.Fatalf("check %d: NonceTakenAt not recorded", i)
+		}
+	}
+	if tx.Error != "nonce consumed by another transaction" {
+		t.Fatalf("error = %q", tx.Error)
+	}
+}
+
+// laggingReceipts hides receipts from the first n lookups, like an RPC node behind the one that served the nonce.
+type laggingReceipts struct {
+	simulated.Client
+	n atomic.Int32
+}
+
+func (c *laggingReceipts) TransactionReceipt(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+	if c.n.Add(-1) >= 0 {
+		return nil, ethereum.NotFound
+	}
+	return c.Client.TransactionReceipt(ctx, hash)
+}
+
+func TestCheckRequeriesReceiptAfterNonceMoved(t *testing.T) {
+	c := newTestChain(t)
+	cli := &laggingReceipts{Client: c.cli}
+	m := c.managerWith(t, cli, testConfig())
+	tx := mustSend(t, m, transfer(recipient, params.TxGas))
+	c.backend.Commit()
+
+	// The first receipt lookup misses the mined tx while the nonce already moved; the second one finds it.
+	cli.n.Store(1)
+	tx = mustCheck(t, m, tx)
+	if tx.State != StateConfirmed {
+		t.Fatalf("state %s (%s), want %s", tx.State, tx.Error, StateConfirmed)
+	}
+
+	// If receipts lag for longer, the tx stays submitted instead of being failed right away.
+	tx = mustSend(t, m, transfer(recipient, params.TxGas))
+	c.backend.Commit()
+	cli.n.Store(2)
+	tx = mustCheck(t, m, tx)
+	if tx.State != StateSubmitted || tx.NonceTakenAt == 0 {
+		t.Fatalf("state %s, nonce taken at %d, want submitted with the nonce recorded", tx.State, tx.NonceTakenAt)
+	}
+	tx = mustCheck(t, m, tx)
+	if tx.State != StateConfirmed || tx.NonceTakenAt != 0 {
+		t.Fatalf("state %s, nonce taken at %d, want confirmed", tx.State, tx.NonceTakenAt)
+	}
+}
+
+func TestCheckIgnoresFinalTx(t *testing.T) {
+	m := &Manager{}
+	for _, state := range []State{StateConfirmed, StateFailed} {
+		tx := Tx{State: state, Error: "kept"}
+		got, err := m.Check(context.Background(), tx)
+		if err != nil || got.State != state || got.Error != "kept" {
+			t.Fatalf("Check(%s) = %+v, %v", state, got, err)
+		}
+	}
+}
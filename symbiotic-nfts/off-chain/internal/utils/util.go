@@ -1,27 +1,167 @@
 package utils
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"strings"
 	"time"
 
+	"github.com/go-errors/errors"
 	grpcmiddleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	"github.com/grpc-ecosystem/go-grpc-middleware/retry"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
 )
 
-func GetGRPCConnection(address string) (*grpc.ClientConn, error) {
+// Retry backoff strategies of GRPCOptions.Backoff
+const (
+	BackoffLinear      = "linear"
+	BackoffExponential = "exponential"
+)
+
+// GRPCOptions configures a relay connection, see DefaultGRPCOptions.
+type GRPCOptions struct {
+	// TLS enables transport security. It is implied by CAFile and CertFile.
+	TLS bool
+	// CAFile holds PEM root certificates the server is verified against instead of the system roots.
+	CAFile string
+	// ServerName overrides the host name the server certificate is verified against.
+	ServerName string
+	// CertFile and KeyFile are the PEM client certificate and key for mutual TLS.
+	CertFile string
+	KeyFile  string
+	// TokenFile holds a bearer token sent with every call. It is re-read per call so that rotated tokens
+	// are picked up, and requires TLS.
+	TokenFile string
+
+	// MaxRetries is how often a call failing with Unavailable or ResourceExhausted is retried, 0 disables retries.
+	MaxRetries uint
+	// RetryBackoff is the wait before a retry, growing per attempt with the exponential Backoff.
+	RetryBackoff time.Duration
+	Backoff      string
+
+	// KeepaliveTime is the idle time after which the connection is pinged, 0 disables keepalive pings.
+	KeepaliveTime time.Duration
+	// KeepaliveTimeout is how long a ping may go unanswered before the connection is closed.
+	KeepaliveTimeout time.Duration
+}
+
+// DefaultGRPCOptions dials without TLS and retries calls 3 times, 1s apart.
+func DefaultGRPCOptions() GRPCOptions {
+	return GRPCOptions{
+		MaxRetries:       3,
+		RetryBackoff:     time.Second,
+		Backoff:          BackoffLinear,
+		KeepaliveTimeout: 20 * time.Second,
+	}
+}
+
+func GetGRPCConnection(address string, o GRPCOptions) (*grpc.ClientConn, error) {
+	var backoff grpc_retry.BackoffFunc
+	switch o.Backoff {
+	case "", BackoffLinear:
+		backoff = grpc_retry.BackoffLinear(o.RetryBackoff)
+	case BackoffExponential:
+		backoff = grpc_retry.BackoffExponential(o.RetryBackoff)
+	default:
+		return nil, errors.Errorf("unknown retry backoff %q, expected %s or %s", o.Backoff, BackoffLinear, BackoffExponential)
+	}
 	retryOpts := []grpc_retry.CallOption{
-		grpc_retry.WithMax(3),
-		grpc_retry.WithBackoff(grpc_retry.BackoffLinear(time.Second)),
+		// WithMax counts the first attempt.
+		grpc_retry.WithMax(o.MaxRetries + 1),
+		grpc_retry.WithBackoff(backoff),
 	}
 	unaryInterceptors := []grpc.UnaryClientInterceptor{grpc_retry.UnaryClientInterceptor(retryOpts...)}
 	opts := []grpc.DialOption{
 		grpc.WithStreamInterceptor(grpc_retry.StreamClientInterceptor(retryOpts...)),
 		grpc.WithUnaryInterceptor(grpcmiddleware.ChainUnaryClient(unaryInterceptors...)),
 		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(100*1024*1024), grpc.MaxCallSendMsgSize(100*1024*1024)),
+		// Traces calls made under a traced context and propagates the trace to the relay.
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
 	}
 
-	opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if o.KeepaliveTime > 0 {
+		opts = append(opts, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:    o.KeepaliveTime,
+			Timeout: o.KeepaliveTimeout,
+		}))
+	}
+
+	if o.TLS || o.CAFile != "" || o.CertFile != "" {
+		tlsCfg, err := clientTLSConfig(o)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(tlsCfg)))
+	} else {
+		if o.TokenFile != "" {
+			return nil, errors.Errorf("a bearer token requires TLS to the relay")
+		}
+		opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	}
+	if o.TokenFile != "" {
+		if _, err := readToken(o.TokenFile); err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.WithPerRPCCredentials(tokenCredentials{path: o.TokenFile}))
+	}
 
 	return grpc.NewClient(address, opts...)
 }
+
+func clientTLSConfig(o GRPCOptions) (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12, ServerName: o.ServerName}
+	if o.CAFile != "" {
+		pem, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, errors.Errorf("failed to read CA file: %w", err)
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, errors.Errorf("no PEM certificates in CA file %s", o.CAFile)
+		}
+	}
+	if (o.CertFile == "") != (o.KeyFile == "") {
+		return nil, errors.Errorf("client certificate and key must be set together")
+	}
+	if o.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, errors.Errorf("failed to load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// tokenCredentials sends the bearer token of a file as authorization metadata.
+type tokenCredentials struct {
+	path string
+}
+
+func (c tokenCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	token, err := readToken(c.path)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"authorization": "Bearer " + token}, nil
+}
+
+func (tokenCredentials) RequireTransportSecurity() bool { return true }
+
+func readToken(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", errors.Errorf("failed to read token file: %w", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", errors.Errorf("token file %s is empty", path)
+	}
+	return token, nil
+}
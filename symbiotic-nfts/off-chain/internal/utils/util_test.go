@@ -0,0 +1,127 @@
+package utils
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	v1 "github.com/symbioticfi/relay/api/client/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// serveFailing starts a gRPC server failing every call with code and returns its address and call count.
+func serveFailing(t *testing.T, code codes.Code) (string, *atomic.Int32) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var calls atomic.Int32
+	srv := grpc.NewServer(grpc.UnknownServiceHandler(func(any, grpc.ServerStream) error {
+		calls.Add(1)
+		return status.Error(code, "failing")
+	}))
+	go func() { _ = srv.Serve(ln) }()
+	t.Cleanup(srv.Stop)
+	return ln.Addr().String(), &calls
+}
+
+func TestGetGRPCConnectionRetries(t *testing.T) {
+	tests := []struct {
+		code       codes.Code
+		maxRetries uint
+		backoff    string
+		want       int32
+	}{
+		{codes.Unavailable, 0, BackoffLinear, 1},
+		{codes.Unavailable, 1, BackoffLinear, 2},
+		{codes.ResourceExhausted, 3, BackoffExponential, 4},
+		{codes.Internal, 3, BackoffLinear, 1},
+	}
+	for _, tt := range tests {
+		addr, calls := serveFailing(t, tt.code)
+		o := DefaultGRPCOptions()
+		o.MaxRetries, o.RetryBackoff, o.Backoff = tt.maxRetries, time.Millisecond, tt.backoff
+		conn, err := GetGRPCConnection(addr, o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		_, err = v1.NewSymbioticClient(conn).GetCurrentEpoch(ctx, &v1.GetCurrentEpochRequest{})
+		cancel()
+		_ = conn.Close()
+		if status.Code(err) != tt.code {
+			t.Fatalf("call failing with %s = %v", tt.code, err)
+		}
+		if got := calls.Load(); got != tt.want {
+			t.Errorf("%d retries of %s: %d attempts, want %d", tt.maxRetries, tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestGetGRPCConnectionOptions(t *testing.T) {
+	token := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(token, []byte("secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	empty := filepath.Join(t.TempDir(), "empty")
+	if err := os.WriteFile(empty, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		edit func(*GRPCOptions)
+		want string
+	}{
+		{"unknown backoff", func(o *GRPCOptions) { o.Backoff = "fibonacci" }, `unknown retry backoff "fibonacci", expected linear or exponential`},
+		{"token without TLS", func(o *GRPCOptions) { o.TokenFile = token }, "a bearer token requires TLS to the relay"},
+		{"empty token", func(o *GRPCOptions) { o.TLS, o.TokenFile = true, empty }, "is empty"},
+		{"missing token", func(o *GRPCOptions) { o.TLS, o.TokenFile = true, filepath.Join(t.TempDir(), "missing") }, "failed to read token file"},
+		{"certificate without key", func(o *GRPCOptions) { o.CertFile = token }, "client certificate and key must be set together"},
+		{"CA file without certificates", func(o *GRPCOptions) { o.CAFile = token }, "no PEM certificates in CA file"},
+	}
+	for _, tt := range tests {
+		o := DefaultGRPCOptions()
+		tt.edit(&o)
+		conn, err := GetGRPCConnection("127.0.0.1:1", o)
+		if err == nil {
+			_ = conn.Close()
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: GetGRPCConnection() = %v, want an error containing %q", tt.name, err, tt.want)
+		}
+	}
+
+	o := DefaultGRPCOptions()
+	o.TLS, o.TokenFile = true, token
+	conn, err := GetGRPCConnection("127.0.0.1:1", o)
+	if err != nil {
+		t.Fatalf("GetGRPCConnection() with a token over TLS: %v", err)
+	}
+	_ = conn.Close()
+}
+
+func TestTokenCredentials(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	creds := tokenCredentials{path: path}
+	for _, token := range []string{"first", "rotated"} {
+		if err := os.WriteFile(path, []byte(token+"\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		md, err := creds.GetRequestMetadata(context.Background())
+		if err != nil || md["authorization"] != "Bearer "+token {
+			t.Fatalf("GetRequestMetadata() = %v, %v, want the bearer token %q", md, err, token)
+		}
+	}
+	if !creds.RequireTransportSecurity() {
+		t.Error("the token is sent without transport security")
+	}
+}
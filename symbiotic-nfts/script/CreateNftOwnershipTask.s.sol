@@ -20,6 +20,8 @@ contract CreateNftOwnershipTask is Script {
         uint256 chkRaw     = vm.envOr("CHECKED_BLOCK", uint256(block.number)); 
         uint64 checked     = uint64(chkRaw);
 
+        uint256 minAmount  = vm.envOr("MIN_AMOUNT", uint256(0));
+
         vm.startBroadcast(pk);
 
         NftOwnershipTask task = NftOwnershipTask(taskAddr);
@@ -30,7 +32,8 @@ contract CreateNftOwnershipTask is Script {
             tokenId,
             owner,
             checked,
-            NftOwnershipTask.Standard(standard)
+            NftOwnershipTask.Standard(standard),
+            minAmount
         );
 
         console2.log("Created task on NftOwnershipTask:", taskAddr);
@@ -40,6 +43,7 @@ contract CreateNftOwnershipTask is Script {
         console2.log("owner:", owner);
         console2.log("checkedBlock:", checked);
         console2.log("standard:", standard);
+        console2.log("minAmount:", minAmount);
         console2.log("TaskID:");
         console2.logBytes32(taskId);
 
@@ -7,6 +7,7 @@ contract NftOwnershipTask {
     error AlreadyResponded();
     error InvalidQuorumSignature();
     error InvalidVerifyingEpoch();
+    error UnsupportedPayloadVersion(uint8 version);
 
     enum TaskStatus {
         CREATED,
@@ -17,7 +18,18 @@ contract NftOwnershipTask {
 
     enum Standard {
         ERC721,
-        ERC1155
+        ERC1155,
+        ERC20,
+        ERC721_COLLECTION // any token of the collection, via balanceOf(owner); tokenId is ignored
+    }
+
+    enum Reason {
+        OWNER,
+        NOT_OWNER,
+        NONEXISTENT_TOKEN,
+        NOT_A_CONTRACT,
+        STATE_UNAVAILABLE,
+        INVALID_RESPONSE
     }
 
     struct Request {
@@ -27,6 +39,7 @@ contract NftOwnershipTask {
         address owner;        
         uint64  checkedBlock; 
         Standard standard;     
+        uint256 minAmount;     // required balance (ERC20 base units or token count), 0 means any; ignored for ERC721
         uint256 nonce;       
         uint48  createdAt;     
     }
@@ -36,6 +49,9 @@ contract NftOwnershipTask {
         bool    isOwner;
         address ownerAtBlock;  
         uint64  observedBlock; 
+        Reason  reason;
+        uint256 observedAmount;
+        uint8   decimals;
     }
 
     event CreateTask(bytes32 indexed taskId, Request req);
@@ -45,6 +61,8 @@ contract NftOwnershipTask {
 
     uint32 public constant TASK_EXPIRY = 12000;
 
+    uint8 public constant PAYLOAD_VERSION = 2;
+
     ISettlement public settlement;
     uint256 public nonce;
 
@@ -74,7 +92,8 @@ contract NftOwnershipTask {
         uint256 tokenId,
         address owner,
         uint64  checkedBlock,
-        Standard standard
+        Standard standard,
+        uint256 minAmount
     ) public returns (bytes32 taskId) {
         uint256 nonce_ = nonce++;
         Request memory req = Request({
@@ -84,12 +103,13 @@ contract NftOwnershipTask {
             owner: owner,
             checkedBlock: checkedBlock,
             standard: standard,
+            minAmount: minAmount,
             nonce: nonce_,
             createdAt: uint48(block.timestamp)
         });
 
         taskId = keccak256(
-            abi.encode(block.chainid, chainId, collection, tokenId, owner, checkedBlock, standard, nonce_)
+            abi.encode(block.chainid, chainId, collection, tokenId, owner, checkedBlock, standard, minAmount, nonce_)
         );
 
         tasks[taskId] = req;
@@ -101,7 +121,9 @@ contract NftOwnershipTask {
     /**
      * @notice Store an attested result after settlement verification.
      * The off-chain node signs `abi.encode(taskId, payload)` where
-     * `payload = abi.encode(bool isOwner, address ownerAtBlock, uint64 observedBlock)`.
+     * `payload = abi.encode(uint8 version, Reason reason, bool isOwner, address ownerAtBlock, uint64 observedBlock,
+     * uint256 observedAmount, uint8 decimals)`, where `observedAmount` is the balance seen at `observedBlock`
+     * (ERC20 amount or token count) and `decimals` the ERC20 token decimals (0 otherwise).
      */
     function respondTask(bytes32 taskId, bytes calldata payload, uint48 epoch, bytes calldata proof) public {
         if (responses[taskId].answeredAt > 0) {
@@ -126,14 +148,28 @@ contract NftOwnershipTask {
             revert InvalidQuorumSignature();
         }
 
-        (bool isOwner, address ownerAtBlock, uint64 observedBlock) =
-            abi.decode(payload, (bool, address, uint64));
+        uint8 version = abi.decode(payload[:32], (uint8));
+        if (version != PAYLOAD_VERSION) {
+            revert UnsupportedPayloadVersion(version);
+        }
+        (
+            ,
+            Reason reason,
+            bool isOwner,
+            address ownerAtBlock,
+            uint64 observedBlock,
+            uint256 observedAmount,
+            uint8 decimals
+        ) = abi.decode(payload, (uint8, Reason, bool, address, uint64, uint256, uint8));
 
         Response memory resp = Response({
             answeredAt: uint48(block.timestamp),
             isOwner: isOwner,
             ownerAtBlock: ownerAtBlock,
-            observedBlock: observedBlock
+            observedBlock: observedBlock,
+            reason: reason,
+            observedAmount: observedAmount,
+            decimals: decimals
         });
 
         responses[taskId] = resp;